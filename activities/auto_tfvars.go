@@ -0,0 +1,87 @@
+package activities
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Terraform loads variable values in this order, later sources winning:
+// environment variables, terraform.tfvars, terraform.tfvars.json,
+// *.auto.tfvars and *.auto.tfvars.json (lexical order), then -var and
+// -var-file flags in command-line order. The var files built here are
+// passed as flags, so by default they override every auto-loaded file.
+
+// baseVariables parses TFVars. With PreferAutoTFVars, variables that an
+// auto-loaded file in the module directory also sets are dropped, so the
+// auto-loaded value is the one terraform uses.
+func baseVariables(params TerraformParams) (map[string]interface{}, error) {
+	if params.TFVars == "" {
+		return make(map[string]interface{}), nil
+	}
+	variables, err := parseTFVarsFile(params.TFVars)
+	if err != nil {
+		return nil, err
+	}
+	if !params.PreferAutoTFVars {
+		return variables, nil
+	}
+
+	autoVars, err := autoLoadedVariables(filepath.Join(params.Dir, params.Chdir))
+	if err != nil {
+		return nil, err
+	}
+	for name := range autoVars {
+		delete(variables, name)
+	}
+	return variables, nil
+}
+
+// autoLoadedVariables returns the values terraform loads on its own from a
+// module directory, merged in terraform's load order.
+func autoLoadedVariables(dir string) (map[string]interface{}, error) {
+	files, err := autoLoadedVarFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	variables := make(map[string]interface{})
+	for _, file := range files {
+		values, err := parseTFVarsFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range values {
+			variables[name] = value
+		}
+	}
+	return variables, nil
+}
+
+// autoLoadedVarFiles lists the var files terraform auto-loads from dir, in
+// the order it loads them.
+func autoLoadedVarFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files, auto []string
+	for _, name := range []string{"terraform.tfvars", "terraform.tfvars.json"} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err == nil && !info.IsDir() {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".auto.tfvars") || strings.HasSuffix(name, ".auto.tfvars.json")) {
+			continue
+		}
+		auto = append(auto, name)
+	}
+	sort.Strings(auto)
+	for _, name := range auto {
+		files = append(files, filepath.Join(dir, name))
+	}
+	return files, nil
+}
@@ -0,0 +1,97 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+)
+
+// BackendSource is a workspace whose module is checked for a backend. Only
+// Params.Dir and Params.Chdir are read.
+type BackendSource struct {
+	Workspace string
+	Params    TerraformParams
+}
+
+var terraformBlockSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{{Type: "terraform"}},
+}
+
+var backendBlockSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "backend", LabelNames: []string{"type"}},
+		{Type: "cloud"},
+	},
+}
+
+// ModuleBackend returns the backend a module declares in its terraform
+// blocks: the backend type, "cloud" for a cloud block, or "" when it
+// declares none and terraform keeps state in a local file. Both .tf and
+// .tf.json files are read; an explicit "local" backend is returned as is.
+func ModuleBackend(moduleDir string) (string, error) {
+	entries, err := os.ReadDir(moduleDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read module dir: %v", err)
+	}
+
+	parser := hclparse.NewParser()
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		var file *hcl.File
+		var diags hcl.Diagnostics
+		switch {
+		case strings.HasSuffix(name, ".tf"):
+			file, diags = parser.ParseHCLFile(filepath.Join(moduleDir, name))
+		case strings.HasSuffix(name, ".tf.json"):
+			file, diags = parser.ParseJSONFile(filepath.Join(moduleDir, name))
+		default:
+			continue
+		}
+		if diags.HasErrors() {
+			return "", fmt.Errorf("failed to parse %s: %v", name, diags.Error())
+		}
+
+		content, _, diags := file.Body.PartialContent(terraformBlockSchema)
+		if diags.HasErrors() {
+			return "", fmt.Errorf("failed to parse %s: %v", name, diags.Error())
+		}
+		for _, block := range content.Blocks {
+			inner, _, diags := block.Body.PartialContent(backendBlockSchema)
+			if diags.HasErrors() {
+				return "", fmt.Errorf("failed to parse %s: %v", name, diags.Error())
+			}
+			for _, backend := range inner.Blocks {
+				if backend.Type == "cloud" {
+					return "cloud", nil
+				}
+				return backend.Labels[0], nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// CheckLocalState returns the workspaces, in source order, whose module
+// keeps terraform state on the worker's disk: it declares no backend or the
+// "local" backend. State written there is lost with an ephemeral worker.
+func (a *TerraformActivities) CheckLocalState(ctx context.Context, sources []BackendSource) ([]string, error) {
+	var local []string
+	for _, src := range sources {
+		backend, err := ModuleBackend(filepath.Join(src.Params.Dir, src.Params.Chdir))
+		if err != nil {
+			return nil, fmt.Errorf("workspace %s: %v", src.Workspace, err)
+		}
+		if backend == "" || backend == "local" {
+			local = append(local, src.Workspace)
+		}
+	}
+	return local, nil
+}
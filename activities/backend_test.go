@@ -0,0 +1,78 @@
+package activities
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, body := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(body), 0644))
+	}
+	return dir
+}
+
+func TestModuleBackend(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"no terraform block", map[string]string{"main.tf": `resource "null_resource" "x" {}`}, ""},
+		{"terraform block without backend", map[string]string{"versions.tf": `terraform { required_version = ">= 1.5" }`}, ""},
+		{"s3 backend", map[string]string{
+			"main.tf":    `resource "null_resource" "x" {}`,
+			"backend.tf": "terraform {\n  backend \"s3\" {\n    bucket = \"state\"\n  }\n}\n",
+		}, "s3"},
+		{"explicit local backend", map[string]string{"backend.tf": "terraform {\n  backend \"local\" {}\n}\n"}, "local"},
+		{"cloud block", map[string]string{"main.tf": "terraform {\n  cloud {\n    organization = \"acme\"\n  }\n}\n"}, "cloud"},
+		{"json backend", map[string]string{"backend.tf.json": `{"terraform": {"backend": {"gcs": {"bucket": "state"}}}}`}, "gcs"},
+		{"other files ignored", map[string]string{"notes.txt": `terraform { backend "s3" {} }`}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, err := ModuleBackend(writeModule(t, tt.files))
+			require.NoError(t, err)
+			require.Equal(t, tt.want, backend)
+		})
+	}
+}
+
+func TestModuleBackendRejectsInvalidHCL(t *testing.T) {
+	_, err := ModuleBackend(writeModule(t, map[string]string{"main.tf": `terraform {`}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to parse main.tf")
+}
+
+func TestCheckLocalStateFlagsModulesWithoutRemoteBackend(t *testing.T) {
+	remote := writeModule(t, map[string]string{"backend.tf": "terraform {\n  backend \"s3\" {}\n}\n"})
+	local := writeModule(t, map[string]string{"main.tf": `resource "null_resource" "x" {}`})
+	explicit := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(explicit, "env"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(explicit, "env", "backend.tf"), []byte("terraform {\n  backend \"local\" {}\n}\n"), 0644))
+
+	a := &TerraformActivities{}
+	flagged, err := a.CheckLocalState(context.Background(), []BackendSource{
+		{Workspace: "vpc", Params: TerraformParams{Dir: remote}},
+		{Workspace: "scratch", Params: TerraformParams{Dir: local}},
+		{Workspace: "env", Params: TerraformParams{Dir: explicit, Chdir: "env"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"scratch", "env"}, flagged)
+}
+
+func TestCheckLocalStateMissingDir(t *testing.T) {
+	a := &TerraformActivities{}
+	_, err := a.CheckLocalState(context.Background(), []BackendSource{
+		{Workspace: "vpc", Params: TerraformParams{Dir: filepath.Join(t.TempDir(), "missing")}},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "workspace vpc: failed to read module dir")
+}
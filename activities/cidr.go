@@ -0,0 +1,120 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// CIDRSource names the variables holding the address ranges one workspace
+// allocates, such as a VPC CIDR. Values are read from the workspace's tfvars
+// file and static vars in Params.
+type CIDRSource struct {
+	Workspace string
+	Params    TerraformParams
+	Vars      []string
+}
+
+// CIDROverlap is a pair of ranges from different workspaces that overlap.
+// Variables of list values are named with their index, e.g. "cidrs[1]".
+type CIDROverlap struct {
+	Workspace      string `json:"workspace"`
+	Variable       string `json:"variable"`
+	CIDR           string `json:"cidr"`
+	OtherWorkspace string `json:"otherWorkspace"`
+	OtherVariable  string `json:"otherVariable"`
+	OtherCIDR      string `json:"otherCidr"`
+}
+
+func (o CIDROverlap) String() string {
+	return fmt.Sprintf("%s.%s (%s) overlaps %s.%s (%s)", o.Workspace, o.Variable, o.CIDR, o.OtherWorkspace, o.OtherVariable, o.OtherCIDR)
+}
+
+// CIDRsOverlap reports whether two networks share any address. Networks of
+// different address families never overlap.
+func CIDRsOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// namedCIDR is one parsed range and where it came from.
+type namedCIDR struct {
+	workspace string
+	variable  string
+	network   *net.IPNet
+	raw       string
+}
+
+// CheckCIDROverlaps compares the declared ranges of every workspace against
+// those of every other workspace and returns the overlapping pairs, in
+// source order. Ranges within one workspace are not compared, and variables
+// that aren't set (e.g. left to module defaults or inputs) are ignored.
+func (a *TerraformActivities) CheckCIDROverlaps(ctx context.Context, sources []CIDRSource) ([]CIDROverlap, error) {
+	var cidrs []namedCIDR
+	for _, src := range sources {
+		found, err := workspaceCIDRs(src)
+		if err != nil {
+			return nil, err
+		}
+		cidrs = append(cidrs, found...)
+	}
+
+	var overlaps []CIDROverlap
+	for i, x := range cidrs {
+		for _, y := range cidrs[i+1:] {
+			if x.workspace == y.workspace || !CIDRsOverlap(x.network, y.network) {
+				continue
+			}
+			overlaps = append(overlaps, CIDROverlap{
+				Workspace:      x.workspace,
+				Variable:       x.variable,
+				CIDR:           x.raw,
+				OtherWorkspace: y.workspace,
+				OtherVariable:  y.variable,
+				OtherCIDR:      y.raw,
+			})
+		}
+	}
+	return overlaps, nil
+}
+
+// workspaceCIDRs parses the declared variables of one workspace. A variable
+// may hold a single CIDR string or a list of them.
+func workspaceCIDRs(src CIDRSource) ([]namedCIDR, error) {
+	variables, err := combinedVariables(src.Params)
+	if err != nil {
+		return nil, fmt.Errorf("workspace %s: %v", src.Workspace, err)
+	}
+
+	var cidrs []namedCIDR
+	parse := func(name string, value interface{}) error {
+		raw, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("workspace %s: variable %s must be a CIDR string, got %T", src.Workspace, name, value)
+		}
+		_, network, err := net.ParseCIDR(raw)
+		if err != nil {
+			return fmt.Errorf("workspace %s: variable %s is not a CIDR: %q", src.Workspace, name, raw)
+		}
+		cidrs = append(cidrs, namedCIDR{workspace: src.Workspace, variable: name, network: network, raw: raw})
+		return nil
+	}
+
+	for _, name := range src.Vars {
+		value, ok := variables[name]
+		if !ok {
+			continue
+		}
+		if list, ok := value.([]interface{}); ok {
+			for i, item := range list {
+				if err := parse(fmt.Sprintf("%s[%d]", name, i), item); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if err := parse(name, value); err != nil {
+			return nil, err
+		}
+	}
+	return cidrs, nil
+}
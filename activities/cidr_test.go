@@ -0,0 +1,70 @@
+package activities
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mustCIDR(t *testing.T, s string) *net.IPNet {
+	t.Helper()
+	_, network, err := net.ParseCIDR(s)
+	require.NoError(t, err)
+	return network
+}
+
+func TestCIDRsOverlap(t *testing.T) {
+	require.True(t, CIDRsOverlap(mustCIDR(t, "10.0.0.0/16"), mustCIDR(t, "10.0.128.0/20")))
+	require.True(t, CIDRsOverlap(mustCIDR(t, "10.0.128.0/20"), mustCIDR(t, "10.0.0.0/16")))
+	require.False(t, CIDRsOverlap(mustCIDR(t, "10.0.0.0/16"), mustCIDR(t, "10.1.0.0/16")))
+	require.False(t, CIDRsOverlap(mustCIDR(t, "10.0.0.0/8"), mustCIDR(t, "fd00::/8")))
+}
+
+func TestCheckCIDROverlapsFlagsOverlappingWorkspaces(t *testing.T) {
+	tfvars := filepath.Join(t.TempDir(), "vpc.tfvars")
+	require.NoError(t, os.WriteFile(tfvars, []byte(`vpc_cidr = "10.0.0.0/16"`), 0644))
+
+	a := &TerraformActivities{}
+	overlaps, err := a.CheckCIDROverlaps(context.Background(), []CIDRSource{
+		{Workspace: "vpc", Params: TerraformParams{TFVars: tfvars}, Vars: []string{"vpc_cidr"}},
+		{Workspace: "peer", Params: TerraformParams{Vars: map[string]interface{}{
+			"cidrs": []interface{}{"192.168.0.0/24", "10.0.64.0/18"},
+		}}, Vars: []string{"cidrs", "unset"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []CIDROverlap{{
+		Workspace:      "vpc",
+		Variable:       "vpc_cidr",
+		CIDR:           "10.0.0.0/16",
+		OtherWorkspace: "peer",
+		OtherVariable:  "cidrs[1]",
+		OtherCIDR:      "10.0.64.0/18",
+	}}, overlaps)
+}
+
+func TestCheckCIDROverlapsPassesDisjointRanges(t *testing.T) {
+	a := &TerraformActivities{}
+	overlaps, err := a.CheckCIDROverlaps(context.Background(), []CIDRSource{
+		// Overlaps within one workspace (a VPC and its subnet) are expected
+		{Workspace: "vpc", Params: TerraformParams{Vars: map[string]interface{}{
+			"vpc_cidr":    "10.0.0.0/16",
+			"subnet_cidr": "10.0.1.0/24",
+		}}, Vars: []string{"vpc_cidr", "subnet_cidr"}},
+		{Workspace: "peer", Params: TerraformParams{Vars: map[string]interface{}{"vpc_cidr": "10.1.0.0/16"}}, Vars: []string{"vpc_cidr"}},
+	})
+	require.NoError(t, err)
+	require.Empty(t, overlaps)
+}
+
+func TestCheckCIDROverlapsRejectsInvalidValues(t *testing.T) {
+	a := &TerraformActivities{}
+	_, err := a.CheckCIDROverlaps(context.Background(), []CIDRSource{
+		{Workspace: "vpc", Params: TerraformParams{Vars: map[string]interface{}{"vpc_cidr": "10.0.0.0"}}, Vars: []string{"vpc_cidr"}},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "workspace vpc: variable vpc_cidr is not a CIDR")
+}
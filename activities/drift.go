@@ -0,0 +1,67 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// DriftResult reports whether a workspace's infrastructure has drifted from
+// its state, i.e. changed outside terraform since the last apply.
+type DriftResult struct {
+	Drifted bool `json:"drifted"`
+	// Changed is the number of drifted resources
+	Changed int `json:"changed"`
+	// Resources lists the drifted resource addresses, in plan order
+	Resources []string `json:"resources,omitempty"`
+}
+
+// driftedResource matches the header terraform prints for each resource a
+// refresh-only plan found changed or deleted outside terraform.
+var driftedResource = regexp.MustCompile(`(?m)^\s*# (\S+) has (?:changed|been deleted)\s*$`)
+
+// TerraformDriftCheck runs a refresh-only plan, which compares the state with
+// the real infrastructure without proposing any change to it. Nothing is
+// saved or applied: exit code 2 means drift, 0 means none.
+func (a *TerraformActivities) TerraformDriftCheck(ctx context.Context, params TerraformParams) (DriftResult, error) {
+	if err := validatePaths(params); err != nil {
+		return DriftResult{}, err
+	}
+
+	varFiles, cleanupVarFiles, err := varFileArgs(params)
+	if err != nil {
+		return DriftResult{}, err
+	}
+	defer cleanupVarFiles()
+
+	args := append([]string{"plan", "-refresh-only", "-no-color", "-detailed-exitcode"}, stateCommandArgs(params)...)
+	if params.DisableLock {
+		args = append(args, "-lock=false")
+	}
+	for _, target := range params.Targets {
+		args = append(args, "-target="+target)
+	}
+	args = append(args, varFiles...)
+
+	cmd := terraformCommand(ctx, params, args...)
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return DriftResult{}, nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 2 {
+		return parseDrift(string(output)), nil
+	}
+	return DriftResult{}, stateLockError(fmt.Errorf("terraform drift check failed: %v, args: %s, output: %s", err, strings.Join(args, " "), errorOutput(params, "plan", output)), output)
+}
+
+// parseDrift builds the result of a refresh-only plan that found drift.
+func parseDrift(output string) DriftResult {
+	result := DriftResult{Drifted: true}
+	for _, match := range driftedResource.FindAllStringSubmatch(output, -1) {
+		result.Resources = append(result.Resources, match[1])
+	}
+	result.Changed = len(result.Resources)
+	return result
+}
@@ -0,0 +1,88 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestTerraformDriftCheckReportsDrift(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	t.Setenv("TF_DRIFT", "aws_instance.web module.net.aws_subnet.a")
+	invocations := recordTerraformArgs(t)
+
+	tmp := t.TempDir()
+	params := TerraformParams{Dir: tmp, PlanFile: "drift.plan", DetectOnly: true, DisableLock: true, TempRoot: tmp}
+
+	act := &TerraformActivities{}
+	result, err := act.TerraformDriftCheck(context.Background(), params)
+	require.NoError(t, err)
+	require.Equal(t, DriftResult{
+		Drifted:   true,
+		Changed:   2,
+		Resources: []string{"aws_instance.web", "module.net.aws_subnet.a"},
+	}, result)
+
+	calls := invocations()
+	require.Len(t, calls, 1)
+	args := strings.Fields(calls[0])
+	require.Equal(t, []string{"plan", "-refresh-only", "-no-color", "-detailed-exitcode"}, args[:4])
+	require.Contains(t, args, "-lock=false")
+	require.NotContains(t, args, "-out")
+
+	// Nothing is saved for apply
+	_, err = os.Stat(filepath.Join(tmp, "drift.plan"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestTerraformDriftCheckNoDrift(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+
+	act := &TerraformActivities{}
+	result, err := act.TerraformDriftCheck(context.Background(), TerraformParams{Dir: t.TempDir()})
+	require.NoError(t, err)
+	require.Equal(t, DriftResult{}, result)
+}
+
+func TestTerraformDriftCheckReportsStateLock(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	t.Setenv("TF_STATE_LOCKED", "1")
+
+	act := &TerraformActivities{}
+	_, err := act.TerraformDriftCheck(context.Background(), TerraformParams{Dir: t.TempDir()})
+	require.Error(t, err)
+
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr))
+	require.Equal(t, StateLockErrorType, appErr.Type())
+}
+
+func TestParseDrift(t *testing.T) {
+	output := `Note: Objects have changed outside of Terraform
+
+Terraform detected the following changes made outside of Terraform since the
+last "terraform apply" which may have affected this plan:
+
+  # aws_instance.web has changed
+  ~ resource "aws_instance" "web" {
+      ~ tags = {
+          + "Owner" = "ops"
+        }
+    }
+
+  # aws_s3_bucket.logs["eu"] has been deleted
+  - resource "aws_s3_bucket" "logs" {
+    }
+`
+	require.Equal(t, DriftResult{
+		Drifted:   true,
+		Changed:   2,
+		Resources: []string{"aws_instance.web", `aws_s3_bucket.logs["eu"]`},
+	}, parseDrift(output))
+}
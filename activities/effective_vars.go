@@ -0,0 +1,57 @@
+package activities
+
+import (
+	"context"
+	"strings"
+)
+
+// RedactedValue replaces sensitive values in effective variables.
+const RedactedValue = "(sensitive)"
+
+// sensitiveNameParts mark a variable as sensitive by name even when it isn't
+// listed in TerraformParams.SensitiveVars.
+var sensitiveNameParts = []string{"password", "secret", "token", "private_key", "access_key", "credential"}
+
+// TerraformEffectiveVars returns the variables terraform will see for this
+// workspace (tfvars file merged with extra vars), with sensitive values
+// redacted. It is meant for debugging failed plans.
+func (a *TerraformActivities) TerraformEffectiveVars(ctx context.Context, params TerraformParams) (map[string]interface{}, error) {
+	if err := validatePaths(params); err != nil {
+		return nil, err
+	}
+
+	variables, err := combinedVariables(params)
+	if err != nil {
+		return nil, err
+	}
+	return redactVars(variables, params.SensitiveVars), nil
+}
+
+// redactVars replaces the values of sensitive variables, matched by explicit
+// name or by a name containing a common secret marker.
+func redactVars(variables map[string]interface{}, sensitive []string) map[string]interface{} {
+	explicit := make(map[string]bool, len(sensitive))
+	for _, name := range sensitive {
+		explicit[name] = true
+	}
+
+	redacted := make(map[string]interface{}, len(variables))
+	for name, value := range variables {
+		if explicit[name] || looksSensitive(name) {
+			redacted[name] = RedactedValue
+			continue
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+func looksSensitive(name string) bool {
+	lower := strings.ToLower(name)
+	for _, part := range sensitiveNameParts {
+		if strings.Contains(lower, part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package activities
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// InitInfo summarizes what terraform init did, parsed from its -json output.
+type InitInfo struct {
+	Providers []InstalledProvider `json:"providers,omitempty"`
+	Backend   string              `json:"backend,omitempty"` // Backend type, when one was configured
+	Messages  []string            `json:"messages,omitempty"`
+}
+
+// InstalledProvider is a provider installed (or reused) by terraform init.
+type InstalledProvider struct {
+	Source  string `json:"source"`
+	Version string `json:"version"`
+	Reused  bool   `json:"reused,omitempty"` // Already present from a previous init
+}
+
+var (
+	installedProviderRe = regexp.MustCompile(`^(?:- )?Installed (\S+) v(\S+)`)
+	reusedProviderRe    = regexp.MustCompile(`^(?:- )?Using previously-installed (\S+) v(\S+)`)
+	backendConfiguredRe = regexp.MustCompile(`Successfully configured the backend "([^"]+)"`)
+)
+
+// TerraformInitInfo runs init like TerraformInit, with -json, and returns
+// the providers and backend it reports. Use it when the workspace result
+// should record what was installed.
+func (a *TerraformActivities) TerraformInitInfo(ctx context.Context, params TerraformParams) (InitInfo, error) {
+	if err := validatePaths(params); err != nil {
+		return InitInfo{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	args := []string{"init", "-json"}
+	if params.InitUpgrade {
+		args = append(args, "-upgrade")
+	}
+	cmd := terraformCommand(ctx, params, args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return InitInfo{}, fmt.Errorf("terraform init failed: %v, output: %s", err, errorOutput(params, "init", output))
+	}
+
+	info, err := parseInitJSON(output)
+	if err != nil {
+		return InitInfo{}, err
+	}
+	if err := checkInitProviders(params); err != nil {
+		return InitInfo{}, err
+	}
+	return info, nil
+}
+
+// parseInitJSON reads the JSON lines emitted by terraform init -json.
+// Lines that aren't JSON (some versions print plain text alongside) are ignored.
+func parseInitJSON(output []byte) (InitInfo, error) {
+	var info InitInfo
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var entry struct {
+			Message string `json:"@message"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			continue
+		}
+		msg := strings.TrimSpace(entry.Message)
+		if msg == "" {
+			continue
+		}
+		info.Messages = append(info.Messages, msg)
+
+		if m := installedProviderRe.FindStringSubmatch(msg); m != nil {
+			info.Providers = append(info.Providers, InstalledProvider{Source: m[1], Version: m[2]})
+		} else if m := reusedProviderRe.FindStringSubmatch(msg); m != nil {
+			info.Providers = append(info.Providers, InstalledProvider{Source: m[1], Version: m[2], Reused: true})
+		} else if m := backendConfiguredRe.FindStringSubmatch(msg); m != nil {
+			info.Backend = m[1]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return InitInfo{}, fmt.Errorf("failed to read terraform init output: %v", err)
+	}
+	return info, nil
+}
@@ -0,0 +1,107 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+)
+
+// WorkspaceState is the reconciled state of a workspace workflow that was
+// started by an earlier run of the orchestration.
+type WorkspaceState struct {
+	Running          bool                   // The workflow is still open (e.g. hosting children)
+	Finished         bool                   // Terraform operations completed successfully
+	Outputs          map[string]interface{} // Outputs, when Finished
+	SensitiveOutputs []string               // Names of Outputs terraform marks sensitive
+	Details          *WorkspaceDetails      // Details, when Finished and any were recorded
+}
+
+// WorkspaceDetails is what a workspace run reports besides its terraform
+// outputs. It is kept apart from the outputs so it never reaches
+// dependents' inputs or the flattened outputs. Each field is set only when
+// the workspace asked for it, or for Apply, when apply ran.
+type WorkspaceDetails struct {
+	Init          *InitInfo              `json:"init,omitempty"`
+	EffectiveVars map[string]interface{} `json:"effectiveVars,omitempty"`
+	Plan          *PlanSummary           `json:"plan,omitempty"`
+	Apply         *ApplyResult           `json:"apply,omitempty"`
+	Drift         *DriftResult           `json:"drift,omitempty"`
+}
+
+// ReconcileParams identifies the workspace workflows to inspect.
+type ReconcileParams struct {
+	WorkflowIDs map[string]string // workspace name -> deterministic workflow ID
+	QueryType   string            // query handler reporting a running workflow's WorkspaceState
+}
+
+// OrchestrationActivities inspects orchestration state through the Temporal
+// client, for use by workflows that need to look at other executions.
+type OrchestrationActivities struct {
+	Client client.Client
+}
+
+// ReconcileWorkspaces looks up each workspace workflow by ID and reports the
+// ones that are still running or whose terraform operations finished.
+// Workflows that were never started or failed are omitted so they run again.
+func (a *OrchestrationActivities) ReconcileWorkspaces(ctx context.Context, params ReconcileParams) (map[string]WorkspaceState, error) {
+	if a.Client == nil {
+		return nil, errors.New("orchestration activities require a Temporal client")
+	}
+
+	states := make(map[string]WorkspaceState)
+	for name, id := range params.WorkflowIDs {
+		resp, err := a.Client.DescribeWorkflowExecution(ctx, id, "")
+		if err != nil {
+			var notFound *serviceerror.NotFound
+			if errors.As(err, &notFound) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to describe workflow %s: %v", id, err)
+		}
+
+		switch resp.GetWorkflowExecutionInfo().GetStatus() {
+		case enums.WORKFLOW_EXECUTION_STATUS_COMPLETED:
+			var outputs map[string]interface{}
+			if err := a.Client.GetWorkflow(ctx, id, "").Get(ctx, &outputs); err != nil {
+				return nil, fmt.Errorf("failed to read result of workflow %s: %v", id, err)
+			}
+			states[name] = WorkspaceState{Finished: true, Outputs: outputs}
+
+		case enums.WORKFLOW_EXECUTION_STATUS_RUNNING:
+			state, err := a.queryState(ctx, id, params.QueryType)
+			if err != nil {
+				return nil, err
+			}
+			state.Running = true
+			states[name] = state
+
+		default:
+			// Closed without a result (e.g. terminated along with the previous
+			// run while hosting children). Closed workflows can still be queried,
+			// so keep the outputs if terraform had finished.
+			state, err := a.queryState(ctx, id, params.QueryType)
+			if err != nil || !state.Finished {
+				continue
+			}
+			state.Running = false
+			states[name] = state
+		}
+	}
+	return states, nil
+}
+
+func (a *OrchestrationActivities) queryState(ctx context.Context, id, queryType string) (WorkspaceState, error) {
+	var state WorkspaceState
+	value, err := a.Client.QueryWorkflow(ctx, id, "", queryType)
+	if err != nil {
+		return state, fmt.Errorf("failed to query workflow %s: %v", id, err)
+	}
+	if err := value.Get(&state); err != nil {
+		return state, fmt.Errorf("failed to decode state of workflow %s: %v", id, err)
+	}
+	return state, nil
+}
@@ -0,0 +1,74 @@
+package activities
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/mocks"
+)
+
+func describeResponse(status enums.WorkflowExecutionStatus) *workflowservice.DescribeWorkflowExecutionResponse {
+	return &workflowservice.DescribeWorkflowExecutionResponse{
+		WorkflowExecutionInfo: &workflowpb.WorkflowExecutionInfo{Status: status},
+	}
+}
+
+func TestReconcileWorkspaces(t *testing.T) {
+	c := &mocks.Client{}
+
+	// Completed: outputs come from the workflow result
+	c.On("DescribeWorkflowExecution", mock.Anything, "iac-run-vpc", "").
+		Return(describeResponse(enums.WORKFLOW_EXECUTION_STATUS_COMPLETED), nil)
+	run := &mocks.WorkflowRun{}
+	run.On("Get", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(1).(*map[string]interface{}) = map[string]interface{}{"vpc_id": "vpc-123"}
+	}).Return(nil)
+	c.On("GetWorkflow", mock.Anything, "iac-run-vpc", "").Return(run)
+
+	// Running: state comes from the query handler
+	c.On("DescribeWorkflowExecution", mock.Anything, "iac-run-eks", "").
+		Return(describeResponse(enums.WORKFLOW_EXECUTION_STATUS_RUNNING), nil)
+	value := &mocks.Value{}
+	value.On("Get", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*WorkspaceState) = WorkspaceState{Finished: true, Outputs: map[string]interface{}{"cluster": "eks-1"}}
+	}).Return(nil)
+	c.On("QueryWorkflow", mock.Anything, "iac-run-eks", "", "workspace-state").Return(value, nil)
+
+	// Failed: the query reports terraform did not finish, so it runs again
+	c.On("DescribeWorkflowExecution", mock.Anything, "iac-run-db", "").
+		Return(describeResponse(enums.WORKFLOW_EXECUTION_STATUS_FAILED), nil)
+	failed := &mocks.Value{}
+	failed.On("Get", mock.Anything).Return(nil)
+	c.On("QueryWorkflow", mock.Anything, "iac-run-db", "", "workspace-state").Return(failed, nil)
+
+	// Never started
+	c.On("DescribeWorkflowExecution", mock.Anything, "iac-run-apps", "").
+		Return(nil, serviceerror.NewNotFound("not found"))
+
+	act := &OrchestrationActivities{Client: c}
+	states, err := act.ReconcileWorkspaces(context.Background(), ReconcileParams{
+		WorkflowIDs: map[string]string{
+			"vpc":  "iac-run-vpc",
+			"eks":  "iac-run-eks",
+			"db":   "iac-run-db",
+			"apps": "iac-run-apps",
+		},
+		QueryType: "workspace-state",
+	})
+	require.NoError(t, err)
+	require.Len(t, states, 2)
+	require.Equal(t, WorkspaceState{Finished: true, Outputs: map[string]interface{}{"vpc_id": "vpc-123"}}, states["vpc"])
+	require.Equal(t, WorkspaceState{Running: true, Finished: true, Outputs: map[string]interface{}{"cluster": "eks-1"}}, states["eks"])
+}
+
+func TestReconcileWorkspacesRequiresClient(t *testing.T) {
+	act := &OrchestrationActivities{}
+	_, err := act.ReconcileWorkspaces(context.Background(), ReconcileParams{})
+	require.Error(t, err)
+}
@@ -0,0 +1,57 @@
+package activities
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// defaultMaxOutputBytes bounds the terraform output embedded in errors when
+// TerraformParams.MaxOutputBytes is unset. Errors end up in Temporal event
+// payloads and logs, so a verbose apply must not be copied in full.
+const defaultMaxOutputBytes = 16 * 1024
+
+// errorOutput prepares captured terraform output for an error message: the
+// full output is saved under OutputLogDir when configured, and the returned
+// text is truncated to the configured head+tail window.
+func errorOutput(params TerraformParams, subcommand string, output []byte) string {
+	text := truncateOutput(output, params.MaxOutputBytes)
+	if params.OutputLogDir == "" {
+		return text
+	}
+
+	path, err := saveOutput(params.OutputLogDir, subcommand, output)
+	if err != nil {
+		return fmt.Sprintf("%s (failed to save full output: %v)", text, err)
+	}
+	return fmt.Sprintf("%s (full output: %s)", text, path)
+}
+
+// truncateOutput keeps the first and last parts of output, which hold the
+// command's context and its final errors, replacing the middle with a marker.
+// Output within max bytes is returned unchanged; max <= 0 uses the default.
+func truncateOutput(output []byte, max int) string {
+	if max <= 0 {
+		max = defaultMaxOutputBytes
+	}
+	if len(output) <= max {
+		return string(output)
+	}
+
+	head := max / 2
+	tail := max - head
+	return fmt.Sprintf("%s\n[...truncated %d bytes...]\n%s", output[:head], len(output)-max, output[len(output)-tail:])
+}
+
+// saveOutput writes the full output of a terraform subcommand to a new file in dir.
+func saveOutput(dir, subcommand string, output []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("terraform-%s-%d.log", subcommand, time.Now().UnixNano()))
+	if err := os.WriteFile(path, output, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
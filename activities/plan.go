@@ -0,0 +1,213 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// supportedPlanFormatMajor is the plan JSON format_version major version
+// this package understands. Terraform only adds fields within a major version.
+const supportedPlanFormatMajor = "1"
+
+// planJSON is the subset of `terraform show -json <planfile>` read here.
+type planJSON struct {
+	FormatVersion   string           `json:"format_version"`
+	ResourceChanges []resourceChange `json:"resource_changes"`
+}
+
+type resourceChange struct {
+	Address string `json:"address"`
+	Change  struct {
+		Actions      []string    `json:"actions"`
+		Before       interface{} `json:"before"`
+		After        interface{} `json:"after"`
+		AfterUnknown interface{} `json:"after_unknown"`
+	} `json:"change"`
+}
+
+// TerraformShowPlan returns the saved plan rendered by `terraform show -json`.
+func (a *TerraformActivities) TerraformShowPlan(ctx context.Context, params TerraformParams) (json.RawMessage, error) {
+	if err := validatePaths(params); err != nil {
+		return nil, err
+	}
+	planPath := planFullPath(params)
+	if _, err := os.Stat(planPath); err != nil {
+		return nil, fmt.Errorf("plan file not found for show: %s", planPath)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	output, err := terraformCommand(ctx, params, "show", "-json", planPath).Output()
+	if err != nil {
+		return nil, fmt.Errorf("terraform show failed: %v, output: %s", err, errorOutput(params, "show", output))
+	}
+	if !isJSON(output) {
+		return nil, fmt.Errorf("terraform show returned non-JSON output: %s", errorOutput(params, "show", output))
+	}
+	if _, err := parsePlanJSON(output); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(output), nil
+}
+
+// PlanSummary describes what a saved plan will change: the rendered plan
+// text and the resource counts from its JSON form.
+type PlanSummary struct {
+	Text    string `json:"text"`
+	Add     int    `json:"add"`
+	Change  int    `json:"change"`
+	Destroy int    `json:"destroy"`
+}
+
+// TerraformPlanSummary renders the saved plan as text for review and counts
+// the resources it adds, changes and destroys. The text is bounded like
+// error output, by MaxOutputBytes.
+func (a *TerraformActivities) TerraformPlanSummary(ctx context.Context, params TerraformParams) (PlanSummary, error) {
+	plan, err := a.TerraformShowPlan(ctx, params)
+	if err != nil {
+		return PlanSummary{}, err
+	}
+	parsed, err := parsePlanJSON(plan)
+	if err != nil {
+		return PlanSummary{}, err
+	}
+	summary := countPlanChanges(parsed)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	output, err := terraformCommand(ctx, params, "show", "-no-color", planFullPath(params)).Output()
+	if err != nil {
+		return PlanSummary{}, fmt.Errorf("terraform show failed: %v, output: %s", err, errorOutput(params, "show", output))
+	}
+	summary.Text = truncateOutput(output, params.MaxOutputBytes)
+	return summary, nil
+}
+
+// countPlanChanges counts resource changes the way terraform's plan summary
+// does: a replacement is one add and one destroy; reads and no-ops are not counted.
+func countPlanChanges(plan planJSON) PlanSummary {
+	var summary PlanSummary
+	for _, rc := range plan.ResourceChanges {
+		for _, action := range rc.Change.Actions {
+			switch action {
+			case "create":
+				summary.Add++
+			case "update":
+				summary.Change++
+			case "delete":
+				summary.Destroy++
+			}
+		}
+	}
+	return summary
+}
+
+// PlannedAttribute extracts the value a resource attribute will have after
+// apply from plan JSON produced by TerraformShowPlan. The path uses dots for
+// object keys and either dots or brackets for list indexes, e.g.
+// "ingress[0].cidr_blocks" or "tags.Name".
+//
+// found is false when the plan has no change for the address or the
+// attribute is absent (including resources being deleted). Values that are
+// only known after apply return an error.
+func PlannedAttribute(plan []byte, address, path string) (value interface{}, found bool, err error) {
+	parsed, err := parsePlanJSON(plan)
+	if err != nil {
+		return nil, false, err
+	}
+	steps, err := parseAttributePath(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, rc := range parsed.ResourceChanges {
+		if rc.Address != address {
+			continue
+		}
+		if afterUnknown(rc.Change.AfterUnknown, steps) {
+			return nil, false, fmt.Errorf("%s.%s is known only after apply", address, path)
+		}
+		value, found = lookupAttribute(rc.Change.After, steps)
+		return value, found, nil
+	}
+	return nil, false, nil
+}
+
+// parsePlanJSON decodes plan JSON, rejecting format versions whose layout
+// may differ from what this package expects.
+func parsePlanJSON(data []byte) (planJSON, error) {
+	var plan planJSON
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return plan, fmt.Errorf("failed to parse plan JSON: %v", err)
+	}
+	if plan.FormatVersion == "" {
+		return plan, fmt.Errorf("plan JSON has no format_version")
+	}
+	if major, _, _ := strings.Cut(plan.FormatVersion, "."); major != supportedPlanFormatMajor {
+		return plan, fmt.Errorf("unsupported plan JSON format_version %q: supported versions are %s.x", plan.FormatVersion, supportedPlanFormatMajor)
+	}
+	return plan, nil
+}
+
+// parseAttributePath splits "a.b[0].c" into ["a", "b", "0", "c"].
+func parseAttributePath(path string) ([]string, error) {
+	if strings.TrimSpace(path) == "" {
+		return nil, fmt.Errorf("attribute path is required")
+	}
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+
+	steps := strings.Split(path, ".")
+	for _, step := range steps {
+		if step == "" {
+			return nil, fmt.Errorf("invalid attribute path %q", path)
+		}
+	}
+	return steps, nil
+}
+
+func lookupAttribute(value interface{}, steps []string) (interface{}, bool) {
+	for _, step := range steps {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[step]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []interface{}:
+			i, err := strconv.Atoi(step)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// afterUnknown reports whether the attribute, or any object or list
+// containing it, is marked unknown in the plan's after_unknown tree.
+func afterUnknown(unknown interface{}, steps []string) bool {
+	for i := 0; i <= len(steps); i++ {
+		if unknown == true {
+			return true
+		}
+		if i == len(steps) {
+			break
+		}
+		next, ok := lookupAttribute(unknown, steps[i:i+1])
+		if !ok {
+			return false
+		}
+		unknown = next
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PlanStore receives saved plan files for auditing, e.g. an S3 or GCS
+// bucket. Keys are slash-separated paths chosen by the workflow.
+type PlanStore interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+}
+
+// NoopPlanStore discards plans. It is the worker's default, so persistPlan
+// is harmless until a real store is configured.
+type NoopPlanStore struct{}
+
+func (NoopPlanStore) Put(ctx context.Context, key string, r io.Reader) error {
+	return nil
+}
+
+// FilePlanStore writes each plan to Root/<key>, creating directories as needed.
+type FilePlanStore struct {
+	Root string
+}
+
+func (s FilePlanStore) Put(ctx context.Context, key string, r io.Reader) error {
+	if strings.TrimSpace(s.Root) == "" {
+		return fmt.Errorf("plan store root is required")
+	}
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("invalid plan key %q: must be a relative path inside the store", key)
+	}
+
+	path := filepath.Join(s.Root, clean)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create plan store directory: %v", err)
+	}
+	// Write to a temp file first so a failed copy never leaves a partial plan under the key
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".plan-*")
+	if err != nil {
+		return fmt.Errorf("failed to create plan file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write plan %s: %v", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write plan %s: %v", key, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store plan %s: %v", key, err)
+	}
+	return nil
+}
+
+// TerraformSavePlan uploads the workspace's saved plan file to the worker's
+// PlanStore under key. It reports false, without reading the plan, when the
+// worker uses NoopPlanStore and so would discard it.
+func (a *TerraformActivities) TerraformSavePlan(ctx context.Context, params TerraformParams, key string) (bool, error) {
+	if a == nil || a.PlanStore == nil {
+		return false, fmt.Errorf("no plan store configured on this worker")
+	}
+	switch a.PlanStore.(type) {
+	case NoopPlanStore, *NoopPlanStore:
+		return false, nil
+	}
+	planPath := planFullPath(params)
+	f, err := os.Open(planPath)
+	if err != nil {
+		return false, fmt.Errorf("plan file not found for save: %s", planPath)
+	}
+	defer f.Close()
+
+	if err := a.PlanStore.Put(ctx, key, f); err != nil {
+		return false, fmt.Errorf("failed to save plan %s: %v", key, err)
+	}
+	return true, nil
+}
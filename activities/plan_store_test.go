@@ -0,0 +1,104 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePlanStorePutWritesUnderRoot(t *testing.T) {
+	root := t.TempDir()
+	store := FilePlanStore{Root: root}
+
+	require.NoError(t, store.Put(context.Background(), "wf-1/run-1/vpc.tfplan", strings.NewReader("plan-bytes")))
+
+	data, err := os.ReadFile(filepath.Join(root, "wf-1", "run-1", "vpc.tfplan"))
+	require.NoError(t, err)
+	require.Equal(t, "plan-bytes", string(data))
+
+	// A second put replaces the plan, leaving no temp files behind
+	require.NoError(t, store.Put(context.Background(), "wf-1/run-1/vpc.tfplan", strings.NewReader("newer")))
+	entries, err := os.ReadDir(filepath.Join(root, "wf-1", "run-1"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestFilePlanStorePutRejectsEscapingKeys(t *testing.T) {
+	store := FilePlanStore{Root: t.TempDir()}
+	for _, key := range []string{"", "../outside.tfplan", "/etc/plan", "a/../../b"} {
+		err := store.Put(context.Background(), key, strings.NewReader("x"))
+		require.Error(t, err, key)
+		require.Contains(t, err.Error(), "invalid plan key")
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("read failed") }
+
+func TestFilePlanStorePutLeavesNoPartialPlan(t *testing.T) {
+	root := t.TempDir()
+	store := FilePlanStore{Root: root}
+
+	err := store.Put(context.Background(), "vpc.tfplan", io.MultiReader(strings.NewReader("partial"), failingReader{}))
+	require.Error(t, err)
+
+	entries, err := os.ReadDir(root)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+// recordingPlanStore keeps each stored plan in memory.
+type recordingPlanStore map[string]string
+
+func (s recordingPlanStore) Put(ctx context.Context, key string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s[key] = string(data)
+	return nil
+}
+
+func TestTerraformSavePlanUploadsPlanFile(t *testing.T) {
+	tmp := t.TempDir()
+	params := TerraformParams{Dir: tmp, PlanFile: "test.plan"}
+	require.NoError(t, os.WriteFile(planFullPath(params), []byte("saved-plan"), 0o600))
+
+	store := recordingPlanStore{}
+	act := &TerraformActivities{PlanStore: store}
+	saved, err := act.TerraformSavePlan(context.Background(), params, "wf/run/vpc.tfplan")
+	require.NoError(t, err)
+	require.True(t, saved)
+	require.Equal(t, recordingPlanStore{"wf/run/vpc.tfplan": "saved-plan"}, store)
+}
+
+func TestTerraformSavePlanReportsDiscardedPlan(t *testing.T) {
+	params := TerraformParams{Dir: t.TempDir(), PlanFile: "test.plan"}
+
+	act := &TerraformActivities{PlanStore: NoopPlanStore{}}
+	saved, err := act.TerraformSavePlan(context.Background(), params, "wf/run/vpc.tfplan")
+	require.NoError(t, err)
+	require.False(t, saved)
+}
+
+func TestTerraformSavePlanErrors(t *testing.T) {
+	tmp := t.TempDir()
+	params := TerraformParams{Dir: tmp, PlanFile: "missing.plan"}
+
+	var unconfigured *TerraformActivities
+	_, err := unconfigured.TerraformSavePlan(context.Background(), params, "key")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no plan store configured")
+
+	act := &TerraformActivities{PlanStore: recordingPlanStore{}}
+	_, err = act.TerraformSavePlan(context.Background(), params, "key")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "plan file not found for save")
+}
@@ -0,0 +1,90 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// ProtectedResourceErrorType is the ApplicationError type returned when a
+// plan would delete or replace a protected resource. Retrying can't help, so
+// the error is non-retryable.
+const ProtectedResourceErrorType = "ProtectedResourceChange"
+
+// TerraformCheckProtectedResources fails if the saved plan deletes or
+// replaces a resource matching one of patterns. Patterns are resource
+// addresses where `*` matches any run of characters; a pattern without a
+// wildcard also covers the address's instances and, for modules, the
+// resources inside it (e.g. "module.db" covers "module.db.aws_db_instance.main[0]").
+func (a *TerraformActivities) TerraformCheckProtectedResources(ctx context.Context, params TerraformParams, patterns []string) error {
+	plan, err := a.TerraformShowPlan(ctx, params)
+	if err != nil {
+		return err
+	}
+	parsed, err := parsePlanJSON(plan)
+	if err != nil {
+		return err
+	}
+	blocked, err := protectedChanges(parsed, patterns)
+	if err != nil {
+		return err
+	}
+	if len(blocked) > 0 {
+		return temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("plan would destroy protected resources: %s", strings.Join(blocked, ", ")),
+			ProtectedResourceErrorType, nil)
+	}
+	return nil
+}
+
+// protectedChanges lists the deleted or replaced resources matching
+// patterns, as "address (delete)" or "address (replace)", in plan order.
+func protectedChanges(plan planJSON, patterns []string) ([]string, error) {
+	matchers := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		re, err := addressPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		matchers[i] = re
+	}
+
+	var blocked []string
+	for _, rc := range plan.ResourceChanges {
+		if !slices.Contains(rc.Change.Actions, "delete") {
+			continue
+		}
+		action := "delete"
+		if slices.Contains(rc.Change.Actions, "create") {
+			action = "replace"
+		}
+		for _, re := range matchers {
+			if re.MatchString(rc.Address) {
+				blocked = append(blocked, fmt.Sprintf("%s (%s)", rc.Address, action))
+				break
+			}
+		}
+	}
+	return blocked, nil
+}
+
+// addressPattern compiles a protected resource pattern. Addresses contain
+// brackets and quotes, so only `*` is special.
+func addressPattern(pattern string) (*regexp.Regexp, error) {
+	if strings.TrimSpace(pattern) == "" {
+		return nil, fmt.Errorf("protected resource pattern cannot be empty")
+	}
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	expr := strings.Join(parts, ".*")
+	if !strings.Contains(pattern, "*") {
+		expr += `([\[.].*)?`
+	}
+	return regexp.Compile("^" + expr + "$")
+}
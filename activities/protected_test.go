@@ -0,0 +1,67 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestTerraformCheckProtectedResources(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	// The fixture plan creates aws_vpc.main and deletes aws_subnet.old
+	fixture, err := filepath.Abs(filepath.Join("testdata", "plan.json"))
+	require.NoError(t, err)
+	t.Setenv("TF_SHOW_JSON", fixture)
+
+	a := &TerraformActivities{}
+	tmp := t.TempDir()
+	params := TerraformParams{Dir: tmp}
+	require.NoError(t, os.WriteFile(filepath.Join(tmp, "tfplan"), []byte("plan"), 0o644))
+
+	t.Run("deleting a protected resource is blocked", func(t *testing.T) {
+		err := a.TerraformCheckProtectedResources(context.Background(), params, []string{"aws_db_instance.*", "aws_subnet.*"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "plan would destroy protected resources: aws_subnet.old (delete)")
+
+		var appErr *temporal.ApplicationError
+		require.True(t, errors.As(err, &appErr))
+		require.Equal(t, ProtectedResourceErrorType, appErr.Type())
+		require.True(t, appErr.NonRetryable())
+	})
+
+	t.Run("a safe plan passes", func(t *testing.T) {
+		// aws_vpc.main is only created
+		require.NoError(t, a.TerraformCheckProtectedResources(context.Background(), params, []string{"aws_vpc.main"}))
+	})
+}
+
+func TestProtectedChanges(t *testing.T) {
+	plan, err := parsePlanJSON([]byte(`{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "module.db.aws_db_instance.main[0]", "change": {"actions": ["delete", "create"]}},
+    {"address": "aws_s3_bucket.logs", "change": {"actions": ["delete"]}},
+    {"address": "aws_s3_bucket.logs_archive", "change": {"actions": ["delete"]}},
+    {"address": "aws_instance.web[\"a\"]", "change": {"actions": ["update"]}},
+    {"address": "aws_instance.web[\"b\"]", "change": {"actions": ["create", "delete"]}}
+  ]
+}`))
+	require.NoError(t, err)
+
+	// Exact addresses cover instances and module contents, but not similarly named resources
+	blocked, err := protectedChanges(plan, []string{"module.db", "aws_s3_bucket.logs", `aws_instance.web["*"]`})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"module.db.aws_db_instance.main[0] (replace)",
+		"aws_s3_bucket.logs (delete)",
+		`aws_instance.web["b"] (replace)`,
+	}, blocked)
+
+	_, err = protectedChanges(plan, []string{" "})
+	require.Error(t, err)
+}
@@ -0,0 +1,100 @@
+package activities
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"go.temporal.io/sdk/temporal"
+)
+
+// defaultProviderRegistry is the host terraform assumes for short provider
+// sources such as "hashicorp/aws".
+const defaultProviderRegistry = "registry.terraform.io"
+
+// ProviderPolicyErrorType is the ApplicationError type returned when a
+// module's providers break the provider policy. Retrying can't help, so the
+// error is non-retryable.
+const ProviderPolicyErrorType = "ProviderPolicyViolation"
+
+// lockFileProviders returns the provider source addresses recorded in a
+// module's .terraform.lock.hcl, which terraform init writes or updates.
+func lockFileProviders(moduleDir string) ([]string, error) {
+	lockPath := filepath.Join(moduleDir, ".terraform.lock.hcl")
+	if _, err := os.Stat(lockPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // no providers required
+		}
+		return nil, fmt.Errorf("failed to read provider lock file: %v", err)
+	}
+
+	file, diags := hclparse.NewParser().ParseHCLFile(lockPath)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse provider lock file: %v", diags.Error())
+	}
+
+	content, _, diags := file.Body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: "provider", LabelNames: []string{"source"}}},
+	})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse provider lock file: %v", diags.Error())
+	}
+
+	providers := make([]string, 0, len(content.Blocks))
+	for _, block := range content.Blocks {
+		providers = append(providers, block.Labels[0])
+	}
+	sort.Strings(providers)
+	return providers, nil
+}
+
+// checkProviderPolicy fails when a provider matches a deny pattern, or when
+// an allowlist is configured and the provider matches none of its patterns.
+// Patterns use path.Match syntax (e.g. "hashicorp/*"), where `*` stops at
+// `/`; short sources are expanded with the default registry before
+// matching, and a lone "*" matches every provider.
+func checkProviderPolicy(providers, allow, deny []string) error {
+	var violations []string
+	for _, provider := range providers {
+		if pattern, ok := matchProvider(provider, deny); ok {
+			violations = append(violations, fmt.Sprintf("%s is denied by %s", provider, pattern))
+			continue
+		}
+		if len(allow) > 0 {
+			if _, ok := matchProvider(provider, allow); !ok {
+				violations = append(violations, fmt.Sprintf("%s is not in the allowed providers", provider))
+			}
+		}
+	}
+	if len(violations) > 0 {
+		return temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("provider policy violation: %s", strings.Join(violations, "; ")), ProviderPolicyErrorType, nil)
+	}
+	return nil
+}
+
+func matchProvider(provider string, patterns []string) (string, bool) {
+	for _, pattern := range patterns {
+		if pattern == "*" {
+			return pattern, true
+		}
+		if ok, _ := path.Match(normalizeProviderSource(pattern), provider); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// normalizeProviderSource expands a short provider source ("hashicorp/aws")
+// to its full address ("registry.terraform.io/hashicorp/aws").
+func normalizeProviderSource(source string) string {
+	if strings.Count(source, "/") == 1 {
+		return defaultProviderRegistry + "/" + source
+	}
+	return source
+}
@@ -0,0 +1,249 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"go.temporal.io/sdk/temporal"
+)
+
+// DefaultTFCAddress is the Terraform Cloud API host used when none is configured.
+const DefaultTFCAddress = "https://app.terraform.io"
+
+// DefaultTFCTokenEnv is the worker environment variable holding the
+// Terraform Cloud API token when none is configured.
+const DefaultTFCTokenEnv = "TFE_TOKEN"
+
+// RemoteVarsNotAllowedErrorType is the non-retryable ApplicationError type
+// returned when a config asks for an address or token variable the worker
+// doesn't allow.
+const RemoteVarsNotAllowedErrorType = "RemoteVarsNotAllowed"
+
+// RemoteVarsParams identifies a Terraform Cloud/Enterprise variable source:
+// either a workspace's variables or a variable set.
+type RemoteVarsParams struct {
+	Address     string // API host, defaults to DefaultTFCAddress
+	WorkspaceID string // ws-... (mutually exclusive with VarSetID)
+	VarSetID    string // varset-...
+
+	// TokenEnv names the environment variable holding the API token on the
+	// worker, so the token never appears in workflow history. Defaults to
+	// DefaultTFCTokenEnv.
+	TokenEnv string
+}
+
+// RemoteVarsPolicy is the worker operator's allowlist for FetchRemoteVars.
+// Configs come from callers, so without it a config could have the worker
+// send any of its environment variables, as a bearer token, to any host.
+type RemoteVarsPolicy struct {
+	// Addresses are the API addresses (scheme and host, e.g.
+	// "https://tfe.example.com") that may be called besides
+	// DefaultTFCAddress.
+	Addresses []string
+
+	// TokenEnvs are the environment variables that may be read as tokens
+	// besides DefaultTFCTokenEnv.
+	TokenEnvs []string
+}
+
+// check returns the address and token variable to use for params, or an
+// error if the policy doesn't allow them.
+func (p RemoteVarsPolicy) check(params RemoteVarsParams) (string, string, error) {
+	address := strings.TrimRight(params.Address, "/")
+	if address == "" {
+		address = DefaultTFCAddress
+	}
+	origin, err := apiOrigin(address)
+	if err != nil {
+		return "", "", temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("invalid remote vars address %q: %v", params.Address, err), RemoteVarsNotAllowedErrorType, nil)
+	}
+	allowed := origin == DefaultTFCAddress
+	for _, a := range p.Addresses {
+		if o, err := apiOrigin(strings.TrimRight(a, "/")); err == nil && o == origin {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return "", "", temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("remote vars address %s is not allowed on this worker", origin), RemoteVarsNotAllowedErrorType, nil)
+	}
+
+	tokenEnv := params.TokenEnv
+	if tokenEnv == "" {
+		tokenEnv = DefaultTFCTokenEnv
+	}
+	if tokenEnv != DefaultTFCTokenEnv && !slices.Contains(p.TokenEnvs, tokenEnv) {
+		return "", "", temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("remote vars token variable %s is not allowed on this worker", tokenEnv), RemoteVarsNotAllowedErrorType, nil)
+	}
+	return origin, tokenEnv, nil
+}
+
+// apiOrigin reduces an API address to its scheme and host, rejecting
+// anything else, such as credentials or a path, that could change where
+// the request goes.
+func apiOrigin(address string) (string, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", err
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", fmt.Errorf("want an http(s) URL with a host")
+	}
+	if u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("want only a scheme and host")
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host), nil
+}
+
+// RemoteVars are the terraform variables fetched from a remote source.
+type RemoteVars struct {
+	Values map[string]interface{}
+
+	// Sensitive lists sensitive variables. Their values are write-only in the
+	// API, so they can't be merged; they are reported by name only.
+	Sensitive []string
+}
+
+type tfcVarsResponse struct {
+	Data []struct {
+		Attributes struct {
+			Key       string  `json:"key"`
+			Value     *string `json:"value"`
+			Category  string  `json:"category"`
+			HCL       bool    `json:"hcl"`
+			Sensitive bool    `json:"sensitive"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Links struct {
+		Next string `json:"next"`
+	} `json:"links"`
+}
+
+// FetchRemoteVars reads the terraform-category variables of a Terraform
+// Cloud workspace or variable set. HCL-typed values are decoded into their
+// structured form; environment variables are ignored. The address and
+// token variable must be allowed by the worker's RemoteVars policy.
+func (a *TerraformActivities) FetchRemoteVars(ctx context.Context, params RemoteVarsParams) (RemoteVars, error) {
+	address, tokenEnv, err := a.RemoteVars.check(params)
+	if err != nil {
+		return RemoteVars{}, err
+	}
+	token := os.Getenv(tokenEnv)
+	if token == "" {
+		return RemoteVars{}, fmt.Errorf("remote vars token not set: environment variable %s is empty", tokenEnv)
+	}
+
+	var endpoint string
+	switch {
+	case params.WorkspaceID != "":
+		endpoint = fmt.Sprintf("%s/api/v2/workspaces/%s/vars", address, url.PathEscape(params.WorkspaceID))
+	case params.VarSetID != "":
+		endpoint = fmt.Sprintf("%s/api/v2/varsets/%s/relationships/vars", address, url.PathEscape(params.VarSetID))
+	default:
+		return RemoteVars{}, fmt.Errorf("remote vars require a workspace ID or variable set ID")
+	}
+
+	vars := RemoteVars{Values: make(map[string]interface{})}
+	for endpoint != "" {
+		page, err := fetchTFCVars(ctx, endpoint, token)
+		if err != nil {
+			return RemoteVars{}, err
+		}
+		for _, item := range page.Data {
+			attr := item.Attributes
+			if attr.Category != "terraform" {
+				continue
+			}
+			if attr.Sensitive || attr.Value == nil {
+				vars.Sensitive = append(vars.Sensitive, attr.Key)
+				continue
+			}
+			value, err := decodeRemoteVar(*attr.Value, attr.HCL)
+			if err != nil {
+				return RemoteVars{}, fmt.Errorf("failed to decode remote variable %s: %v", attr.Key, err)
+			}
+			vars.Values[attr.Key] = value
+		}
+		if endpoint, err = nextPage(address, endpoint, page.Links.Next); err != nil {
+			return RemoteVars{}, err
+		}
+	}
+	sort.Strings(vars.Sensitive)
+	return vars, nil
+}
+
+// nextPage resolves a pagination link against the current page and makes
+// sure it stays on the allowed origin, so a response can't redirect the
+// token to another host.
+func nextPage(origin, current, next string) (string, error) {
+	if next == "" {
+		return "", nil
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return "", fmt.Errorf("invalid remote vars page %q: %v", current, err)
+	}
+	ref, err := url.Parse(next)
+	if err != nil {
+		return "", fmt.Errorf("invalid remote vars next page %q: %v", next, err)
+	}
+	u := base.ResolveReference(ref)
+	if u.User != nil || strings.ToLower(u.Scheme+"://"+u.Host) != origin {
+		return "", temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("remote vars next page %s is not on %s", u.Redacted(), origin), RemoteVarsNotAllowedErrorType, nil)
+	}
+	return u.String(), nil
+}
+
+func fetchTFCVars(ctx context.Context, endpoint, token string) (tfcVarsResponse, error) {
+	var page tfcVarsResponse
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return page, fmt.Errorf("failed to build remote vars request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/vnd.api+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return page, fmt.Errorf("failed to fetch remote vars: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return page, fmt.Errorf("failed to fetch remote vars: %s returned %s", endpoint, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return page, fmt.Errorf("failed to parse remote vars response: %v", err)
+	}
+	return page, nil
+}
+
+// decodeRemoteVar returns a plain string as-is, or evaluates an HCL value
+// (lists, maps, numbers, ...) into its Go form.
+func decodeRemoteVar(value string, isHCL bool) (interface{}, error) {
+	if !isHCL {
+		return value, nil
+	}
+	expr, diags := hclsyntax.ParseExpression([]byte(value), "remote-var", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("%s", diags.Error())
+	}
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("%s", diags.Error())
+	}
+	return ctyToGo(val)
+}
@@ -0,0 +1,167 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+// fakeTFCServer serves a workspace's variables over two pages, the way the
+// Terraform Cloud API paginates.
+func fakeTFCServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/api/v2/workspaces/ws-123/vars" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprint(w, `{"data":[
+				{"attributes":{"key":"db_password","value":null,"category":"terraform","sensitive":true}}
+			]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"data":[
+			{"attributes":{"key":"region","value":"us-west-2","category":"terraform"}},
+			{"attributes":{"key":"azs","value":"[\"us-west-2a\", \"us-west-2b\"]","category":"terraform","hcl":true}},
+			{"attributes":{"key":"instance_count","value":"3","category":"terraform","hcl":true}},
+			{"attributes":{"key":"AWS_REGION","value":"us-west-2","category":"env"}}
+		],"links":{"next":"%s/api/v2/workspaces/ws-123/vars?page=2"}}`, srv.URL)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// testRemoteVarsPolicy allows the fake server and its token variable.
+func testRemoteVarsPolicy(srv *httptest.Server) RemoteVarsPolicy {
+	return RemoteVarsPolicy{Addresses: []string{srv.URL}, TokenEnvs: []string{"TEST_TFE_TOKEN"}}
+}
+
+func TestFetchRemoteVars(t *testing.T) {
+	srv := fakeTFCServer(t)
+	t.Setenv("TEST_TFE_TOKEN", "test-token")
+
+	act := &TerraformActivities{RemoteVars: testRemoteVarsPolicy(srv)}
+	vars, err := act.FetchRemoteVars(context.Background(), RemoteVarsParams{
+		Address:     srv.URL,
+		WorkspaceID: "ws-123",
+		TokenEnv:    "TEST_TFE_TOKEN",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]interface{}{
+		"region":         "us-west-2",
+		"azs":            []interface{}{"us-west-2a", "us-west-2b"},
+		"instance_count": float64(3),
+	}, vars.Values)
+	require.Equal(t, []string{"db_password"}, vars.Sensitive)
+
+	// Remote values land in the combined tfvars like any other extra vars
+	tempRoot := t.TempDir()
+	combined, err := createCombinedTFVars(TerraformParams{
+		Vars:     vars.Values,
+		RunID:    "remote",
+		TempRoot: tempRoot,
+	})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(combined)
+	require.NoError(t, err)
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &got))
+	require.Equal(t, "us-west-2", got["region"])
+	require.Equal(t, []interface{}{"us-west-2a", "us-west-2b"}, got["azs"])
+	require.NotContains(t, got, "db_password")
+	require.NotContains(t, got, "AWS_REGION")
+}
+
+func TestFetchRemoteVars_Errors(t *testing.T) {
+	srv := fakeTFCServer(t)
+	act := &TerraformActivities{RemoteVars: testRemoteVarsPolicy(srv)}
+
+	t.Run("missing token", func(t *testing.T) {
+		t.Setenv("TEST_TFE_TOKEN", "")
+		_, err := act.FetchRemoteVars(context.Background(), RemoteVarsParams{Address: srv.URL, WorkspaceID: "ws-123", TokenEnv: "TEST_TFE_TOKEN"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "TEST_TFE_TOKEN is empty")
+	})
+
+	t.Run("rejected token", func(t *testing.T) {
+		t.Setenv("TEST_TFE_TOKEN", "wrong-token")
+		_, err := act.FetchRemoteVars(context.Background(), RemoteVarsParams{Address: srv.URL, WorkspaceID: "ws-123", TokenEnv: "TEST_TFE_TOKEN"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "401")
+		require.NotContains(t, err.Error(), "wrong-token", "the token must not leak into errors")
+	})
+
+	t.Run("no source", func(t *testing.T) {
+		t.Setenv("TEST_TFE_TOKEN", "test-token")
+		_, err := act.FetchRemoteVars(context.Background(), RemoteVarsParams{Address: srv.URL, TokenEnv: "TEST_TFE_TOKEN"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "workspace ID or variable set ID")
+	})
+
+	t.Run("address not allowed", func(t *testing.T) {
+		t.Setenv("TEST_TFE_TOKEN", "test-token")
+		for _, address := range []string{
+			"https://attacker.example.com",
+			srv.URL + "/collect",
+			strings.Replace(srv.URL, "://", "://user@", 1),
+		} {
+			_, err := act.FetchRemoteVars(context.Background(), RemoteVarsParams{Address: address, WorkspaceID: "ws-123", TokenEnv: "TEST_TFE_TOKEN"})
+			var appErr *temporal.ApplicationError
+			require.ErrorAs(t, err, &appErr, address)
+			require.Equal(t, RemoteVarsNotAllowedErrorType, appErr.Type())
+			require.True(t, appErr.NonRetryable())
+		}
+	})
+
+	t.Run("token variable not allowed", func(t *testing.T) {
+		t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+		_, err := act.FetchRemoteVars(context.Background(), RemoteVarsParams{Address: srv.URL, WorkspaceID: "ws-123", TokenEnv: "AWS_SECRET_ACCESS_KEY"})
+		var appErr *temporal.ApplicationError
+		require.ErrorAs(t, err, &appErr)
+		require.Equal(t, RemoteVarsNotAllowedErrorType, appErr.Type())
+		require.NotContains(t, err.Error(), "secret")
+	})
+
+	t.Run("next page on another host", func(t *testing.T) {
+		t.Setenv("TEST_TFE_TOKEN", "test-token")
+		collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("next page request leaked to %s with Authorization %q", r.URL, r.Header.Get("Authorization"))
+		}))
+		defer collector.Close()
+		redirecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"data":[],"links":{"next":"%s/api/v2/workspaces/ws-123/vars?page=2"}}`, collector.URL)
+		}))
+		defer redirecting.Close()
+
+		act := &TerraformActivities{RemoteVars: RemoteVarsPolicy{Addresses: []string{redirecting.URL}, TokenEnvs: []string{"TEST_TFE_TOKEN"}}}
+		_, err := act.FetchRemoteVars(context.Background(), RemoteVarsParams{Address: redirecting.URL, WorkspaceID: "ws-123", TokenEnv: "TEST_TFE_TOKEN"})
+		var appErr *temporal.ApplicationError
+		require.ErrorAs(t, err, &appErr)
+		require.Equal(t, RemoteVarsNotAllowedErrorType, appErr.Type())
+	})
+
+	t.Run("default token variable", func(t *testing.T) {
+		t.Setenv("TFE_TOKEN", "test-token")
+		_, err := act.FetchRemoteVars(context.Background(), RemoteVarsParams{Address: srv.URL, WorkspaceID: "ws-123"})
+		require.NoError(t, err)
+	})
+}
@@ -7,110 +7,367 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/zclconf/go-cty/cty"
 	"github.com/zclconf/go-cty/cty/gocty"
+	"go.temporal.io/sdk/temporal"
 )
 
+// StateLockErrorType is the ApplicationError type returned when plan, apply
+// or destroy fail because another run holds the workspace's state lock. The
+// error is retryable, so the activity retry policy waits out the other run.
+const StateLockErrorType = "TerraformStateLocked"
+
+// stateLockFailure matches terraform's lock errors across backends.
+var stateLockFailure = regexp.MustCompile(`Error acquiring the state lock|Error locking state`)
+
 type TerraformParams struct {
 	Dir      string
 	TFVars   string
 	PlanFile string
 	Vars     map[string]interface{} // Preserves JSON types (string, array, object, etc.)
+	VarTypes map[string]string      // Optional type hints (string, number, bool) used to coerce Vars
 	RunID    string
+	TempRoot string // Optional root for generated artifacts (defaults to os.TempDir())
+
+	// Chdir is a module subdirectory (relative to Dir) passed to terraform's
+	// global -chdir flag. The process still runs from Dir.
+	Chdir string
+
+	// DetectOnly runs plan without -out, reporting only whether changes exist.
+	// No plan file is written, so it cannot be combined with apply.
+	DetectOnly bool
+
+	// LayerVarFiles passes TFVars and the extra Vars as separate -var-file
+	// flags (base first) instead of merging them into one file, leaving
+	// precedence to terraform.
+	LayerVarFiles bool
+
+	// PreferAutoTFVars lets terraform.tfvars and *.auto.tfvars in the module
+	// directory win over TFVars: values they set are dropped from the var
+	// files passed on the command line. Extra Vars still override them.
+	PreferAutoTFVars bool
+
+	// AllowedProviders and DeniedProviders restrict the providers recorded in
+	// the module's lock file after init. Entries are provider sources such as
+	// "hashicorp/aws" and may use path.Match wildcards.
+	AllowedProviders []string
+	DeniedProviders  []string
+
+	// Targets are passed to plan as -target flags, limiting it (and the
+	// apply of its plan file) to those resource addresses.
+	Targets []string
+
+	// Parallelism, when positive, is passed to plan, apply and destroy as
+	// -parallelism. Zero keeps terraform's default of 10.
+	Parallelism int
+
+	// LockTimeout is passed to plan, apply and destroy as -lock-timeout (a
+	// duration such as "30s"). Unset keeps terraform's default of 0s: fail
+	// immediately if the state is locked.
+	LockTimeout string
+
+	// DisableLock runs detect-only plans and drift checks with -lock=false,
+	// so change detection doesn't wait on (or block) a run holding the state
+	// lock. It is ignored for saved plans, apply and destroy, which must lock.
+	// terraform output never locks state, so it needs no flag.
+	DisableLock bool
+
+	// LegacyRefresh makes TerraformRefresh run `terraform refresh`, for
+	// terraform versions older than 0.15.4 without `apply -refresh-only`.
+	LegacyRefresh bool
+
+	// SkipBackend runs init with -backend=false, for validation that must not
+	// touch remote state.
+	SkipBackend bool
+
+	// SkipInitIfInitialized skips init when the module already has a
+	// .terraform directory and a readable lock file, for fast repeated runs.
+	// InitUpgrade always runs init.
+	SkipInitIfInitialized bool
+
+	// InitUpgrade runs init with -upgrade, picking the newest provider and
+	// module versions the constraints allow.
+	InitUpgrade bool
+
+	// MaxOutputBytes bounds the terraform output embedded in error messages
+	// (head and tail are kept). Zero uses a 16 KiB default.
+	MaxOutputBytes int
+
+	// OutputLogDir, when set, receives the full output of failed commands.
+	OutputLogDir string
+
+	// SensitiveVars are redacted when effective variables are reported.
+	SensitiveVars []string
 }
 
-type TerraformActivities struct{}
+type TerraformActivities struct {
+	// PlanStore receives plans saved by TerraformSavePlan
+	PlanStore PlanStore
+
+	// RemoteVars limits where FetchRemoteVars may send which token
+	RemoteVars RemoteVarsPolicy
+}
 
 // createCombinedTFVars creates a combined tfvars file merging the original tfvars
 // file with extra variables passed from parent workspaces. Extra vars override
 // any variables with the same name in the original file.
 // Uses HCL library for proper parsing and outputs as JSON for compatibility.
 func createCombinedTFVars(params TerraformParams) (string, error) {
-	// If no extra vars and no original tfvars, return empty
-	if len(params.Vars) == 0 {
+	// If no extra vars and the original tfvars is used as-is, return it
+	if len(params.Vars) == 0 && (params.TFVars == "" || !params.PreferAutoTFVars) {
 		return params.TFVars, nil
 	}
 
-	// Initialize variables map
+	variables, err := combinedVariables(params)
+	if err != nil {
+		return "", err
+	}
+	return writeTFVarsJSON(params, "combined.tfvars.json", variables)
+}
+
+// combinedVariables parses the original tfvars file and layers the extra
+// vars over it, coercing hinted types. This is the effective set of values
+// terraform sees, whichever way the var files are passed.
+func combinedVariables(params TerraformParams) (map[string]interface{}, error) {
+	variables, err := baseVariables(params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Merge/override with extra vars from parent workspaces
+	for key, value := range params.Vars {
+		variables[key] = value
+	}
+
+	if err := coerceVars(variables, params.VarTypes); err != nil {
+		return nil, err
+	}
+	return variables, nil
+}
+
+// parseTFVarsFile reads a tfvars file, as JSON for a .json extension and as
+// HCL otherwise.
+func parseTFVarsFile(path string) (map[string]interface{}, error) {
 	variables := make(map[string]interface{})
+	if filepath.Ext(path) == ".json" {
+		// Parse as JSON
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JSON tfvars file: %v", err)
+		}
+		if err := json.Unmarshal(data, &variables); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON tfvars: %v", err)
+		}
+		return variables, nil
+	}
 
-	// Parse original tfvars if provided
-	if params.TFVars != "" {
-		ext := filepath.Ext(params.TFVars)
-		if ext == ".json" {
-			// Parse as JSON
-			data, err := os.ReadFile(params.TFVars)
-			if err != nil {
-				return "", fmt.Errorf("failed to read JSON tfvars file: %v", err)
-			}
-			if err := json.Unmarshal(data, &variables); err != nil {
-				return "", fmt.Errorf("failed to parse JSON tfvars: %v", err)
-			}
-		} else {
-			// Parse as HCL
-			parser := hclparse.NewParser()
-			var file *hcl.File
-			var diags hcl.Diagnostics
-
-			file, diags = parser.ParseHCLFile(params.TFVars)
-			if diags.HasErrors() {
-				return "", fmt.Errorf("failed to parse HCL tfvars: %v", diags.Error())
-			}
+	// Parse as HCL
+	parser := hclparse.NewParser()
+	file, diags := parser.ParseHCLFile(path)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse HCL tfvars: %v", diags.Error())
+	}
 
-			// Extract attributes from the HCL file
-			attrs, diags := file.Body.JustAttributes()
-			if diags.HasErrors() {
-				return "", fmt.Errorf("failed to extract attributes from HCL: %v", diags.Error())
-			}
+	// Extract attributes from the HCL file
+	attrs, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to extract attributes from HCL: %v", diags.Error())
+	}
 
-			// Convert each attribute to a Go value
-			for name, attr := range attrs {
-				val, diags := attr.Expr.Value(nil)
-				if diags.HasErrors() {
-					return "", fmt.Errorf("failed to evaluate attribute %s: %v", name, diags.Error())
-				}
+	// Convert each attribute to a Go value
+	for name, attr := range attrs {
+		val, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to evaluate attribute %s: %v", name, diags.Error())
+		}
 
-				// Convert cty.Value to Go interface{}
-				goValue, err := ctyToGo(val)
-				if err != nil {
-					return "", fmt.Errorf("failed to convert attribute %s: %v", name, err)
-				}
-				variables[name] = goValue
-			}
+		// Convert cty.Value to Go interface{}
+		goValue, err := ctyToGo(val)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert attribute %s: %v", name, err)
 		}
+		variables[name] = goValue
 	}
+	return variables, nil
+}
 
-	// Merge/override with extra vars from parent workspaces
-	for key, value := range params.Vars {
-		variables[key] = value
+// varFileArgs returns the -var-file flags for a plan. By default the base
+// tfvars and extra vars are merged into a single combined file. With
+// LayerVarFiles, the base file is passed unchanged followed by a file holding
+// only the extra vars; terraform's last-file-wins rule gives the same
+// precedence while keeping the base file's own parsing and types intact.
+//
+// The returned cleanup removes the files generated for the flags; callers
+// defer it once terraform has read them. On error, nothing is left behind.
+func varFileArgs(params TerraformParams) ([]string, func(), error) {
+	var generated []string
+	cleanup := func() {
+		for _, path := range generated {
+			os.Remove(path)
+		}
 	}
+	args, err := buildVarFileArgs(params, &generated)
+	if err != nil {
+		cleanup()
+		return nil, func() {}, err
+	}
+	return args, cleanup, nil
+}
 
-	// Create temp directory for this run
-	tmpDir := filepath.Join(os.TempDir(), "terraform-orchestrator", params.RunID)
+// buildVarFileArgs does the work of varFileArgs, recording each file it
+// writes in generated.
+func buildVarFileArgs(params TerraformParams, generated *[]string) ([]string, error) {
+	if !params.LayerVarFiles {
+		tfvarsFile, err := createCombinedTFVars(params)
+		if err != nil {
+			return nil, err
+		}
+		if tfvarsFile == "" {
+			return nil, nil
+		}
+		if tfvarsFile != params.TFVars {
+			*generated = append(*generated, tfvarsFile)
+		}
+		return []string{"-var-file", tfvarsFile}, nil
+	}
+
+	var args []string
+	if params.TFVars != "" && params.PreferAutoTFVars {
+		base, err := baseVariables(params)
+		if err != nil {
+			return nil, err
+		}
+		basePath, err := writeTFVarsJSON(params, "base.tfvars.json", base)
+		if err != nil {
+			return nil, err
+		}
+		*generated = append(*generated, basePath)
+		args = append(args, "-var-file", basePath)
+	} else if params.TFVars != "" {
+		args = append(args, "-var-file", params.TFVars)
+	}
+	if len(params.Vars) > 0 {
+		extra := make(map[string]interface{}, len(params.Vars))
+		for key, value := range params.Vars {
+			extra[key] = value
+		}
+		if err := coerceVars(extra, params.VarTypes); err != nil {
+			return nil, err
+		}
+		extraPath, err := writeTFVarsJSON(params, "extra.tfvars.json", extra)
+		if err != nil {
+			return nil, err
+		}
+		*generated = append(*generated, extraPath)
+		args = append(args, "-var-file", extraPath)
+	}
+	return args, nil
+}
+
+// coerceVars converts hinted variables in place (e.g. an output of "8080"
+// feeding a number variable). Variables that aren't present are ignored.
+func coerceVars(variables map[string]interface{}, types map[string]string) error {
+	for key, typ := range types {
+		value, ok := variables[key]
+		if !ok {
+			continue
+		}
+		coerced, err := coerceVar(value, typ)
+		if err != nil {
+			return fmt.Errorf("failed to coerce variable %s: %v", key, err)
+		}
+		variables[key] = coerced
+	}
+	return nil
+}
+
+// writeTFVarsJSON writes variables to a new file in the run's temp
+// directory, named after name with a unique suffix (combined.tfvars.json
+// becomes combined-<random>.tfvars.json). Workspaces of a run share the
+// directory and may run at once, so each call gets its own file, which its
+// caller can remove without affecting another workspace's terraform command.
+// Terraform accepts .tfvars.json files, which preserve the values' JSON types.
+func writeTFVarsJSON(params TerraformParams, name string, variables map[string]interface{}) (string, error) {
+	tmpDir := runTempDir(params)
 	if err := os.MkdirAll(tmpDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create temp directory: %v", err)
 	}
 
-	// Write as JSON (Terraform accepts .tfvars.json files)
-	combinedPath := filepath.Join(tmpDir, "combined.tfvars.json")
 	jsonData, err := json.MarshalIndent(variables, "", "  ")
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal variables to JSON: %v", err)
 	}
 
-	if err := os.WriteFile(combinedPath, jsonData, 0644); err != nil {
-		return "", fmt.Errorf("failed to write combined tfvars JSON: %v", err)
+	pattern := strings.TrimSuffix(name, ".tfvars.json") + "-*.tfvars.json"
+	file, err := os.CreateTemp(tmpDir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create tfvars JSON %s: %v", name, err)
+	}
+	_, err = file.Write(jsonData)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write tfvars JSON %s: %v", name, err)
 	}
 
-	return combinedPath, nil
+	return file.Name(), nil
+}
+
+// coerceVar converts a value to the Terraform primitive type named by typ.
+// Only safe conversions are performed; anything lossy or ambiguous is an error.
+func coerceVar(value interface{}, typ string) (interface{}, error) {
+	switch typ {
+	case "":
+		return value, nil
+	case "number":
+		switch v := value.(type) {
+		case float64, float32, int, int32, int64:
+			return v, nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+				return nil, fmt.Errorf("cannot convert %q to number", v)
+			}
+			return f, nil
+		}
+	case "bool":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("cannot convert %q to bool", v)
+			}
+			return b, nil
+		}
+	case "string":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case bool:
+			return strconv.FormatBool(v), nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		case int, int32, int64:
+			return fmt.Sprintf("%d", v), nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %s", typ)
+	}
+	return nil, fmt.Errorf("cannot convert %T to %s", value, typ)
 }
 
 // ctyToGo converts a cty.Value to a Go interface{} for JSON serialization
@@ -171,7 +428,67 @@ func (a *TerraformActivities) TerraformInit(ctx context.Context, params Terrafor
 	if err := validatePaths(params); err != nil {
 		return err
 	}
-	return runTerraform(ctx, params.Dir, "init")
+	if params.SkipInitIfInitialized && !params.InitUpgrade && isInitialized(filepath.Join(params.Dir, params.Chdir), !params.SkipBackend) {
+		// The provider policy still applies to what the earlier init installed
+		return checkInitProviders(params)
+	}
+	args := []string{"init"}
+	if params.SkipBackend {
+		args = append(args, "-backend=false")
+	}
+	if params.InitUpgrade {
+		args = append(args, "-upgrade")
+	}
+	if err := runTerraform(ctx, params, args...); err != nil {
+		return err
+	}
+	return checkInitProviders(params)
+}
+
+// isInitialized reports whether a module has been initialized: it has a
+// .terraform directory and a lock file that parses. Modules without a lock
+// file (no providers) are always initialized again. With withBackend, a
+// module declaring a backend also needs the .terraform/terraform.tfstate
+// that a backend init writes, so an `init -backend=false` left by a
+// validate-only run doesn't count.
+func isInitialized(moduleDir string, withBackend bool) bool {
+	info, err := os.Stat(filepath.Join(moduleDir, ".terraform"))
+	if err != nil || !info.IsDir() {
+		return false
+	}
+	if _, err := os.Stat(filepath.Join(moduleDir, ".terraform.lock.hcl")); err != nil {
+		return false
+	}
+	if _, err := lockFileProviders(moduleDir); err != nil {
+		return false
+	}
+	if !withBackend {
+		return true
+	}
+	backend, err := ModuleBackend(moduleDir)
+	if err != nil {
+		return false
+	}
+	if backend != "" {
+		if _, err := os.Stat(filepath.Join(moduleDir, ".terraform", "terraform.tfstate")); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// checkInitProviders applies the provider policy, if any, to the lock file
+// written by init.
+func checkInitProviders(params TerraformParams) error {
+	if len(params.AllowedProviders) == 0 && len(params.DeniedProviders) == 0 {
+		return nil
+	}
+
+	providers, err := lockFileProviders(filepath.Join(params.Dir, params.Chdir))
+	if err != nil {
+		return err
+	}
+	return checkProviderPolicy(providers, params.AllowedProviders, params.DeniedProviders)
 }
 
 func (a *TerraformActivities) TerraformPlan(ctx context.Context, params TerraformParams) (bool, error) {
@@ -179,35 +496,49 @@ func (a *TerraformActivities) TerraformPlan(ctx context.Context, params Terrafor
 		return false, err
 	}
 
-	// Create combined tfvars file if we have extra vars
-	tfvarsFile, err := createCombinedTFVars(params)
+	varFiles, cleanupVarFiles, err := varFileArgs(params)
 	if err != nil {
 		return false, err
 	}
+	defer cleanupVarFiles()
 
 	planPath := planFullPath(params)
-	args := []string{"plan", "-no-color", "-out", planPath, "-detailed-exitcode"}
-	if tfvarsFile != "" {
-		args = append(args, "-var-file", tfvarsFile)
+	args := append([]string{"plan", "-no-color", "-detailed-exitcode"}, stateCommandArgs(params)...)
+	if !params.DetectOnly {
+		if err := os.MkdirAll(filepath.Dir(planPath), 0755); err != nil {
+			return false, fmt.Errorf("failed to create plan directory: %v", err)
+		}
+		args = append(args, "-out", planPath)
+	} else if params.DisableLock {
+		args = append(args, "-lock=false")
+	}
+	for _, target := range params.Targets {
+		args = append(args, "-target="+target)
 	}
+	args = append(args, varFiles...)
 
-	cmd := exec.CommandContext(ctx, "terraform", args...)
-	cmd.Dir = params.Dir
+	cmd := terraformCommand(ctx, params, args...)
 	output, err := cmd.CombinedOutput()
 
 	// Exit code 0: No changes, 2: Changes present
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			if exitErr.ExitCode() == 2 {
+				if params.DetectOnly {
+					return true, nil // Changes present, no plan file requested
+				}
 				if err := ensurePlanFile(planPath); err != nil {
 					return false, fmt.Errorf("failed to create plan file: %v", err)
 				}
 				return true, nil // Changes present
 			}
 		}
-		return false, fmt.Errorf("terraform plan failed: %v, args: %s, output: %s", err, strings.Join(args, " "), string(output))
+		return false, stateLockError(fmt.Errorf("terraform plan failed: %v, args: %s, output: %s", err, strings.Join(args, " "), errorOutput(params, "plan", output)), output)
 	}
 
+	if params.DetectOnly {
+		return false, nil // No changes
+	}
 	if err := ensurePlanFile(planPath); err != nil {
 		return false, fmt.Errorf("failed to create plan file: %v", err)
 	}
@@ -218,46 +549,101 @@ func (a *TerraformActivities) TerraformValidate(ctx context.Context, params Terr
 	if err := validatePaths(params); err != nil {
 		return err
 	}
-	return runTerraform(ctx, params.Dir, "validate")
+	return runTerraform(ctx, params, "validate")
+}
+
+// ApplyResult confirms an apply ran and what it changed.
+type ApplyResult struct {
+	Applied   bool `json:"applied"`
+	Added     int  `json:"added"`
+	Changed   int  `json:"changed"`
+	Destroyed int  `json:"destroyed"`
+	Imported  int  `json:"imported,omitempty"`
 }
 
-func (a *TerraformActivities) TerraformApply(ctx context.Context, params TerraformParams) error {
+func (a *TerraformActivities) TerraformApply(ctx context.Context, params TerraformParams) (ApplyResult, error) {
 	if err := validatePaths(params); err != nil {
-		return err
+		return ApplyResult{}, err
 	}
 	planPath := planFullPath(params)
 
 	if _, err := os.Stat(planPath); err != nil {
-		return fmt.Errorf("plan file not found for apply: %s", planPath)
+		return ApplyResult{}, fmt.Errorf("plan file not found for apply: %s", planPath)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	args := append(append([]string{"apply", "-no-color"}, stateCommandArgs(params)...), planPath)
+	output, err := terraformCommand(ctx, params, args...).CombinedOutput()
+	if err != nil {
+		return ApplyResult{}, stateLockError(fmt.Errorf("terraform %s failed: %v, output: %s", strings.Join(args, " "), err, errorOutput(params, "apply", output)), output)
 	}
+	return parseApplySummary(output), nil
+}
 
-	return runTerraform(ctx, params.Dir, "apply", "-no-color", planPath)
+// applySummary matches terraform's final apply line, e.g.
+// "Apply complete! Resources: 1 imported, 2 added, 0 changed, 1 destroyed."
+var applySummary = regexp.MustCompile(`Apply complete! Resources: (?:(\d+) imported, )?(\d+) added, (\d+) changed, (\d+) destroyed`)
+
+// parseApplySummary reads resource counts from successful apply output.
+// Counts stay zero if terraform printed no summary.
+func parseApplySummary(output []byte) ApplyResult {
+	result := ApplyResult{Applied: true}
+	m := applySummary.FindSubmatch(output)
+	if m == nil {
+		return result
+	}
+	result.Imported, _ = strconv.Atoi(string(m[1]))
+	result.Added, _ = strconv.Atoi(string(m[2]))
+	result.Changed, _ = strconv.Atoi(string(m[3]))
+	result.Destroyed, _ = strconv.Atoi(string(m[4]))
+	return result
 }
 
-func (a *TerraformActivities) TerraformOutput(ctx context.Context, params TerraformParams) (map[string]interface{}, error) {
+// TerraformDestroy tears down every resource in the workspace's state. It
+// takes the same var files as plan, since destroy still evaluates variables.
+func (a *TerraformActivities) TerraformDestroy(ctx context.Context, params TerraformParams) error {
 	if err := validatePaths(params); err != nil {
-		return nil, err
+		return err
 	}
 
-	cmd := exec.CommandContext(ctx, "terraform", "output", "-json")
-	cmd.Dir = params.Dir
-	output, err := cmd.CombinedOutput()
+	varFiles, cleanupVarFiles, err := varFileArgs(params)
 	if err != nil {
-		return nil, fmt.Errorf("terraform output failed: %v, output: %s", err, string(output))
+		return err
 	}
+	defer cleanupVarFiles()
+	args := append([]string{"destroy", "-auto-approve", "-no-color"}, stateCommandArgs(params)...)
+	return runTerraform(ctx, params, append(args, varFiles...)...)
+}
 
-	var raw map[string]struct {
-		Value interface{} `json:"value"`
+// TerraformRefresh updates the state to match the real infrastructure,
+// without changing any resource, so a following plan starts from what
+// actually exists. It runs `terraform apply -refresh-only -auto-approve`, or
+// `terraform refresh` with LegacyRefresh.
+func (a *TerraformActivities) TerraformRefresh(ctx context.Context, params TerraformParams) error {
+	if err := validatePaths(params); err != nil {
+		return err
 	}
-	if err := json.Unmarshal(output, &raw); err != nil {
-		return nil, fmt.Errorf("failed to parse terraform output: %v", err)
+
+	varFiles, cleanupVarFiles, err := varFileArgs(params)
+	if err != nil {
+		return err
 	}
+	defer cleanupVarFiles()
+	args := []string{"apply", "-refresh-only", "-auto-approve", "-no-color"}
+	if params.LegacyRefresh {
+		args = []string{"refresh", "-no-color"}
+	}
+	args = append(args, stateCommandArgs(params)...)
+	return runTerraform(ctx, params, append(args, varFiles...)...)
+}
 
-	results := make(map[string]interface{}, len(raw))
-	for k, v := range raw {
-		results[k] = v.Value
+func (a *TerraformActivities) TerraformOutput(ctx context.Context, params TerraformParams) (map[string]interface{}, error) {
+	outputs, err := a.TerraformOutputDetails(ctx, params)
+	if err != nil {
+		return nil, err
 	}
-	return results, nil
+	return outputValues(outputs), nil
 }
 
 func validatePaths(params TerraformParams) error {
@@ -272,21 +658,71 @@ func validatePaths(params TerraformParams) error {
 			return fmt.Errorf("tfvars file invalid: %v", err)
 		}
 	}
+	if params.Chdir != "" {
+		if err := validateChdir(params.Dir, params.Chdir); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func runTerraform(ctx context.Context, dir string, args ...string) error {
+// validateChdir ensures chdir is a relative path that stays inside dir and
+// points at an existing directory.
+func validateChdir(dir, chdir string) error {
+	clean := filepath.Clean(chdir)
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("terraform chdir must be a relative path inside %s: %s", dir, chdir)
+	}
+	if info, err := os.Stat(filepath.Join(dir, clean)); err != nil || !info.IsDir() {
+		return fmt.Errorf("terraform chdir invalid: %v", err)
+	}
+	return nil
+}
+
+func runTerraform(ctx context.Context, params TerraformParams, args ...string) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 	defer cancel()
-	cmd := exec.CommandContext(ctx, "terraform", args...)
-	cmd.Dir = dir
+	cmd := terraformCommand(ctx, params, args...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("terraform %s failed: %v, output: %s", strings.Join(args, " "), err, string(output))
+		return stateLockError(fmt.Errorf("terraform %s failed: %v, output: %s", strings.Join(args, " "), err, errorOutput(params, args[0], output)), output)
 	}
 	return nil
 }
 
+// stateCommandArgs returns the flags shared by plan, apply and destroy:
+// -parallelism and -lock-timeout, each only when configured.
+func stateCommandArgs(params TerraformParams) []string {
+	var args []string
+	if params.Parallelism > 0 {
+		args = append(args, "-parallelism="+strconv.Itoa(params.Parallelism))
+	}
+	if strings.TrimSpace(params.LockTimeout) != "" {
+		args = append(args, "-lock-timeout="+params.LockTimeout)
+	}
+	return args
+}
+
+// stateLockError wraps err as a StateLockErrorType ApplicationError when the
+// command output shows terraform could not acquire the state lock.
+func stateLockError(err error, output []byte) error {
+	if !stateLockFailure.Match(output) {
+		return err
+	}
+	return temporal.NewApplicationError(err.Error(), StateLockErrorType)
+}
+
+// terraformCommand builds a terraform invocation running from the workspace
+// dir. Global flags such as -chdir must precede the subcommand.
+func terraformCommand(ctx context.Context, params TerraformParams, args ...string) *exec.Cmd {
+	if params.Chdir != "" {
+		args = append([]string{"-chdir=" + params.Chdir}, args...)
+	}
+	cmd := exec.CommandContext(ctx, "terraform", args...)
+	cmd.Dir = params.Dir
+	return cmd
+}
+
 func planFilePath(params TerraformParams) string {
 	if strings.TrimSpace(params.PlanFile) == "" {
 		return "tfplan"
@@ -294,10 +730,25 @@ func planFilePath(params TerraformParams) string {
 	return filepath.Base(params.PlanFile)
 }
 
+// planFullPath places the plan next to the module, or in the per-run temp
+// directory when a TempRoot is configured.
 func planFullPath(params TerraformParams) string {
+	if strings.TrimSpace(params.TempRoot) != "" {
+		return filepath.Join(runTempDir(params), planFilePath(params))
+	}
 	return filepath.Join(params.Dir, planFilePath(params))
 }
 
+// runTempDir returns the per-run directory for generated artifacts such as
+// combined tfvars, rooted at TempRoot when set and os.TempDir() otherwise.
+func runTempDir(params TerraformParams) string {
+	root := params.TempRoot
+	if strings.TrimSpace(root) == "" {
+		root = os.TempDir()
+	}
+	return filepath.Join(root, "terraform-orchestrator", params.RunID)
+}
+
 func ensurePlanFile(path string) error {
 	_, err := os.Stat(path)
 	if err == nil {
@@ -3,12 +3,16 @@ package activities
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	"github.com/zclconf/go-cty/cty"
+	"go.temporal.io/sdk/temporal"
 )
 
 func TestTerraformPlanDetectsChangesAndCreatesPlan(t *testing.T) {
@@ -40,7 +44,7 @@ func TestTerraformApplyFailsWithoutPlan(t *testing.T) {
 	}
 	act := &TerraformActivities{}
 
-	err := act.TerraformApply(context.Background(), params)
+	_, err := act.TerraformApply(context.Background(), params)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "plan file not found")
 }
@@ -151,8 +155,225 @@ func TestTerraformApplyWithValidPlan(t *testing.T) {
 	}
 
 	act := &TerraformActivities{}
-	err := act.TerraformApply(context.Background(), params)
+	result, err := act.TerraformApply(context.Background(), params)
 	require.NoError(t, err)
+	require.Equal(t, ApplyResult{Applied: true, Added: 2, Changed: 1}, result)
+}
+
+func TestTerraformDestroyPassesVarFiles(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	invocations := recordTerraformArgs(t)
+
+	tmp := t.TempDir()
+	tempRoot := t.TempDir()
+	params := TerraformParams{
+		Dir:      tmp,
+		Vars:     map[string]interface{}{"vpc_id": "vpc-123"},
+		RunID:    "destroy-run",
+		TempRoot: tempRoot,
+	}
+
+	act := &TerraformActivities{}
+	require.NoError(t, act.TerraformDestroy(context.Background(), params))
+
+	combined := filepath.Join(tempRoot, "terraform-orchestrator", "destroy-run", "combined.tfvars.json")
+	calls := invocations()
+	require.Len(t, calls, 1)
+	require.Equal(t, "destroy -auto-approve -no-color -var-file "+combined, calls[0])
+}
+
+func TestTerraformLockTimeoutPassedToStateCommands(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	invocations := recordTerraformArgs(t)
+
+	tmp := t.TempDir()
+	params := TerraformParams{
+		Dir:         tmp,
+		PlanFile:    "test.plan",
+		LockTimeout: "30s",
+	}
+
+	act := &TerraformActivities{}
+	require.NoError(t, act.TerraformInit(context.Background(), params))
+	_, err := act.TerraformPlan(context.Background(), params)
+	require.NoError(t, err)
+	_, err = act.TerraformApply(context.Background(), params)
+	require.NoError(t, err)
+	require.NoError(t, act.TerraformDestroy(context.Background(), params))
+
+	calls := invocations()
+	require.Len(t, calls, 4)
+	require.NotContains(t, calls[0], "-lock-timeout")
+	for _, call := range calls[1:] {
+		require.Contains(t, strings.Fields(call), "-lock-timeout=30s", call)
+	}
+}
+
+func TestTerraformRefresh(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	invocations := recordTerraformArgs(t)
+
+	tmp := t.TempDir()
+	params := TerraformParams{
+		Dir:         tmp,
+		Vars:        map[string]interface{}{"region": "us-east-1"},
+		RunID:       "refresh-run",
+		TempRoot:    tmp,
+		LockTimeout: "30s",
+	}
+
+	act := &TerraformActivities{}
+	require.NoError(t, act.TerraformRefresh(context.Background(), params))
+	params.LegacyRefresh = true
+	require.NoError(t, act.TerraformRefresh(context.Background(), params))
+
+	combined := filepath.Join(tmp, "terraform-orchestrator", "refresh-run", "combined.tfvars.json")
+	calls := invocations()
+	require.Len(t, calls, 2)
+	require.Equal(t, "apply -refresh-only -auto-approve -no-color -lock-timeout=30s -var-file "+combined, calls[0])
+	require.Equal(t, "refresh -no-color -lock-timeout=30s -var-file "+combined, calls[1])
+}
+
+func TestTerraformRefreshReportsStateLock(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	t.Setenv("TF_STATE_LOCKED", "1")
+
+	act := &TerraformActivities{}
+	err := act.TerraformRefresh(context.Background(), TerraformParams{Dir: t.TempDir()})
+	require.Error(t, err)
+
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr))
+	require.Equal(t, StateLockErrorType, appErr.Type())
+}
+
+func TestTerraformDisableLockOnlyOnDetectOnlyPlan(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	invocations := recordTerraformArgs(t)
+
+	tmp := t.TempDir()
+	params := TerraformParams{
+		Dir:         tmp,
+		PlanFile:    "test.plan",
+		DisableLock: true,
+	}
+
+	act := &TerraformActivities{}
+	detect := params
+	detect.DetectOnly = true
+	_, err := act.TerraformPlan(context.Background(), detect)
+	require.NoError(t, err)
+	_, err = act.TerraformPlan(context.Background(), params)
+	require.NoError(t, err)
+	_, err = act.TerraformApply(context.Background(), params)
+	require.NoError(t, err)
+	require.NoError(t, act.TerraformDestroy(context.Background(), params))
+	_, err = act.TerraformOutput(context.Background(), params)
+	require.NoError(t, err)
+
+	calls := invocations()
+	require.Len(t, calls, 5)
+	require.Contains(t, strings.Fields(calls[0]), "-lock=false", calls[0])
+	for _, call := range calls[1:] {
+		require.NotContains(t, call, "-lock", call)
+	}
+}
+
+func TestTerraformPlanPassesTargets(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	invocations := recordTerraformArgs(t)
+
+	tmp := t.TempDir()
+	params := TerraformParams{
+		Dir:      tmp,
+		PlanFile: "test.plan",
+		Targets:  []string{"aws_instance.web", `module.vpc.aws_subnet.private["a"]`},
+	}
+
+	act := &TerraformActivities{}
+	_, err := act.TerraformPlan(context.Background(), params)
+	require.NoError(t, err)
+
+	calls := invocations()
+	require.Len(t, calls, 1)
+	args := strings.Fields(calls[0])
+	require.Contains(t, args, "-target=aws_instance.web")
+	require.Contains(t, args, `-target=module.vpc.aws_subnet.private["a"]`)
+}
+
+func TestTerraformParallelismAppendedOnlyWhenSet(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+
+	tests := []struct {
+		name        string
+		parallelism int
+		want        bool
+	}{
+		{"unset", 0, false},
+		{"set", 50, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invocations := recordTerraformArgs(t)
+			params := TerraformParams{
+				Dir:         t.TempDir(),
+				PlanFile:    "test.plan",
+				Parallelism: tt.parallelism,
+			}
+
+			act := &TerraformActivities{}
+			_, err := act.TerraformPlan(context.Background(), params)
+			require.NoError(t, err)
+			_, err = act.TerraformApply(context.Background(), params)
+			require.NoError(t, err)
+
+			calls := invocations()
+			require.Len(t, calls, 2)
+			for _, call := range calls {
+				if tt.want {
+					require.Contains(t, strings.Fields(call), "-parallelism=50", call)
+				} else {
+					require.NotContains(t, call, "-parallelism", call)
+				}
+			}
+		})
+	}
+}
+
+func TestTerraformStateLockErrorIsTyped(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	t.Setenv("TF_STATE_LOCKED", "1")
+
+	tmp := t.TempDir()
+	params := TerraformParams{Dir: tmp, PlanFile: "test.plan"}
+
+	act := &TerraformActivities{}
+	_, err := act.TerraformPlan(context.Background(), params)
+	require.Error(t, err)
+
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr))
+	require.Equal(t, StateLockErrorType, appErr.Type())
+	require.False(t, appErr.NonRetryable())
+	require.Contains(t, err.Error(), "Error acquiring the state lock")
+
+	err = act.TerraformDestroy(context.Background(), params)
+	require.True(t, errors.As(err, &appErr))
+	require.Equal(t, StateLockErrorType, appErr.Type())
+}
+
+func TestStateLockErrorLeavesOtherFailuresUntyped(t *testing.T) {
+	err := stateLockError(errors.New("terraform plan failed"), []byte("Error: Invalid reference"))
+	var appErr *temporal.ApplicationError
+	require.False(t, errors.As(err, &appErr))
+}
+
+func TestTerraformDestroyRequiresValidDir(t *testing.T) {
+	act := &TerraformActivities{}
+	err := act.TerraformDestroy(context.Background(), TerraformParams{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "terraform dir is required")
 }
 
 func TestTerraformOutputWithEmptyResult(t *testing.T) {
@@ -272,9 +493,27 @@ func fakeTerraformOnPath(t *testing.T) string {
 	dir := t.TempDir()
 	bin := filepath.Join(dir, "terraform")
 	script := `#!/bin/sh
+[ -n "$TF_ARGS_LOG" ] && echo "$*" >> "$TF_ARGS_LOG"
+while [ "$#" -gt 0 ]; do
+  case "$1" in
+    -chdir=*) shift ;;
+    *) break ;;
+  esac
+done
 cmd="$1"; shift
+case "$cmd" in
+  plan|apply|destroy|refresh)
+    if [ -n "$TF_STATE_LOCKED" ]; then
+      echo "Error: Error acquiring the state lock" >&2
+      exit 1
+    fi
+    ;;
+esac
 case "$cmd" in
   init)
+    if [ "$1" = "-json" ] && [ -n "$TF_INIT_JSON" ]; then
+      while IFS= read -r line; do printf '%s\n' "$line"; done < "$TF_INIT_JSON"
+    fi
     exit 0
     ;;
   validate)
@@ -282,8 +521,22 @@ case "$cmd" in
     ;;
   plan)
     out=""
+    refresh_only=""
     while [ "$#" -gt 0 ]; do
       case "$1" in
+        -refresh-only)
+          refresh_only=1
+          shift
+          continue
+          ;;
+        -var-file)
+          # Var files are removed after the activity, so keep a copy to inspect
+          if [ -n "$TF_VAR_FILES_DIR" ]; then
+            while IFS= read -r line || [ -n "$line" ]; do printf '%s\n' "$line"; done < "$2" > "$TF_VAR_FILES_DIR/${2##*/}"
+          fi
+          shift 2
+          continue
+          ;;
         -out)
           out="$2"
           shift 2
@@ -297,17 +550,42 @@ case "$cmd" in
       esac
       shift
     done
-    if [ -z "$out" ]; then
-      out="tfplan"
+    if [ -n "$refresh_only" ]; then
+      # TF_DRIFT lists the resources changed outside terraform
+      if [ -z "$TF_DRIFT" ]; then
+        echo "No changes. Your infrastructure still matches the configuration."
+        exit 0
+      fi
+      echo "Terraform detected the following changes made outside of Terraform since the last \"terraform apply\":"
+      for addr in $TF_DRIFT; do
+        echo ""
+        echo "  # $addr has changed"
+      done
+      exit 2
     fi
     [ -n "$out" ] && touch "$out"
     exit 2
     ;;
   show)
-    echo '{"format_version":"1.0"}'
+    if [ "$1" != "-json" ]; then
+      echo "Terraform will perform the following actions:"
+      exit 0
+    fi
+    if [ -n "$TF_SHOW_JSON" ]; then
+      while IFS= read -r line; do printf '%s\n' "$line"; done < "$TF_SHOW_JSON"
+    else
+      echo '{"format_version":"1.0"}'
+    fi
+    exit 0
+    ;;
+  refresh)
     exit 0
     ;;
   apply)
+    if [ "$1" = "-refresh-only" ]; then
+      echo "Apply complete! Resources: 0 added, 0 changed, 0 destroyed."
+      exit 0
+    fi
     # Skip flags to find the plan file
     plan=""
     while [ "$#" -gt 0 ]; do
@@ -325,6 +603,11 @@ case "$cmd" in
       echo "missing plan" >&2
       exit 1
     fi
+    echo "Apply complete! Resources: 2 added, 1 changed, 0 destroyed."
+    exit 0
+    ;;
+  destroy)
+    echo "Destroy complete! Resources: 3 destroyed."
     exit 0
     ;;
   output)
@@ -341,6 +624,59 @@ esac
 	return dir
 }
 
+// recordTerraformArgs makes the fake terraform binary append each invocation's
+// arguments to a log file and returns a reader for the recorded invocations.
+func recordTerraformArgs(t *testing.T) func() []string {
+	t.Helper()
+
+	logPath := filepath.Join(t.TempDir(), "terraform-args.log")
+	t.Setenv("TF_ARGS_LOG", logPath)
+	return func() []string {
+		data, err := os.ReadFile(logPath)
+		if os.IsNotExist(err) {
+			return nil // terraform never ran
+		}
+		require.NoError(t, err)
+		return strings.Split(canonicalVarFiles(strings.TrimSpace(string(data))), "\n")
+	}
+}
+
+// generatedVarFileSuffix matches the unique suffix writeTFVarsJSON adds to
+// the files it generates.
+var generatedVarFileSuffix = regexp.MustCompile(`-\d+(\.tfvars\.json)\b`)
+
+// canonicalVarFiles drops the unique suffix from generated var file names,
+// so combined-123.tfvars.json reads combined.tfvars.json.
+func canonicalVarFiles(s string) string {
+	return generatedVarFileSuffix.ReplaceAllString(s, "$1")
+}
+
+// generatedVarFiles lists the files in dir generated under name.
+func generatedVarFiles(t *testing.T, dir, name string) []string {
+	t.Helper()
+
+	matches, err := filepath.Glob(filepath.Join(dir, strings.TrimSuffix(name, ".tfvars.json")+"-*.tfvars.json"))
+	require.NoError(t, err)
+	return matches
+}
+
+// captureVarFiles makes the fake terraform binary copy each -var-file passed
+// to plan into a directory, returning a function that reads a copied file.
+func captureVarFiles(t *testing.T) func(name string) map[string]interface{} {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("TF_VAR_FILES_DIR", dir)
+	return func(name string) map[string]interface{} {
+		files := generatedVarFiles(t, dir, name)
+		if len(files) == 0 {
+			files = []string{filepath.Join(dir, name)}
+		}
+		require.Len(t, files, 1)
+		return readTFVarsJSON(t, files[0])
+	}
+}
+
 func TestCreateCombinedTFVars_NoExtraVars(t *testing.T) {
 	params := TerraformParams{
 		TFVars: "/path/to/original.tfvars",
@@ -353,6 +689,35 @@ func TestCreateCombinedTFVars_NoExtraVars(t *testing.T) {
 	require.Equal(t, "/path/to/original.tfvars", result)
 }
 
+func TestCreateCombinedTFVars_ThreeWayMerge(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalTFVars := filepath.Join(tmpDir, "original.tfvars")
+	require.NoError(t, os.WriteFile(originalTFVars, []byte(`region = "us-west-2"
+instance_type = "t2.micro"
+vpc_id = "vpc-file"
+`), 0o644))
+
+	// The workflow passes static config vars with dependency outputs layered
+	// on top; both override the tfvars file
+	params := TerraformParams{
+		TFVars: originalTFVars,
+		Vars: map[string]interface{}{
+			"instance_type": "t3.large",   // static var over the file
+			"vpc_id":        "vpc-output", // output over the file and the static default
+		},
+		RunID:    "three-way",
+		TempRoot: tmpDir,
+	}
+
+	combinedPath, err := createCombinedTFVars(params)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"region":        "us-west-2",
+		"instance_type": "t3.large",
+		"vpc_id":        "vpc-output",
+	}, readTFVarsJSON(t, combinedPath))
+}
+
 func TestCreateCombinedTFVars_HCLInputWithOverride(t *testing.T) {
 	tmpDir := t.TempDir()
 	originalTFVars := filepath.Join(tmpDir, "original.tfvars")
@@ -644,6 +1009,1058 @@ func TestRunTerraform_Success(t *testing.T) {
 	t.Setenv("PATH", fakeTerraformOnPath(t))
 
 	tmp := t.TempDir()
-	err := runTerraform(context.Background(), tmp, "init")
+	err := runTerraform(context.Background(), TerraformParams{Dir: tmp}, "init")
+	require.NoError(t, err)
+}
+
+func TestCreateCombinedTFVars_CoercesStringToNumber(t *testing.T) {
+	params := TerraformParams{
+		Vars: map[string]interface{}{
+			"port":    "8080",
+			"enabled": "true",
+			"name":    "web",
+		},
+		VarTypes: map[string]string{
+			"port":    "number",
+			"enabled": "bool",
+		},
+		RunID: "test-coerce-number",
+	}
+
+	combinedPath, err := createCombinedTFVars(params)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(combinedPath)
+	require.NoError(t, err)
+
+	var variables map[string]interface{}
+	require.NoError(t, json.Unmarshal(content, &variables))
+
+	require.Equal(t, float64(8080), variables["port"])
+	require.Equal(t, true, variables["enabled"])
+	require.Equal(t, "web", variables["name"])
+}
+
+func TestCreateCombinedTFVars_NonCoercibleValueFails(t *testing.T) {
+	params := TerraformParams{
+		Vars: map[string]interface{}{
+			"port": "not-a-port",
+		},
+		VarTypes: map[string]string{
+			"port": "number",
+		},
+		RunID: "test-coerce-failure",
+	}
+
+	_, err := createCombinedTFVars(params)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to coerce variable port")
+}
+
+func TestCoerceVar(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   interface{}
+		typ     string
+		want    interface{}
+		wantErr bool
+	}{
+		{"no hint", "8080", "", "8080", false},
+		{"number from string", " 42 ", "number", float64(42), false},
+		{"number passthrough", float64(3), "number", float64(3), false},
+		{"number from NaN", "NaN", "number", nil, true},
+		{"number from bool", true, "number", nil, true},
+		{"bool from string", "false", "bool", false, false},
+		{"bool from garbage", "maybe", "bool", nil, true},
+		{"string from number", float64(8080), "string", "8080", false},
+		{"string from list", []interface{}{"a"}, "string", nil, true},
+		{"unsupported type", "x", "list", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := coerceVar(tt.value, tt.typ)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTerraformPlan_TempRootRedirectsArtifacts(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+
+	tmp := t.TempDir()
+	tempRoot := t.TempDir()
+	params := TerraformParams{
+		Dir:      tmp,
+		PlanFile: "tfplan-temproot.plan",
+		Vars:     map[string]interface{}{"foo": "bar"},
+		RunID:    "test-temp-root",
+		TempRoot: tempRoot,
+	}
+
+	combinedPath, err := createCombinedTFVars(params)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(tempRoot, "terraform-orchestrator", "test-temp-root", "combined.tfvars.json"), canonicalVarFiles(combinedPath))
+
+	act := &TerraformActivities{}
+	_, err = act.TerraformPlan(context.Background(), params)
+	require.NoError(t, err)
+
+	_, statErr := os.Stat(filepath.Join(tempRoot, "terraform-orchestrator", "test-temp-root", params.PlanFile))
+	require.NoError(t, statErr, "plan file should be written under TempRoot")
+
+	_, statErr = os.Stat(filepath.Join(tmp, params.PlanFile))
+	require.True(t, os.IsNotExist(statErr), "plan file should not be written to the module dir")
+}
+
+func TestTerraformPlan_DetectOnlySkipsPlanFile(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+
+	tmp := t.TempDir()
+	params := TerraformParams{
+		Dir:        tmp,
+		PlanFile:   "tfplan-detect.plan",
+		DetectOnly: true,
+	}
+
+	act := &TerraformActivities{}
+	changed, err := act.TerraformPlan(context.Background(), params)
+	require.NoError(t, err)
+	require.True(t, changed, "detect-only plan should still report changes from the exit code")
+
+	_, statErr := os.Stat(filepath.Join(tmp, params.PlanFile))
+	require.True(t, os.IsNotExist(statErr), "detect-only plan should not create a plan file")
+
+	entries, err := os.ReadDir(tmp)
+	require.NoError(t, err)
+	require.Empty(t, entries, "detect-only plan should not write anything to the module dir")
+}
+
+func TestTerraformCommands_ChdirPrecedesSubcommand(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	invocations := recordTerraformArgs(t)
+
+	tmp := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmp, "modules", "vpc"), 0o755))
+	params := TerraformParams{
+		Dir:      tmp,
+		Chdir:    "modules/vpc",
+		PlanFile: "chdir.plan",
+	}
+
+	act := &TerraformActivities{}
+	require.NoError(t, act.TerraformInit(context.Background(), params))
+	_, err := act.TerraformPlan(context.Background(), params)
+	require.NoError(t, err)
+	_, err = act.TerraformOutput(context.Background(), params)
+	require.NoError(t, err)
+
+	calls := invocations()
+	require.Len(t, calls, 3)
+	require.True(t, strings.HasPrefix(calls[0], "-chdir=modules/vpc init"), calls[0])
+	require.True(t, strings.HasPrefix(calls[1], "-chdir=modules/vpc plan "), calls[1])
+	require.True(t, strings.HasPrefix(calls[2], "-chdir=modules/vpc output -json"), calls[2])
+}
+
+func TestValidatePathsRejectsInvalidChdir(t *testing.T) {
+	tmp := t.TempDir()
+
+	tests := []struct {
+		name   string
+		chdir  string
+		errMsg string
+	}{
+		{"escapes dir", "../other", "must be a relative path"},
+		{"absolute", "/etc", "must be a relative path"},
+		{"missing", "modules/missing", "chdir invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePaths(TerraformParams{Dir: tmp, Chdir: tt.chdir})
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestTerraformPlan_LayerVarFilesOrdersBaseBeforeExtra(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	invocations := recordTerraformArgs(t)
+	varFile := captureVarFiles(t)
+
+	tmp := t.TempDir()
+	base := filepath.Join(tmp, "base.tfvars")
+	require.NoError(t, os.WriteFile(base, []byte("region = \"us-west-2\"\nport = 80\n"), 0o644))
+
+	tempRoot := t.TempDir()
+	params := TerraformParams{
+		Dir:           tmp,
+		TFVars:        base,
+		PlanFile:      "layered.plan",
+		Vars:          map[string]interface{}{"port": "8080"},
+		VarTypes:      map[string]string{"port": "number"},
+		RunID:         "layered-run",
+		TempRoot:      tempRoot,
+		LayerVarFiles: true,
+	}
+
+	act := &TerraformActivities{}
+	_, err := act.TerraformPlan(context.Background(), params)
 	require.NoError(t, err)
+
+	extraPath := filepath.Join(tempRoot, "terraform-orchestrator", "layered-run", "extra.tfvars.json")
+	calls := invocations()
+	require.Len(t, calls, 1)
+	require.True(t, strings.HasSuffix(calls[0], "-var-file "+base+" -var-file "+extraPath), calls[0])
+
+	// The extra file holds only the propagated vars, coerced by their hints
+	require.Equal(t, map[string]interface{}{"port": float64(8080)}, varFile("extra.tfvars.json"))
+
+	require.Empty(t, generatedVarFiles(t, filepath.Join(tempRoot, "terraform-orchestrator", "layered-run"), "combined.tfvars.json"),
+		"layered mode should not write a combined file")
+}
+
+// autoTFVarsModule writes a module dir with an auto-loaded var file and a
+// workspace tfvars file that both set region.
+func autoTFVarsModule(t *testing.T) (dir, base string) {
+	t.Helper()
+
+	dir = t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "region.auto.tfvars"), []byte("region = \"eu-west-1\"\n"), 0o644))
+	base = filepath.Join(t.TempDir(), "base.tfvars")
+	require.NoError(t, os.WriteFile(base, []byte("region = \"us-west-2\"\nname = \"web\"\n"), 0o644))
+	return dir, base
+}
+
+func readTFVarsJSON(t *testing.T, path string) map[string]interface{} {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var values map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &values))
+	return values
+}
+
+func TestTerraformPlan_RemovesGeneratedVarFiles(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+
+	tmp := t.TempDir()
+	base := filepath.Join(tmp, "base.tfvars")
+	require.NoError(t, os.WriteFile(base, []byte("region = \"us-west-2\"\n"), 0o644))
+
+	for _, tt := range []struct {
+		name          string
+		layered       bool
+		planFails     bool
+		generatedFile string
+	}{
+		{"merged after success", false, false, "combined.tfvars.json"},
+		{"merged after plan failure", false, true, "combined.tfvars.json"},
+		{"layered after plan failure", true, true, "extra.tfvars.json"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.planFails {
+				t.Setenv("TF_STATE_LOCKED", "1")
+			}
+			tempRoot := t.TempDir()
+			params := TerraformParams{
+				Dir:           tmp,
+				TFVars:        base,
+				PlanFile:      "cleanup.plan",
+				Vars:          map[string]interface{}{"vpc_id": "vpc-1"},
+				RunID:         "cleanup-run",
+				TempRoot:      tempRoot,
+				LayerVarFiles: tt.layered,
+			}
+
+			act := &TerraformActivities{}
+			_, err := act.TerraformPlan(context.Background(), params)
+			if tt.planFails {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Empty(t, generatedVarFiles(t, filepath.Join(tempRoot, "terraform-orchestrator", "cleanup-run"), tt.generatedFile))
+			require.FileExists(t, base, "the user's tfvars file must never be removed")
+		})
+	}
+}
+
+func TestTerraformPlan_CombinedFileOverridesAutoTFVarsByDefault(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	invocations := recordTerraformArgs(t)
+	varFile := captureVarFiles(t)
+
+	dir, base := autoTFVarsModule(t)
+	tempRoot := t.TempDir()
+	params := TerraformParams{
+		Dir:      dir,
+		TFVars:   base,
+		PlanFile: "auto.plan",
+		Vars:     map[string]interface{}{"vpc_id": "vpc-1"},
+		RunID:    "auto-default",
+		TempRoot: tempRoot,
+	}
+
+	act := &TerraformActivities{}
+	_, err := act.TerraformPlan(context.Background(), params)
+	require.NoError(t, err)
+
+	// The base region is passed as a flag, so it beats region.auto.tfvars
+	combined := filepath.Join(tempRoot, "terraform-orchestrator", "auto-default", "combined.tfvars.json")
+	require.True(t, strings.HasSuffix(invocations()[0], "-var-file "+combined))
+	require.Equal(t, map[string]interface{}{"region": "us-west-2", "name": "web", "vpc_id": "vpc-1"}, varFile("combined.tfvars.json"))
+}
+
+func TestTerraformPlan_PreferAutoTFVarsDropsShadowedValues(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	invocations := recordTerraformArgs(t)
+	varFile := captureVarFiles(t)
+
+	dir, base := autoTFVarsModule(t)
+	tempRoot := t.TempDir()
+	params := TerraformParams{
+		Dir:              dir,
+		TFVars:           base,
+		PlanFile:         "auto.plan",
+		RunID:            "auto-prefer",
+		TempRoot:         tempRoot,
+		PreferAutoTFVars: true,
+	}
+
+	act := &TerraformActivities{}
+	_, err := act.TerraformPlan(context.Background(), params)
+	require.NoError(t, err)
+
+	// Without extra vars the base file is still rewritten, minus region
+	combined := filepath.Join(tempRoot, "terraform-orchestrator", "auto-prefer", "combined.tfvars.json")
+	require.True(t, strings.HasSuffix(invocations()[0], "-var-file "+combined))
+	require.Equal(t, map[string]interface{}{"name": "web"}, varFile("combined.tfvars.json"))
+}
+
+func TestVarFileArgs_PreferAutoTFVars(t *testing.T) {
+	dir, base := autoTFVarsModule(t)
+	tempRoot := t.TempDir()
+
+	t.Run("inputs still override auto-loaded files", func(t *testing.T) {
+		args, _, err := varFileArgs(TerraformParams{
+			Dir:              dir,
+			TFVars:           base,
+			Vars:             map[string]interface{}{"region": "ap-south-1"},
+			RunID:            "prefer-inputs",
+			TempRoot:         tempRoot,
+			PreferAutoTFVars: true,
+		})
+		require.NoError(t, err)
+		combined := filepath.Join(tempRoot, "terraform-orchestrator", "prefer-inputs", "combined.tfvars.json")
+		require.Equal(t, []string{"-var-file", combined}, strings.Fields(canonicalVarFiles(strings.Join(args, " "))))
+		require.Equal(t, map[string]interface{}{"region": "ap-south-1", "name": "web"}, readTFVarsJSON(t, args[1]))
+	})
+
+	t.Run("layered passes a filtered base file", func(t *testing.T) {
+		args, _, err := varFileArgs(TerraformParams{
+			Dir:              dir,
+			TFVars:           base,
+			RunID:            "prefer-layered",
+			TempRoot:         tempRoot,
+			LayerVarFiles:    true,
+			PreferAutoTFVars: true,
+		})
+		require.NoError(t, err)
+		filtered := filepath.Join(tempRoot, "terraform-orchestrator", "prefer-layered", "base.tfvars.json")
+		require.Equal(t, []string{"-var-file", filtered}, strings.Fields(canonicalVarFiles(strings.Join(args, " "))))
+		require.Equal(t, map[string]interface{}{"name": "web"}, readTFVarsJSON(t, args[1]))
+	})
+
+	t.Run("no tfvars passes nothing", func(t *testing.T) {
+		args, _, err := varFileArgs(TerraformParams{Dir: dir, PreferAutoTFVars: true})
+		require.NoError(t, err)
+		require.Empty(t, args)
+	})
+}
+
+func TestAutoLoadedVarFiles_TerraformLoadOrder(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.auto.tfvars", "a.auto.tfvars.json", "terraform.tfvars.json", "terraform.tfvars", "prod.tfvars"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(""), 0o644))
+	}
+
+	files, err := autoLoadedVarFiles(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, filepath.Base(f))
+	}
+	require.Equal(t, []string{"terraform.tfvars", "terraform.tfvars.json", "a.auto.tfvars.json", "b.auto.tfvars"}, names)
+}
+
+func TestVarFileArgs(t *testing.T) {
+	tmp := t.TempDir()
+	base := filepath.Join(tmp, "base.tfvars")
+	require.NoError(t, os.WriteFile(base, []byte("region = \"us-west-2\"\n"), 0o644))
+
+	t.Run("layered without extra vars passes only the base file", func(t *testing.T) {
+		args, _, err := varFileArgs(TerraformParams{TFVars: base, LayerVarFiles: true})
+		require.NoError(t, err)
+		require.Equal(t, []string{"-var-file", base}, args)
+	})
+
+	t.Run("layered without base file passes only the extra vars", func(t *testing.T) {
+		args, _, err := varFileArgs(TerraformParams{
+			Vars:          map[string]interface{}{"vpc_id": "vpc-1"},
+			RunID:         "layered-extra-only",
+			TempRoot:      tmp,
+			LayerVarFiles: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"-var-file", filepath.Join(tmp, "terraform-orchestrator", "layered-extra-only", "extra.tfvars.json")}, strings.Fields(canonicalVarFiles(strings.Join(args, " "))))
+	})
+
+	t.Run("merged mode passes a single combined file", func(t *testing.T) {
+		args, _, err := varFileArgs(TerraformParams{
+			TFVars:   base,
+			Vars:     map[string]interface{}{"vpc_id": "vpc-1"},
+			RunID:    "merged",
+			TempRoot: tmp,
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"-var-file", filepath.Join(tmp, "terraform-orchestrator", "merged", "combined.tfvars.json")}, strings.Fields(canonicalVarFiles(strings.Join(args, " "))))
+	})
+
+	t.Run("each call gets its own generated file", func(t *testing.T) {
+		// Workspaces of a run share its temp dir and may run at once
+		params := TerraformParams{
+			TFVars:   base,
+			Vars:     map[string]interface{}{"vpc_id": "vpc-1"},
+			RunID:    "shared-run",
+			TempRoot: tmp,
+		}
+		first, cleanupFirst, err := varFileArgs(params)
+		require.NoError(t, err)
+		second, cleanupSecond, err := varFileArgs(params)
+		require.NoError(t, err)
+		defer cleanupSecond()
+		require.NotEqual(t, first[1], second[1])
+
+		cleanupFirst()
+		require.NoFileExists(t, first[1])
+		require.FileExists(t, second[1], "one workspace's cleanup must not remove another's var file")
+	})
+
+	t.Run("no vars at all passes nothing", func(t *testing.T) {
+		args, _, err := varFileArgs(TerraformParams{LayerVarFiles: true})
+		require.NoError(t, err)
+		require.Empty(t, args)
+	})
+}
+
+const testLockFile = `
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "5.31.0"
+  constraints = "~> 5.0"
+}
+
+provider "registry.terraform.io/hashicorp/random" {
+  version = "3.6.0"
+}
+`
+
+func TestTerraformInit_ProviderPolicy(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+
+	tmp := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmp, ".terraform.lock.hcl"), []byte(testLockFile), 0o644))
+
+	tests := []struct {
+		name   string
+		allow  []string
+		deny   []string
+		errMsg string
+	}{
+		{name: "no policy"},
+		{name: "all allowed", allow: []string{"hashicorp/aws", "hashicorp/random"}},
+		{name: "wildcard allow", allow: []string{"hashicorp/*"}},
+		{name: "full address allow", allow: []string{"registry.terraform.io/hashicorp/*"}},
+		{name: "allow everything", allow: []string{"*"}},
+		{
+			name:   "deny everything",
+			deny:   []string{"*"},
+			errMsg: "registry.terraform.io/hashicorp/aws is denied by *",
+		},
+		{
+			name:   "provider missing from allowlist",
+			allow:  []string{"hashicorp/aws"},
+			errMsg: "registry.terraform.io/hashicorp/random is not in the allowed providers",
+		},
+		{
+			name:   "denied provider",
+			deny:   []string{"hashicorp/random"},
+			errMsg: "registry.terraform.io/hashicorp/random is denied by hashicorp/random",
+		},
+		{
+			name:   "deny wins over allow",
+			allow:  []string{"hashicorp/*"},
+			deny:   []string{"hashicorp/random"},
+			errMsg: "is denied by hashicorp/random",
+		},
+	}
+
+	act := &TerraformActivities{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := act.TerraformInit(context.Background(), TerraformParams{
+				Dir:              tmp,
+				AllowedProviders: tt.allow,
+				DeniedProviders:  tt.deny,
+			})
+			if tt.errMsg == "" {
+				require.NoError(t, err)
+				return
+			}
+			var appErr *temporal.ApplicationError
+			require.ErrorAs(t, err, &appErr)
+			require.Equal(t, ProviderPolicyErrorType, appErr.Type())
+			require.True(t, appErr.NonRetryable())
+			require.Contains(t, err.Error(), "provider policy violation")
+			require.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestTerraformInit_ProviderPolicyReadsChdirLockFile(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+
+	tmp := t.TempDir()
+	module := filepath.Join(tmp, "modules", "vpc")
+	require.NoError(t, os.MkdirAll(module, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(module, ".terraform.lock.hcl"), []byte(testLockFile), 0o644))
+
+	act := &TerraformActivities{}
+	err := act.TerraformInit(context.Background(), TerraformParams{
+		Dir:             tmp,
+		Chdir:           "modules/vpc",
+		DeniedProviders: []string{"hashicorp/aws"},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "hashicorp/aws is denied")
+}
+
+func TestTerraformInit_ProviderPolicyWithoutLockFile(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+
+	act := &TerraformActivities{}
+	err := act.TerraformInit(context.Background(), TerraformParams{
+		Dir:              t.TempDir(),
+		AllowedProviders: []string{"hashicorp/aws"},
+	})
+	require.NoError(t, err, "a module without providers has nothing to violate")
+}
+
+func TestTerraformInitInfo_ParsesJSONLog(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	invocations := recordTerraformArgs(t)
+
+	logLines := []string{
+		`{"@level":"info","@message":"Initializing the backend...","@module":"terraform.ui","type":"init_output","message_code":"initializing_backend_message"}`,
+		`{"@level":"info","@message":"Successfully configured the backend \"s3\"! Terraform will automatically use this backend unless the backend configuration changes.","@module":"terraform.ui","type":"init_output"}`,
+		`{"@level":"info","@message":"hashicorp/aws: Finding matching versions for provider","@module":"terraform.ui","type":"log"}`,
+		`{"@level":"info","@message":"- Installed hashicorp/aws v5.31.0 (signed by HashiCorp)","@module":"terraform.ui","type":"log"}`,
+		`{"@level":"info","@message":"- Using previously-installed hashicorp/random v3.6.0","@module":"terraform.ui","type":"log"}`,
+		`not json`,
+		`{"@level":"info","@message":"Terraform has been successfully initialized!","@module":"terraform.ui","type":"init_output"}`,
+	}
+	logPath := filepath.Join(t.TempDir(), "init.jsonl")
+	require.NoError(t, os.WriteFile(logPath, []byte(strings.Join(logLines, "\n")+"\n"), 0o644))
+	t.Setenv("TF_INIT_JSON", logPath)
+
+	act := &TerraformActivities{}
+	info, err := act.TerraformInitInfo(context.Background(), TerraformParams{Dir: t.TempDir()})
+	require.NoError(t, err)
+
+	require.Equal(t, "s3", info.Backend)
+	require.Equal(t, []InstalledProvider{
+		{Source: "hashicorp/aws", Version: "5.31.0"},
+		{Source: "hashicorp/random", Version: "3.6.0", Reused: true},
+	}, info.Providers)
+	require.Len(t, info.Messages, 6)
+	require.Equal(t, "Terraform has been successfully initialized!", info.Messages[5])
+
+	require.Equal(t, []string{"init -json"}, invocations())
+}
+
+func TestTerraformInitInfo_AppliesProviderPolicy(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+
+	tmp := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmp, ".terraform.lock.hcl"), []byte(testLockFile), 0o644))
+
+	act := &TerraformActivities{}
+	_, err := act.TerraformInitInfo(context.Background(), TerraformParams{
+		Dir:             tmp,
+		DeniedProviders: []string{"hashicorp/random"},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "provider policy violation")
+}
+
+func TestTruncateOutput(t *testing.T) {
+	small := []byte("Error: something failed\n")
+	require.Equal(t, string(small), truncateOutput(small, 100), "small output is untouched")
+
+	large := []byte(strings.Repeat("a", 50) + strings.Repeat("m", 1000) + strings.Repeat("z", 50))
+	got := truncateOutput(large, 100)
+	require.Equal(t, strings.Repeat("a", 50)+"\n[...truncated 1000 bytes...]\n"+strings.Repeat("z", 50), got)
+
+	require.Equal(t, string(large), truncateOutput(large, 0), "output under the default limit is untouched")
+}
+
+// fakeTerraformWithNoisyFailure creates a shim terraform binary that prints
+// a large amount of output ending in an error and exits non-zero.
+func fakeTerraformWithNoisyFailure(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "terraform")
+	script := `#!/bin/sh
+echo "Initializing..."
+i=0
+while [ $i -lt 2000 ]; do
+  echo "module.vpc.aws_subnet.private[$i]: Still creating... [10s elapsed]"
+  i=$((i+1))
+done
+echo "Error: creating subnet: InvalidSubnet.Conflict"
+exit 1
+`
+	require.NoError(t, os.WriteFile(bin, []byte(script), 0o755))
+	return dir
+}
+
+func TestTerraformApply_TruncatesLargeOutputInError(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformWithNoisyFailure(t))
+
+	tmp := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmp, "apply.plan"), []byte("plan"), 0o644))
+	logDir := filepath.Join(t.TempDir(), "logs")
+
+	act := &TerraformActivities{}
+	_, err := act.TerraformApply(context.Background(), TerraformParams{
+		Dir:            tmp,
+		PlanFile:       "apply.plan",
+		MaxOutputBytes: 1024,
+		OutputLogDir:   logDir,
+	})
+	require.Error(t, err)
+	msg := err.Error()
+	require.Contains(t, msg, "Initializing...", "head is kept")
+	require.Contains(t, msg, "Error: creating subnet: InvalidSubnet.Conflict", "tail is kept")
+	require.Regexp(t, `\[\.\.\.truncated \d+ bytes\.\.\.\]`, msg)
+	require.Less(t, len(msg), 2048)
+
+	// The full output is preserved as an artifact
+	entries, readErr := os.ReadDir(logDir)
+	require.NoError(t, readErr)
+	require.Len(t, entries, 1)
+	require.True(t, strings.HasPrefix(entries[0].Name(), "terraform-apply-"))
+	require.Contains(t, msg, filepath.Join(logDir, entries[0].Name()))
+
+	full, readErr := os.ReadFile(filepath.Join(logDir, entries[0].Name()))
+	require.NoError(t, readErr)
+	require.Contains(t, string(full), "private[1000]")
+}
+
+func TestTerraformInit_SkipBackend(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	invocations := recordTerraformArgs(t)
+
+	act := &TerraformActivities{}
+	require.NoError(t, act.TerraformInit(context.Background(), TerraformParams{Dir: t.TempDir(), SkipBackend: true}))
+	require.Equal(t, []string{"init -backend=false"}, invocations())
+}
+
+func TestTerraformInit_SkipIfInitialized(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	act := &TerraformActivities{}
+
+	initialized := func(t *testing.T) string {
+		dir := t.TempDir()
+		require.NoError(t, os.Mkdir(filepath.Join(dir, ".terraform"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".terraform.lock.hcl"), []byte(`
+provider "registry.terraform.io/hashicorp/aws" {
+  version = "5.31.0"
+}
+`), 0o644))
+		return dir
+	}
+
+	t.Run("skips when initialized", func(t *testing.T) {
+		invocations := recordTerraformArgs(t)
+		require.NoError(t, act.TerraformInit(context.Background(), TerraformParams{Dir: initialized(t), SkipInitIfInitialized: true}))
+		require.Empty(t, invocations())
+	})
+
+	t.Run("runs without a .terraform directory", func(t *testing.T) {
+		invocations := recordTerraformArgs(t)
+		dir := initialized(t)
+		require.NoError(t, os.RemoveAll(filepath.Join(dir, ".terraform")))
+		require.NoError(t, act.TerraformInit(context.Background(), TerraformParams{Dir: dir, SkipInitIfInitialized: true}))
+		require.Equal(t, []string{"init"}, invocations())
+	})
+
+	t.Run("runs with an unreadable lock file", func(t *testing.T) {
+		invocations := recordTerraformArgs(t)
+		dir := initialized(t)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".terraform.lock.hcl"), []byte(`provider "x" {`), 0o644))
+		require.NoError(t, act.TerraformInit(context.Background(), TerraformParams{Dir: dir, SkipInitIfInitialized: true}))
+		require.Equal(t, []string{"init"}, invocations())
+	})
+
+	t.Run("upgrade forces init", func(t *testing.T) {
+		invocations := recordTerraformArgs(t)
+		require.NoError(t, act.TerraformInit(context.Background(), TerraformParams{Dir: initialized(t), SkipInitIfInitialized: true, InitUpgrade: true}))
+		require.Equal(t, []string{"init -upgrade"}, invocations())
+	})
+
+	t.Run("backend init after a validate-only init", func(t *testing.T) {
+		invocations := recordTerraformArgs(t)
+		dir := initialized(t)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`terraform {
+  backend "s3" {}
+}
+`), 0o644))
+
+		// ValidateOnlyWorkflow's init skips the backend and can reuse the module
+		require.NoError(t, act.TerraformInit(context.Background(), TerraformParams{Dir: dir, SkipBackend: true, SkipInitIfInitialized: true}))
+		require.Empty(t, invocations())
+
+		// A normal run still needs the backend initialized
+		require.NoError(t, act.TerraformInit(context.Background(), TerraformParams{Dir: dir, SkipInitIfInitialized: true}))
+		require.Equal(t, []string{"init"}, invocations())
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".terraform", "terraform.tfstate"), []byte(`{"backend":{"type":"s3"}}`), 0o644))
+		require.NoError(t, act.TerraformInit(context.Background(), TerraformParams{Dir: dir, SkipInitIfInitialized: true}))
+		require.Equal(t, []string{"init"}, invocations(), "a backend init is reused")
+	})
+
+	t.Run("skipped init still applies the provider policy", func(t *testing.T) {
+		invocations := recordTerraformArgs(t)
+		err := act.TerraformInit(context.Background(), TerraformParams{Dir: initialized(t), SkipInitIfInitialized: true, DeniedProviders: []string{"hashicorp/aws"}})
+		require.Error(t, err)
+		require.Empty(t, invocations())
+	})
+}
+
+func TestTerraformEffectiveVars_RedactsSensitiveValues(t *testing.T) {
+	tmp := t.TempDir()
+	base := filepath.Join(tmp, "base.tfvars")
+	require.NoError(t, os.WriteFile(base, []byte(`
+region      = "us-west-2"
+db_password = "hunter2"
+api_key     = "abc123"
+port        = 80
+`), 0o644))
+
+	act := &TerraformActivities{}
+	vars, err := act.TerraformEffectiveVars(context.Background(), TerraformParams{
+		Dir:           tmp,
+		TFVars:        base,
+		Vars:          map[string]interface{}{"port": "8080", "github_token": "ghp_x", "vpc_id": "vpc-1"},
+		VarTypes:      map[string]string{"port": "number"},
+		SensitiveVars: []string{"api_key"},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]interface{}{
+		"region":       "us-west-2",
+		"vpc_id":       "vpc-1",
+		"port":         float64(8080), // extra vars override and are coerced
+		"db_password":  RedactedValue, // secret-looking name
+		"github_token": RedactedValue, // secret-looking name
+		"api_key":      RedactedValue, // listed explicitly
+	}, vars)
+}
+
+func TestValidationCache_HitAfterRecord(t *testing.T) {
+	ctx := context.Background()
+	a := &TerraformActivities{}
+	tmp := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmp, "main.tf"), []byte(`variable "region" {}`), 0o644))
+	params := TerraformParams{Dir: tmp, Vars: map[string]interface{}{"region": "us-east-1"}}
+
+	first, err := a.CheckValidationCache(ctx, params)
+	require.NoError(t, err)
+	require.False(t, first.Hit)
+	require.NotEmpty(t, first.Key)
+
+	require.NoError(t, a.RecordValidationCache(ctx, params, first.Key))
+
+	// State and plan files written by later operations don't invalidate
+	require.NoError(t, os.WriteFile(filepath.Join(tmp, "terraform.tfstate"), []byte("{}"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmp, "tfplan"), []byte("plan"), 0o644))
+
+	second, err := a.CheckValidationCache(ctx, params)
+	require.NoError(t, err)
+	require.True(t, second.Hit)
+	require.Equal(t, first.Key, second.Key)
+}
+
+func TestValidationCache_MissOnChange(t *testing.T) {
+	providerDir := func(dir, version string) string {
+		return filepath.Join(dir, ".terraform", "providers", "registry.terraform.io", "hashicorp", "aws", version, "linux_amd64")
+	}
+
+	tests := []struct {
+		name   string
+		change func(t *testing.T, dir string, params *TerraformParams)
+	}{
+		{"module file", func(t *testing.T, dir string, _ *TerraformParams) {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`variable "zone" {}`), 0o644))
+		}},
+		{"new module file", func(t *testing.T, dir string, _ *TerraformParams) {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "outputs.tf"), []byte(`output "x" { value = 1 }`), 0o644))
+		}},
+		{"lock file", func(t *testing.T, dir string, _ *TerraformParams) {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, ".terraform.lock.hcl"), []byte(`provider "registry.terraform.io/hashicorp/aws" { version = "5.32.0" }`), 0o644))
+		}},
+		{"tfvars", func(t *testing.T, _ string, params *TerraformParams) {
+			require.NoError(t, os.WriteFile(params.TFVars, []byte(`region = "eu-west-1"`), 0o644))
+		}},
+		{"vars", func(t *testing.T, _ string, params *TerraformParams) {
+			params.Vars = map[string]interface{}{"vpc_id": "vpc-2"}
+		}},
+		{"provider version", func(t *testing.T, dir string, _ *TerraformParams) {
+			require.NoError(t, os.RemoveAll(filepath.Join(dir, ".terraform", "providers")))
+			require.NoError(t, os.MkdirAll(providerDir(dir, "5.32.0"), 0o755))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			a := &TerraformActivities{}
+			tmp := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(tmp, "main.tf"), []byte(`variable "region" {}`), 0o644))
+			require.NoError(t, os.WriteFile(filepath.Join(tmp, ".terraform.lock.hcl"), []byte(`provider "registry.terraform.io/hashicorp/aws" { version = "5.31.0" }`), 0o644))
+			require.NoError(t, os.MkdirAll(providerDir(tmp, "5.31.0"), 0o755))
+			tfvars := filepath.Join(t.TempDir(), "vars.tfvars")
+			require.NoError(t, os.WriteFile(tfvars, []byte(`region = "us-east-1"`), 0o644))
+			params := TerraformParams{Dir: tmp, TFVars: tfvars, Vars: map[string]interface{}{"vpc_id": "vpc-1"}}
+
+			before, err := a.CheckValidationCache(ctx, params)
+			require.NoError(t, err)
+			require.NoError(t, a.RecordValidationCache(ctx, params, before.Key))
+
+			tt.change(t, tmp, &params)
+
+			after, err := a.CheckValidationCache(ctx, params)
+			require.NoError(t, err)
+			require.False(t, after.Hit)
+			require.NotEqual(t, before.Key, after.Key)
+		})
+	}
+}
+
+func TestPlannedAttribute_ExtractsValues(t *testing.T) {
+	plan, err := os.ReadFile(filepath.Join("testdata", "plan.json"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		address, path string
+		want          interface{}
+	}{
+		{"aws_vpc.main", "cidr_block", "10.0.0.0/16"},
+		{"aws_vpc.main", "tags.Name", "main"},
+		{"aws_security_group.web", "ingress[0].cidr_blocks[1]", "10.0.2.0/24"},
+		{"aws_security_group.web", "ingress.0.from_port", float64(443)},
+		{"aws_security_group.web", "ingress[0].cidr_blocks", []interface{}{"10.0.1.0/24", "10.0.2.0/24"}},
+	}
+	for _, tt := range tests {
+		value, found, err := PlannedAttribute(plan, tt.address, tt.path)
+		require.NoError(t, err, tt.path)
+		require.True(t, found, tt.path)
+		require.Equal(t, tt.want, value, tt.path)
+	}
+}
+
+func TestPlannedAttribute_MissingValues(t *testing.T) {
+	plan, err := os.ReadFile(filepath.Join("testdata", "plan.json"))
+	require.NoError(t, err)
+
+	for _, tt := range []struct{ address, path string }{
+		{"aws_vpc.missing", "cidr_block"},             // no such resource
+		{"aws_vpc.main", "tags.owner"},                // no such attribute
+		{"aws_security_group.web", "ingress[3].name"}, // index out of range
+		{"aws_subnet.old", "cidr_block"},              // resource is deleted
+	} {
+		value, found, err := PlannedAttribute(plan, tt.address, tt.path)
+		require.NoError(t, err, tt.address+" "+tt.path)
+		require.False(t, found, tt.address+" "+tt.path)
+		require.Nil(t, value)
+	}
+}
+
+func TestPlannedAttribute_Errors(t *testing.T) {
+	plan, err := os.ReadFile(filepath.Join("testdata", "plan.json"))
+	require.NoError(t, err)
+
+	_, _, err = PlannedAttribute(plan, "aws_vpc.main", "id")
+	require.ErrorContains(t, err, "known only after apply")
+
+	_, _, err = PlannedAttribute(plan, "aws_vpc.main", "tags..Name")
+	require.ErrorContains(t, err, "invalid attribute path")
+
+	_, _, err = PlannedAttribute([]byte("not json"), "aws_vpc.main", "id")
+	require.ErrorContains(t, err, "failed to parse plan JSON")
+}
+
+func TestTerraformShowPlan(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	a := &TerraformActivities{}
+	tmp := t.TempDir()
+	params := TerraformParams{Dir: tmp}
+
+	_, err := a.TerraformShowPlan(context.Background(), params)
+	require.ErrorContains(t, err, "plan file not found")
+
+	require.NoError(t, os.WriteFile(filepath.Join(tmp, "tfplan"), []byte("plan"), 0o644))
+	plan, err := a.TerraformShowPlan(context.Background(), params)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"format_version":"1.0"}`, string(plan))
+}
+
+func TestTerraformPlanSummary(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	fixture, err := filepath.Abs(filepath.Join("testdata", "plan.json"))
+	require.NoError(t, err)
+	t.Setenv("TF_SHOW_JSON", fixture)
+
+	a := &TerraformActivities{}
+	tmp := t.TempDir()
+	params := TerraformParams{Dir: tmp}
+	require.NoError(t, os.WriteFile(filepath.Join(tmp, "tfplan"), []byte("plan"), 0o644))
+
+	summary, err := a.TerraformPlanSummary(context.Background(), params)
+	require.NoError(t, err)
+	require.Equal(t, PlanSummary{
+		Text:    "Terraform will perform the following actions:\n",
+		Add:     1,
+		Change:  1,
+		Destroy: 1,
+	}, summary)
+}
+
+func TestCountPlanChanges_Replacement(t *testing.T) {
+	plan, err := parsePlanJSON([]byte(`{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "a", "change": {"actions": ["delete", "create"]}},
+    {"address": "b", "change": {"actions": ["create", "delete"]}},
+    {"address": "c", "change": {"actions": ["no-op"]}},
+    {"address": "d", "change": {"actions": ["read"]}},
+    {"address": "e", "change": {"actions": ["update"]}}
+  ]
+}`))
+	require.NoError(t, err)
+	require.Equal(t, PlanSummary{Add: 2, Change: 1, Destroy: 2}, countPlanChanges(plan))
+}
+
+func TestParseOutputs_KeepsSensitivityAndType(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "outputs.json"))
+	require.NoError(t, err)
+
+	outputs, err := parseOutputs(data)
+	require.NoError(t, err)
+	require.Len(t, outputs, 5)
+
+	require.Equal(t, OutputValue{Value: "vpc-0a1b2c", Type: "string"}, outputs["vpc_id"])
+	require.True(t, outputs["db_password"].Sensitive)
+	require.Equal(t, []interface{}{"list", "string"}, outputs["subnet_ids"].Type)
+	require.Equal(t, []interface{}{"map", "string"}, outputs["tags"].Type)
+	require.Equal(t, "number", outputs["node_count"].Type)
+
+	// The plain value map is unchanged for dependents
+	require.Equal(t, map[string]interface{}{
+		"vpc_id":      "vpc-0a1b2c",
+		"db_password": "hunter2",
+		"subnet_ids":  []interface{}{"subnet-1", "subnet-2"},
+		"tags":        map[string]interface{}{"env": "dev"},
+		"node_count":  float64(3),
+	}, outputValues(outputs))
+}
+
+func TestParseOutputs_InvalidJSON(t *testing.T) {
+	_, err := parseOutputs([]byte("Warning: No outputs found"))
+	require.ErrorContains(t, err, "failed to parse terraform output")
+}
+
+func TestTerraformOutputDetails(t *testing.T) {
+	t.Setenv("PATH", fakeTerraformOnPath(t))
+	act := &TerraformActivities{}
+
+	outputs, err := act.TerraformOutputDetails(context.Background(), TerraformParams{Dir: t.TempDir()})
+	require.NoError(t, err)
+	require.Equal(t, map[string]OutputValue{"vpc_id": {Value: "example-vpc-id"}}, outputs)
+}
+
+func TestParsePlanJSON_FormatVersion(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("testdata", "plan.json"))
+	require.NoError(t, err)
+
+	plan, err := parsePlanJSON(fixture)
+	require.NoError(t, err)
+	require.Equal(t, "1.0", plan.FormatVersion)
+	require.Len(t, plan.ResourceChanges, 3)
+
+	// Minor versions only add fields
+	_, err = parsePlanJSON([]byte(`{"format_version":"1.2","resource_changes":[]}`))
+	require.NoError(t, err)
+
+	_, err = parsePlanJSON([]byte(`{"format_version":"2.0","resource_changes":[]}`))
+	require.ErrorContains(t, err, `unsupported plan JSON format_version "2.0": supported versions are 1.x`)
+
+	_, err = parsePlanJSON([]byte(`{"resource_changes":[]}`))
+	require.ErrorContains(t, err, "plan JSON has no format_version")
+}
+
+func TestPlannedAttribute_RejectsUnknownFormatVersion(t *testing.T) {
+	plan := []byte(`{"format_version":"2.0","resource_changes":[{"address":"aws_vpc.main","change":{"after":{"cidr_block":"10.0.0.0/16"}}}]}`)
+
+	_, _, err := PlannedAttribute(plan, "aws_vpc.main", "cidr_block")
+	require.ErrorContains(t, err, "unsupported plan JSON format_version")
+}
+
+func TestParseApplySummary(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   ApplyResult
+	}{
+		{
+			name:   "summary line",
+			output: "aws_vpc.main: Creating...\naws_vpc.main: Creation complete after 2s\n\nApply complete! Resources: 1 added, 0 changed, 0 destroyed.\n",
+			want:   ApplyResult{Applied: true, Added: 1},
+		},
+		{
+			name:   "with imports",
+			output: "Apply complete! Resources: 2 imported, 3 added, 4 changed, 5 destroyed.\n\nOutputs:\n",
+			want:   ApplyResult{Applied: true, Imported: 2, Added: 3, Changed: 4, Destroyed: 5},
+		},
+		{
+			name:   "no summary",
+			output: "nothing recognizable",
+			want:   ApplyResult{Applied: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, parseApplySummary([]byte(tt.output)))
+		})
+	}
 }
@@ -0,0 +1,50 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// OutputValue is one entry of `terraform output -json`.
+type OutputValue struct {
+	Value     interface{} `json:"value"`
+	Sensitive bool        `json:"sensitive"`
+	// Type is terraform's JSON type constraint, e.g. "string" or
+	// ["list", "string"].
+	Type interface{} `json:"type,omitempty"`
+}
+
+// TerraformOutputDetails is TerraformOutput keeping each output's sensitivity
+// and type. TerraformWorkflow reads outputs with it, so inputs mapped from
+// sensitive outputs can be redacted in dependents.
+func (a *TerraformActivities) TerraformOutputDetails(ctx context.Context, params TerraformParams) (map[string]OutputValue, error) {
+	if err := validatePaths(params); err != nil {
+		return nil, err
+	}
+
+	output, err := terraformCommand(ctx, params, "output", "-json").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("terraform output failed: %v, output: %s", err, errorOutput(params, "output", output))
+	}
+	return parseOutputs(output)
+}
+
+// parseOutputs decodes `terraform output -json`, keeping sensitivity and type.
+func parseOutputs(data []byte) (map[string]OutputValue, error) {
+	var outputs map[string]OutputValue
+	if err := json.Unmarshal(data, &outputs); err != nil {
+		return nil, fmt.Errorf("failed to parse terraform output: %v", err)
+	}
+	return outputs, nil
+}
+
+// outputValues drops output metadata, leaving the name -> value map
+// dependents consume.
+func outputValues(outputs map[string]OutputValue) map[string]interface{} {
+	values := make(map[string]interface{}, len(outputs))
+	for name, out := range outputs {
+		values[name] = out.Value
+	}
+	return values
+}
@@ -0,0 +1,160 @@
+package activities
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// validationCacheFile lives inside terraform's .terraform directory, so
+// removing that directory (which also forces a fresh init) clears the cache.
+const validationCacheFile = "orchestrator-validation.sha256"
+
+// ValidationCacheResult reports whether init/validate can be skipped.
+type ValidationCacheResult struct {
+	Key string // Content hash of the module, tfvars and installed providers
+	Hit bool   // Key matches the last successful validation
+}
+
+// CheckValidationCache hashes the module and compares it with the key
+// recorded by the last successful init/validate.
+func (a *TerraformActivities) CheckValidationCache(ctx context.Context, params TerraformParams) (ValidationCacheResult, error) {
+	if err := validatePaths(params); err != nil {
+		return ValidationCacheResult{}, err
+	}
+
+	key, err := validationCacheKey(params)
+	if err != nil {
+		return ValidationCacheResult{}, err
+	}
+
+	recorded, err := os.ReadFile(validationCachePath(params))
+	if err != nil {
+		return ValidationCacheResult{Key: key}, nil // nothing recorded yet
+	}
+	return ValidationCacheResult{Key: key, Hit: strings.TrimSpace(string(recorded)) == key}, nil
+}
+
+// RecordValidationCache stores the key computed before a successful
+// init/validate. Using the earlier key means edits made while validation ran
+// are never marked as validated.
+func (a *TerraformActivities) RecordValidationCache(ctx context.Context, params TerraformParams, key string) error {
+	path := validationCachePath(params)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create validation cache directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(key+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write validation cache: %v", err)
+	}
+	return nil
+}
+
+func validationCachePath(params TerraformParams) string {
+	return filepath.Join(params.Dir, params.Chdir, ".terraform", validationCacheFile)
+}
+
+// validationCacheKey hashes everything init/validate depend on: the module's
+// files (including .terraform.lock.hcl), the installed provider versions
+// under .terraform/providers, the tfvars file and the extra vars.
+func validationCacheKey(params TerraformParams) (string, error) {
+	moduleDir := filepath.Join(params.Dir, params.Chdir)
+	h := sha256.New()
+
+	var files []string
+	err := filepath.WalkDir(moduleDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(moduleDir, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && rel == ".terraform" {
+			return fs.SkipDir
+		}
+		if d.Type().IsRegular() && !isTerraformArtifact(d.Name()) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash module: %v", err)
+	}
+	sort.Strings(files)
+
+	for _, rel := range files {
+		if err := hashFile(h, "file:"+filepath.ToSlash(rel), filepath.Join(moduleDir, rel)); err != nil {
+			return "", err
+		}
+	}
+
+	// Provider install paths (<host>/<namespace>/<name>/<version>) change
+	// whenever init installs a different provider version
+	providersDir := filepath.Join(moduleDir, ".terraform", "providers")
+	var providers []string
+	_ = filepath.WalkDir(providersDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // no providers installed yet
+		}
+		rel, err := filepath.Rel(providersDir, path)
+		if err != nil || rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if strings.Count(rel, "/") == 3 {
+			providers = append(providers, rel)
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+		}
+		return nil
+	})
+	sort.Strings(providers)
+	fmt.Fprintf(h, "providers:%s\n", strings.Join(providers, ","))
+
+	if params.TFVars != "" {
+		if err := hashFile(h, "tfvars", params.TFVars); err != nil {
+			return "", err
+		}
+	}
+	vars, err := json.Marshal(params.Vars) // map keys are sorted
+	if err != nil {
+		return "", fmt.Errorf("failed to hash vars: %v", err)
+	}
+	fmt.Fprintf(h, "vars:%s\n", vars)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFile(h io.Writer, label, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to hash %s: %v", path, err)
+	}
+	defer f.Close()
+
+	fmt.Fprintf(h, "%s\n", label)
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to hash %s: %v", path, err)
+	}
+	fmt.Fprint(h, "\n")
+	return nil
+}
+
+// isTerraformArtifact reports files terraform writes while running, which
+// must not invalidate the cache.
+func isTerraformArtifact(name string) bool {
+	return strings.HasSuffix(name, ".tfstate") ||
+		strings.HasSuffix(name, ".tfstate.backup") ||
+		strings.HasSuffix(name, ".plan") ||
+		name == "tfplan" ||
+		name == ".terraform.tfstate.lock.info"
+}
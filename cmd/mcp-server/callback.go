@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fakoli/temporal-terraform-orchestrator/workflow"
+	"go.temporal.io/sdk/client"
+)
+
+const (
+	callbackAttempts     = 3
+	callbackRetryBackoff = 2 * time.Second
+
+	// callbackShutdownGrace bounds how long shutdown waits for pending
+	// callbacks; workflows can run for hours, so it can't wait for them all.
+	callbackShutdownGrace = 30 * time.Second
+)
+
+// callbackPayload is POSTed to a callback_url once the workflow closes.
+type callbackPayload struct {
+	WorkflowID string          `json:"workflow_id"`
+	RunID      string          `json:"run_id"`
+	Status     string          `json:"status"`           // Completed or Failed
+	Result     json.RawMessage `json:"result,omitempty"` // partial result when Failed
+	Error      string          `json:"error,omitempty"`
+}
+
+// callbackDispatcher delivers workflow results to callback URLs in the
+// background and tracks deliveries still pending so shutdown can drain them.
+// Delivery is best-effort: pending callbacks live only in this process, so
+// those dropped at shutdown are lost.
+type callbackDispatcher struct {
+	httpClient *http.Client
+	backoff    time.Duration
+	wg         sync.WaitGroup
+
+	// ctx is cancelled when shutdown gives up on pending callbacks
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+var callbacks = newCallbackDispatcher(newCallbackClient(), callbackRetryBackoff)
+
+// callbackAllowedHosts, from CALLBACK_ALLOWED_HOSTS, are the only hosts
+// callbacks may go to when set. When unset any host is accepted except one
+// resolving to a loopback, link-local, private or unspecified address, so a
+// caller can't have the server POST into the network it runs in.
+var callbackAllowedHosts []string
+
+// newCallbackClient returns the HTTP client callbacks are sent with. It
+// checks the address of every connection and the target of every redirect,
+// so neither DNS nor a redirect can lead around validateCallbackURL. It
+// dials directly rather than through a proxy, whose address would be
+// checked instead of the callback's.
+func newCallbackClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			return checkCallbackIP(net.ParseIP(host))
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return checkCallbackHost(req.Context(), req.URL)
+		},
+	}
+}
+
+func newCallbackDispatcher(httpClient *http.Client, backoff time.Duration) *callbackDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &callbackDispatcher{httpClient: httpClient, backoff: backoff, ctx: ctx, cancel: cancel}
+}
+
+// validateCallbackURL accepts absolute http(s) URLs to hosts callbacks may
+// go to.
+func validateCallbackURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid callback_url %q: expected an absolute http(s) URL", raw)
+	}
+	if err := checkCallbackHost(ctx, u); err != nil {
+		return fmt.Errorf("invalid callback_url %q: %v", raw, err)
+	}
+	return nil
+}
+
+// checkCallbackHost checks u's host against callbackAllowedHosts or, when
+// there are none, checks every address it resolves to.
+func checkCallbackHost(ctx context.Context, u *url.URL) error {
+	host := u.Hostname()
+	if len(callbackAllowedHosts) > 0 {
+		if !slices.ContainsFunc(callbackAllowedHosts, func(allowed string) bool { return strings.EqualFold(allowed, host) }) {
+			return fmt.Errorf("host %s is not in CALLBACK_ALLOWED_HOSTS", host)
+		}
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return checkCallbackIP(ip)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("cannot resolve host %s: %v", host, err)
+	}
+	for _, addr := range addrs {
+		if err := checkCallbackIP(addr.IP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCallbackIP rejects addresses inside the server's own network unless
+// the operator has listed the hosts callbacks may go to.
+func checkCallbackIP(ip net.IP) error {
+	if len(callbackAllowedHosts) > 0 {
+		return nil
+	}
+	if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsPrivate() || ip.IsUnspecified() {
+		return fmt.Errorf("callbacks to %s are not allowed; list internal hosts in CALLBACK_ALLOWED_HOSTS", ip)
+	}
+	return nil
+}
+
+// dispatch waits for the workflow run to close and POSTs its final status.
+func (d *callbackDispatcher) dispatch(we client.WorkflowRun, callbackURL string) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+
+		payload := callbackPayload{WorkflowID: we.GetID(), RunID: we.GetRunID(), Status: "Completed"}
+		var result json.RawMessage
+		if err := we.Get(d.ctx, &result); err != nil {
+			if d.ctx.Err() != nil {
+				log.Printf("Dropping callback for workflow %s to %s: server shut down before the workflow closed", payload.WorkflowID, callbackURL)
+				return
+			}
+			payload.Status = "Failed"
+			payload.Error = err.Error()
+			// Failed orchestrations still report the workspaces that succeeded
+			if partial, ok := workflow.PartialResult(err); ok {
+				if data, err := json.Marshal(partial); err == nil {
+					payload.Result = data
+				}
+			}
+		} else {
+			payload.Result = result
+		}
+
+		if err := d.post(d.ctx, callbackURL, payload); err != nil {
+			log.Printf("Callback for workflow %s failed: %v", payload.WorkflowID, err)
+		}
+	}()
+}
+
+func (d *callbackDispatcher) post(ctx context.Context, callbackURL string, payload callbackPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode callback: %v", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to build callback request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := d.httpClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 300 {
+				return nil
+			}
+			err = fmt.Errorf("callback returned %s", resp.Status)
+		}
+		if attempt == callbackAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(d.backoff):
+		}
+	}
+}
+
+// wait blocks until every pending callback has been delivered (or has
+// exhausted its retries).
+func (d *callbackDispatcher) wait() {
+	d.wg.Wait()
+}
+
+// shutdown waits up to grace for pending callbacks, then cancels the rest,
+// logging each one dropped, and returns once they have stopped.
+func (d *callbackDispatcher) shutdown(grace time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(grace):
+		d.cancel()
+		<-done
+	}
+}
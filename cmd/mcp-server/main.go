@@ -6,18 +6,38 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/google/uuid"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/fakoli/temporal-terraform-orchestrator/activities"
 	"github.com/fakoli/temporal-terraform-orchestrator/utils"
 	"github.com/fakoli/temporal-terraform-orchestrator/workflow"
+	"go.temporal.io/api/enums/v1"
 	"go.temporal.io/sdk/client"
 )
 
 func main() {
+	if limit := os.Getenv(utils.MaxWorkspacesEnv); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			log.Fatalf("Invalid %s %q: %v", utils.MaxWorkspacesEnv, limit, err)
+		}
+		maxWorkspaces = n
+	}
+	for _, host := range strings.Split(os.Getenv("CALLBACK_ALLOWED_HOSTS"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			callbackAllowedHosts = append(callbackAllowedHosts, host)
+		}
+	}
+
 	// 1. Initialize Temporal Client
 	c, err := client.Dial(client.Options{})
 	if err != nil {
@@ -32,78 +52,202 @@ func main() {
 	s.AddTool(mcp.NewTool("list_workflows",
 		mcp.WithDescription("List available Temporal workflows and configured workspaces from infra.yaml"),
 		mcp.WithString("config_path", mcp.Description("Path to YAML config file (defaults to infra.yaml)")),
+		compactOption,
 	), listWorkflowsHandler)
 
 	// --- Tool: execute_workflow ---
 	s.AddTool(mcp.NewTool("execute_workflow",
 		mcp.WithDescription("Execute a terraform orchestration workflow"),
-		mcp.WithString("workflow_name", mcp.Description("Name of the workflow (e.g. ParentWorkflow)"), mcp.Required()),
+		mcp.WithString("workflow_name", mcp.Description("Name of the workflow: ParentWorkflow, or ValidateOnlyWorkflow to run terraform validate without plan/apply"), mcp.Required()),
 		mcp.WithString("config_path", mcp.Description("Path to YAML config on server")),
 		mcp.WithObject("config", mcp.Description("Inline configuration payload (JSON)")),
+		mcp.WithBoolean("dry_run", mcp.Description("Validate and normalize the config and return the execution schedule without starting the workflow")),
+		mcp.WithString("callback_url", mcp.Description("URL to POST the final status and result to once the workflow closes, instead of polling. Best-effort: callbacks still pending when the server stops are not delivered, so poll get_workflow_status if the result matters")),
+		compactOption,
 	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return executeWorkflowHandler(ctx, c, request)
 	})
 
+	// --- Tool: execute_workflows ---
+	s.AddTool(mcp.NewTool("execute_workflows",
+		mcp.WithDescription("Start a ParentWorkflow for each of several configs, reporting per-item errors without aborting the batch"),
+		mcp.WithArray("workflows",
+			mcp.Description("Items of {config_path | config, workflow_id_suffix}"),
+			mcp.Required(),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"config_path":        map[string]any{"type": "string", "description": "Path to YAML config on server"},
+					"config":             map[string]any{"type": "object", "description": "Inline configuration payload (JSON)"},
+					"workflow_id_suffix": map[string]any{"type": "string", "description": "Suffix appended to the workflow ID (defaults to a unique value)"},
+				},
+			}),
+		),
+		compactOption,
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return executeWorkflowsHandler(ctx, c, request)
+	})
+
+	// --- Tool: lint_config ---
+	s.AddTool(mcp.NewTool("lint_config",
+		mcp.WithDescription("Check a config for likely mistakes (unused dependencies, shared dirs, deep chains, workspaces without variables); warnings never block execution"),
+		mcp.WithString("config_path", mcp.Description("Path to YAML config on server")),
+		mcp.WithObject("config", mcp.Description("Inline configuration payload (JSON)")),
+		compactOption,
+	), lintConfigHandler)
+
+	// --- Tool: validate_config ---
+	s.AddTool(mcp.NewTool("validate_config",
+		mcp.WithDescription("Validate a config's structure and dependency graph (cycles, unknown dependencies, bad operations) without running anything, returning each workspace's depth and the execution order"),
+		mcp.WithString("config_path", mcp.Description("Path to YAML config on server")),
+		mcp.WithObject("config", mcp.Description("Inline configuration payload (JSON)")),
+		compactOption,
+	), validateConfigHandler)
+
+	// --- Tool: plan_preview ---
+	s.AddTool(mcp.NewTool("plan_preview",
+		mcp.WithDescription("Run a plan-only orchestration (refresh, apply and destroy dropped) and return each workspace's add/change/destroy counts, e.g. for a pull request comment. Waits for the run to finish"),
+		mcp.WithString("config_path", mcp.Description("Path to YAML config on server")),
+		mcp.WithObject("config", mcp.Description("Inline configuration payload (JSON)")),
+		mcp.WithBoolean("include_text", mcp.Description("Include each workspace's rendered plan text")),
+		compactOption,
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return planPreviewHandler(ctx, c, request)
+	})
+
+	// --- Tool: detect_drift ---
+	s.AddTool(mcp.NewTool("detect_drift",
+		mcp.WithDescription("Run a drift check (a refresh-only plan per workspace; nothing is applied) and return the workspaces whose infrastructure changed outside terraform. Waits for the run to finish"),
+		mcp.WithString("config_path", mcp.Description("Path to YAML config on server")),
+		mcp.WithObject("config", mcp.Description("Inline configuration payload (JSON)")),
+		compactOption,
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return detectDriftHandler(ctx, c, request)
+	})
+
 	// --- Tool: get_workflow_status ---
 	s.AddTool(mcp.NewTool("get_workflow_status",
 		mcp.WithDescription("Get the status of a specific workflow execution"),
 		mcp.WithString("workflow_id", mcp.Description("The ID of the workflow to check"), mcp.Required()),
+		mcp.WithBoolean("include_outputs", mcp.Description("Include workspace outputs once the workflow has completed")),
+		mcp.WithBoolean("flatten_outputs", mcp.Description("Key included outputs as workspace.output, reporting any collisions")),
+		compactOption,
 	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		return getWorkflowStatusHandler(ctx, c, request)
 	})
 
 	// Start server on stdio
-	if err := server.ServeStdio(s); err != nil {
-		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+	serveErr := server.ServeStdio(s)
+
+	// Deliver callbacks for workflows started before shutdown, dropping those
+	// still pending after the grace period
+	log.Printf("Waiting up to %s for pending workflow callbacks", callbackShutdownGrace)
+	callbacks.shutdown(callbackShutdownGrace)
+
+	if serveErr != nil {
+		fmt.Fprintf(os.Stderr, "Server error: %v\n", serveErr)
+		c.Close()
 		os.Exit(1)
 	}
 }
 
+// compactOption lets any tool returning JSON emit it without indentation.
+var compactOption = mcp.WithBoolean("compact", mcp.Description("Emit compact JSON instead of indented, to reduce response size"))
+
+// listWorkflowsResponse is the list_workflows result. The shape is the same
+// whether or not the config file exists; Status tells the cases apart.
+type listWorkflowsResponse struct {
+	Status        string         `json:"status"` // "ok" or "no_config"
+	Message       string         `json:"message,omitempty"`
+	ConfigPath    string         `json:"config_path"`
+	WorkspaceRoot string         `json:"workspace_root,omitempty"`
+	Workflows     []workflowInfo `json:"workflows"`
+
+	// InputSchema describes the config format; included with no_config so
+	// clients know what to provide.
+	InputSchema map[string]interface{} `json:"input_schema,omitempty"`
+}
+
+// workflowInfo describes one workflow and the workspaces it would run.
+type workflowInfo struct {
+	Name                 string                   `json:"name"`
+	Description          string                   `json:"description"`
+	WorkspaceCount       int                      `json:"workspace_count"`
+	ConfiguredWorkspaces []map[string]interface{} `json:"configured_workspaces"`
+}
+
+// configInputSchema summarizes the config format for clients without a config file.
+var configInputSchema = map[string]interface{}{
+	"workspace_root": "string (optional base path)",
+	"workspaces": []map[string]interface{}{
+		{
+			"name":      "string",
+			"kind":      "string (default: terraform)",
+			"dir":       "string (path to terraform dir)",
+			"tfvars":    "string (optional path to tfvars)",
+			"dependsOn": "array<string>",
+			"waitFor":   "array<string> (ordering-only dependencies)",
+			"taskQueue": "string (optional override)",
+		},
+	},
+}
+
+// availableWorkflows lists the workflows the server can start, each reporting
+// the given workspaces.
+func availableWorkflows(workspaces []map[string]interface{}) []workflowInfo {
+	return []workflowInfo{
+		{
+			Name:                 "ParentWorkflow",
+			Description:          "Orchestrates terraform operations across multiple workspaces with dependencies",
+			WorkspaceCount:       len(workspaces),
+			ConfiguredWorkspaces: workspaces,
+		},
+		{
+			Name:                 "ValidateOnlyWorkflow",
+			Description:          "Runs structural validation and terraform init/validate for every workspace without plan or apply",
+			WorkspaceCount:       len(workspaces),
+			ConfiguredWorkspaces: workspaces,
+		},
+	}
+}
+
 func listWorkflowsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	configPath := mcp.ParseString(request, "config_path", "infra.yaml")
 
-	// Try to load the config file
-	config, err := workflow.LoadConfigFromFile(configPath)
-	if err != nil {
-		// If file doesn't exist, return static schema info
-		info := map[string]interface{}{
-			"error": fmt.Sprintf("Config file not found: %s. Using default schema.", configPath),
-			"workflows": []map[string]interface{}{
-				{
-					"name":        "ParentWorkflow",
-					"description": "Orchestrates terraform operations across multiple workspaces with dependencies",
-					"input_schema": map[string]interface{}{
-						"workspace_root": "string (optional base path)",
-						"workspaces": []map[string]interface{}{
-							{
-								"name":      "string",
-								"kind":      "string (default: terraform)",
-								"dir":       "string (path to terraform dir)",
-								"tfvars":    "string (optional path to tfvars)",
-								"dependsOn": "array<string>",
-								"taskQueue": "string (optional override)",
-							},
-						},
-					},
-				},
-			},
+	var response listWorkflowsResponse
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		// No config: report the workflows and the schema in the same shape
+		response = listWorkflowsResponse{
+			Status:      "no_config",
+			Message:     fmt.Sprintf("Config file not found: %s", configPath),
+			ConfigPath:  configPath,
+			Workflows:   availableWorkflows([]map[string]interface{}{}),
+			InputSchema: configInputSchema,
 		}
-		res, err := json.MarshalIndent(info, "", "  ")
+	} else {
+		config, err := workflow.LoadConfigFromFile(configPath)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to marshal response: %v", err)), nil
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to load config: %v", err)), nil
+		}
+		config, err = workflow.PrepareConfig(config)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid config: %v", err)), nil
 		}
-		return mcp.NewToolResultText(string(res)), nil
-	}
 
-	// Validate the config
-	if err := workflow.ValidateInfrastructureConfig(config); err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Invalid config: %v", err)), nil
+		response = listWorkflowsResponse{
+			Status:        "ok",
+			ConfigPath:    configPath,
+			WorkspaceRoot: config.WorkspaceRoot,
+			Workflows:     availableWorkflows(workspaceSummaries(config)),
+		}
 	}
 
-	// Normalize paths
-	config = workflow.NormalizeInfrastructureConfig(config)
+	return jsonResult(request, response)
+}
 
-	// Build response with actual workspace data
+// workspaceSummaries reports the fields of each workspace useful for picking
+// what to run.
+func workspaceSummaries(config workflow.InfrastructureConfig) []map[string]interface{} {
 	workspaces := make([]map[string]interface{}, 0, len(config.Workspaces))
 	for _, ws := range config.Workspaces {
 		wsInfo := map[string]interface{}{
@@ -111,6 +255,11 @@ func listWorkflowsHandler(ctx context.Context, request mcp.CallToolRequest) (*mc
 			"kind":      ws.Kind,
 			"dir":       ws.Dir,
 			"dependsOn": ws.DependsOn,
+			// After normalization, so defaults and mode trimming are visible
+			"operations": ws.Operations,
+		}
+		if len(ws.WaitFor) > 0 {
+			wsInfo["waitFor"] = ws.WaitFor
 		}
 		if ws.TFVars != "" {
 			wsInfo["tfvars"] = ws.TFVars
@@ -120,36 +269,96 @@ func listWorkflowsHandler(ctx context.Context, request mcp.CallToolRequest) (*mc
 		}
 		workspaces = append(workspaces, wsInfo)
 	}
-
-	info := map[string]interface{}{
-		"config_path":    configPath,
-		"workspace_root": config.WorkspaceRoot,
-		"workflows": []map[string]interface{}{
-			{
-				"name":                  "ParentWorkflow",
-				"description":           "Orchestrates terraform operations across multiple workspaces with dependencies",
-				"configured_workspaces": workspaces,
-				"workspace_count":       len(workspaces),
-			},
-		},
-	}
-
-	res, err := json.MarshalIndent(info, "", "  ")
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Failed to marshal response: %v", err)), nil
-	}
-	return mcp.NewToolResultText(string(res)), nil
+	return workspaces
 }
 
 func executeWorkflowHandler(ctx context.Context, c client.Client, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	name := mcp.ParseString(request, "workflow_name", "")
 	configPath := mcp.ParseString(request, "config_path", "")
 	configRaw := mcp.ParseStringMap(request, "config", nil)
+	dryRun := mcp.ParseBoolean(request, "dry_run", false)
+	callbackURL := mcp.ParseString(request, "callback_url", "")
 
-	if name != "ParentWorkflow" {
+	var workflowFn interface{}
+	idPrefix := utils.WorkflowID
+	switch name {
+	case "ParentWorkflow":
+		workflowFn = workflow.ParentWorkflow
+	case "ValidateOnlyWorkflow":
+		workflowFn = workflow.ValidateOnlyWorkflow
+		idPrefix = utils.WorkflowID + "-validate"
+	default:
 		return mcp.NewToolResultError(fmt.Sprintf("Unsupported workflow: %s", name)), nil
 	}
 
+	if callbackURL != "" {
+		if err := validateCallbackURL(ctx, callbackURL); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+
+	config, err := loadWorkflowConfig(configPath, configRaw)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:                       fmt.Sprintf("%s-%d", idPrefix, os.Getpid()),
+		TaskQueue:                utils.TaskQueue,
+		WorkflowExecutionTimeout: workflow.WorkflowExecutionTimeout(config),
+	}
+
+	if dryRun {
+		// Report what would run without touching Temporal
+		preview := map[string]interface{}{
+			"dry_run":       true,
+			"workflow_name": name,
+			"workflow_id":   workflowOptions.ID,
+			"task_queue":    workflowOptions.TaskQueue,
+			"schedule":      workflow.ExecutionLevels(workflow.ScheduledWorkspaces(config)),
+			"config":        config,
+		}
+		return jsonResult(request, preview)
+	}
+
+	we, err := c.ExecuteWorkflow(ctx, workflowOptions, workflowFn, config)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to start workflow: %v", err)), nil
+	}
+
+	resultText := fmt.Sprintf("Workflow started successfully.\nWorkflowID: %s\nRunID: %s", we.GetID(), we.GetRunID())
+	if callbackURL != "" {
+		callbacks.dispatch(we, callbackURL)
+		resultText += fmt.Sprintf("\nCallback: final status will be POSTed to %s", callbackURL)
+	}
+	return mcp.NewToolResultText(resultText), nil
+}
+
+// loadWorkflowConfig loads a config from a server-side path or an inline
+// payload, then validates and normalizes it. Errors are formatted for tool results.
+// maxWorkspaces is the operator's workspace limit, from MAX_WORKSPACES.
+// Zero means workflow.DefaultMaxWorkspaces.
+var maxWorkspaces int
+
+func loadWorkflowConfig(configPath string, configRaw map[string]any) (workflow.InfrastructureConfig, error) {
+	config, err := readWorkflowConfig(configPath, configRaw)
+	if err != nil {
+		return config, err
+	}
+
+	config, err = workflow.PrepareConfig(config)
+	if err != nil {
+		return config, fmt.Errorf("Invalid config: %v", err)
+	}
+	if err := workflow.CheckWorkspaceLimit(config, maxWorkspaces); err != nil {
+		return config, fmt.Errorf("Invalid config: %v", err)
+	}
+	return config, nil
+}
+
+// readWorkflowConfig decodes a config from a file or inline payload without
+// validating it.
+func readWorkflowConfig(configPath string, configRaw map[string]any) (workflow.InfrastructureConfig, error) {
 	var config workflow.InfrastructureConfig
 
 	switch {
@@ -157,33 +366,239 @@ func executeWorkflowHandler(ctx context.Context, c client.Client, request mcp.Ca
 		var err error
 		config, err = workflow.LoadConfigFromFile(configPath)
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Failed to load config: %v", err)), nil
+			return config, fmt.Errorf("Failed to load config: %v", err)
 		}
 	case configRaw != nil:
-		configBytes, _ := json.Marshal(configRaw)
-		if err := json.Unmarshal(configBytes, &config); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("Invalid config format: %v", err)), nil
+		configBytes, err := json.Marshal(configRaw)
+		if err != nil {
+			return config, fmt.Errorf("Invalid config format: %v", err)
+		}
+		config, err = workflow.ParseConfig(configBytes, ".json")
+		if err != nil {
+			return config, fmt.Errorf("Invalid config format: %v", err)
 		}
 	default:
-		return mcp.NewToolResultError("Provide config_path or config"), nil
+		return config, errors.New("Provide config_path or config")
 	}
+	return config, nil
+}
+
+// planPreviewResponse is the plan_preview result.
+type planPreviewResponse struct {
+	WorkflowID string `json:"workflow_id"`
+	RunID      string `json:"run_id"`
+
+	// Downgraded lists workspaces whose apply or destroy was dropped
+	Downgraded []string              `json:"downgraded,omitempty"`
+	Preview    *workflow.PlanPreview `json:"preview"`
+}
 
-	if err := workflow.ValidateInfrastructureConfig(config); err != nil {
+func planPreviewHandler(ctx context.Context, c client.Client, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	config, err := loadWorkflowConfig(mcp.ParseString(request, "config_path", ""), mcp.ParseStringMap(request, "config", nil))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	config, downgraded := workflow.StripMutatingOperations(config)
+	config.PlanPreview = &workflow.PlanPreviewConfig{IncludeText: mcp.ParseBoolean(request, "include_text", false)}
+	if config, err = workflow.PrepareConfig(config); err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Invalid config: %v", err)), nil
 	}
-	config = workflow.NormalizeInfrastructureConfig(config)
 
 	workflowOptions := client.StartWorkflowOptions{
-		ID:        fmt.Sprintf("%s-%d", utils.WorkflowID, os.Getpid()),
-		TaskQueue: utils.TaskQueue,
+		ID:                       runWorkflowID("preview"),
+		TaskQueue:                utils.TaskQueue,
+		WorkflowExecutionTimeout: workflow.WorkflowExecutionTimeout(config),
 	}
+	we, err := c.ExecuteWorkflow(ctx, workflowOptions, workflow.ParentWorkflow, config)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to start workflow: %v", err)), nil
+	}
+
+	var result workflow.OrchestrationResult
+	if err := we.Get(ctx, &result); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Plan preview workflow %s failed: %v", we.GetID(), err)), nil
+	}
+
+	return jsonResult(request, planPreviewResponse{
+		WorkflowID: we.GetID(),
+		RunID:      we.GetRunID(),
+		Downgraded: downgraded,
+		Preview:    result.Preview,
+	})
+}
+
+// runWorkflowID names a workflow the server starts and waits for itself.
+// Every call gets a new ID: previews and drift checks are commonly run
+// concurrently for the same config, and the process ID alone would collide.
+func runWorkflowID(kind string) string {
+	return fmt.Sprintf("%s-%s-%s", utils.WorkflowID, kind, uuid.NewString())
+}
+
+// detectDriftResponse is the detect_drift result.
+type detectDriftResponse struct {
+	WorkflowID string `json:"workflow_id"`
+	RunID      string `json:"run_id"`
+
+	// Downgraded lists workspaces whose refresh, apply or destroy was dropped
+	Downgraded        []string `json:"downgraded,omitempty"`
+	DriftedWorkspaces []string `json:"drifted_workspaces"`
 
+	// Workspaces maps each checked workspace to its drift result
+	Workspaces map[string]activities.DriftResult `json:"workspaces"`
+}
+
+func detectDriftHandler(ctx context.Context, c client.Client, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	config, err := loadWorkflowConfig(mcp.ParseString(request, "config_path", ""), mcp.ParseStringMap(request, "config", nil))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	config, downgraded := workflow.DriftCheckConfig(config)
+	if config, err = workflow.PrepareConfig(config); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid config: %v", err)), nil
+	}
+
+	workflowOptions := client.StartWorkflowOptions{
+		ID:                       runWorkflowID("drift"),
+		TaskQueue:                utils.TaskQueue,
+		WorkflowExecutionTimeout: workflow.WorkflowExecutionTimeout(config),
+	}
 	we, err := c.ExecuteWorkflow(ctx, workflowOptions, workflow.ParentWorkflow, config)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Failed to start workflow: %v", err)), nil
 	}
 
-	return mcp.NewToolResultText(fmt.Sprintf("Workflow started successfully.\nWorkflowID: %s\nRunID: %s", we.GetID(), we.GetRunID())), nil
+	var result workflow.OrchestrationResult
+	if err := we.Get(ctx, &result); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Drift check workflow %s failed: %v", we.GetID(), err)), nil
+	}
+
+	resp := detectDriftResponse{
+		WorkflowID:        we.GetID(),
+		RunID:             we.GetRunID(),
+		Downgraded:        downgraded,
+		DriftedWorkspaces: result.DriftedWorkspaces,
+		Workspaces:        make(map[string]activities.DriftResult, len(result.Details)),
+	}
+	if resp.DriftedWorkspaces == nil {
+		resp.DriftedWorkspaces = []string{}
+	}
+	// Workspaces skipped by their when condition have no result
+	for name, details := range result.Details {
+		if details.Drift != nil {
+			resp.Workspaces[name] = *details.Drift
+		}
+	}
+	return jsonResult(request, resp)
+}
+
+// batchWorkflowResult reports the outcome of one item in an execute_workflows batch.
+type batchWorkflowResult struct {
+	Index      int    `json:"index"`
+	WorkflowID string `json:"workflow_id,omitempty"`
+	RunID      string `json:"run_id,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func executeWorkflowsHandler(ctx context.Context, c client.Client, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	items, ok := request.GetArguments()["workflows"].([]interface{})
+	if !ok || len(items) == 0 {
+		return mcp.NewToolResultError("Provide a non-empty workflows array"), nil
+	}
+
+	// Each item is started independently; a bad config doesn't abort the batch
+	results := make([]batchWorkflowResult, 0, len(items))
+	for i, raw := range items {
+		result := batchWorkflowResult{Index: i}
+
+		item, ok := raw.(map[string]interface{})
+		if !ok {
+			result.Error = "Invalid workflow item: expected an object"
+			results = append(results, result)
+			continue
+		}
+
+		configPath, _ := item["config_path"].(string)
+		configRaw, _ := item["config"].(map[string]interface{})
+		config, err := loadWorkflowConfig(configPath, configRaw)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		suffix, _ := item["workflow_id_suffix"].(string)
+		if suffix == "" {
+			suffix = uuid.NewString()
+		}
+		workflowOptions := client.StartWorkflowOptions{
+			ID:                       fmt.Sprintf("%s-%s", utils.WorkflowID, suffix),
+			TaskQueue:                utils.TaskQueue,
+			WorkflowExecutionTimeout: workflow.WorkflowExecutionTimeout(config),
+		}
+
+		we, err := c.ExecuteWorkflow(ctx, workflowOptions, workflow.ParentWorkflow, config)
+		if err != nil {
+			result.Error = fmt.Sprintf("Failed to start workflow: %v", err)
+			results = append(results, result)
+			continue
+		}
+		result.WorkflowID = we.GetID()
+		result.RunID = we.GetRunID()
+		results = append(results, result)
+	}
+
+	return jsonResult(request, results)
+}
+
+func lintConfigHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	config, err := loadWorkflowConfig(mcp.ParseString(request, "config_path", ""), mcp.ParseStringMap(request, "config", nil))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	warnings := workflow.LintConfig(config)
+	if warnings == nil {
+		warnings = []workflow.LintWarning{}
+	}
+	return jsonResult(request, map[string]interface{}{"warnings": warnings})
+}
+
+// validateConfigResponse is the validate_config result. Depths and the
+// execution order are only reported for valid configs.
+type validateConfigResponse struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+
+	Depths map[string]int `json:"depths,omitempty"`
+	// ExecutionOrder lists workspaces so each comes after its dependencies
+	ExecutionOrder []string `json:"execution_order,omitempty"`
+	// Levels groups workspaces that can run in parallel
+	Levels [][]string `json:"levels,omitempty"`
+}
+
+func validateConfigHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	config, err := readWorkflowConfig(mcp.ParseString(request, "config_path", ""), mcp.ParseStringMap(request, "config", nil))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// An invalid config is a successful check, reported in the response
+	config, err = workflow.PrepareConfig(config)
+	if err != nil {
+		return jsonResult(request, validateConfigResponse{Error: err.Error()})
+	}
+
+	// Report the order ParentWorkflow will use, e.g. reversed for destroy
+	scheduled := workflow.ScheduledWorkspaces(config)
+	response := validateConfigResponse{
+		Valid:  true,
+		Depths: workflow.CalculateDepths(scheduled),
+		Levels: workflow.ExecutionLevels(scheduled),
+	}
+	for _, level := range response.Levels {
+		response.ExecutionOrder = append(response.ExecutionOrder, level...)
+	}
+	return jsonResult(request, response)
 }
 
 func getWorkflowStatusHandler(ctx context.Context, c client.Client, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -203,5 +618,109 @@ func getWorkflowStatusHandler(ctx context.Context, c client.Client, request mcp.
 		resultText += fmt.Sprintf("\nFinished At: %s", info.GetCloseTime().AsTime().Format("2006-01-02 15:04:05"))
 	}
 
+	// Orchestrations report live progress; other workflows have no such query
+	if info.GetStatus() == enums.WORKFLOW_EXECUTION_STATUS_RUNNING {
+		if progress, err := queryProgress(ctx, c, workflowID); err != nil {
+			log.Printf("Progress query for workflow %s failed: %v", workflowID, err)
+		} else {
+			resultText += formatProgress(progress)
+		}
+	}
+
+	if mcp.ParseBoolean(request, "include_outputs", false) {
+		switch info.GetStatus() {
+		case enums.WORKFLOW_EXECUTION_STATUS_COMPLETED, enums.WORKFLOW_EXECUTION_STATUS_FAILED:
+		default:
+			resultText += "\nOutputs: not available until the workflow completes"
+			return mcp.NewToolResultText(resultText), nil
+		}
+
+		label := "Outputs"
+		var result workflow.OrchestrationResult
+		if err := c.GetWorkflow(ctx, workflowID, "").Get(ctx, &result); err != nil {
+			partial, ok := workflow.PartialResult(err)
+			if !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("Failed to read outputs of workflow %s: %v", workflowID, err)), nil
+			}
+			// Report what succeeded alongside what failed
+			result = partial
+			resultText += fmt.Sprintf("\nError: %v", err)
+			for _, name := range sortedKeys(result.Failed) {
+				resultText += fmt.Sprintf("\nFailed: %s: %s", name, result.Failed[name])
+			}
+			for _, name := range sortedKeys(result.Skipped) {
+				resultText += fmt.Sprintf("\nSkipped: %s: %s", name, result.Skipped[name])
+			}
+			label = "Outputs of succeeded workspaces"
+		}
+
+		var payload interface{} = result.Outputs
+		if mcp.ParseBoolean(request, "flatten_outputs", false) {
+			payload = result.Flattened()
+		}
+		data, err := marshalJSON(request, payload)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Failed to encode outputs: %v", err)), nil
+		}
+		resultText += "\n" + label + ":\n" + string(data)
+	}
+
 	return mcp.NewToolResultText(resultText), nil
 }
+
+func queryProgress(ctx context.Context, c client.Client, workflowID string) (workflow.OrchestrationProgress, error) {
+	var progress workflow.OrchestrationProgress
+	value, err := c.QueryWorkflow(ctx, workflowID, "", workflow.QueryProgress)
+	if err != nil {
+		return progress, err
+	}
+	err = value.Get(&progress)
+	return progress, err
+}
+
+func formatProgress(p workflow.OrchestrationProgress) string {
+	text := fmt.Sprintf("\nProgress: %d/%d workspaces completed", len(p.Completed), p.Total)
+	lines := []struct {
+		label string
+		names []string
+	}{
+		{"Completed", p.Completed},
+		{"Running", p.Running},
+		{"Pending", p.Pending},
+		{"Failed", p.Failed},
+		{"Skipped", p.Skipped},
+	}
+	for _, line := range lines {
+		if len(line.names) > 0 {
+			text += fmt.Sprintf("\n  %s: %s", line.label, strings.Join(line.names, ", "))
+		}
+	}
+	return text
+}
+
+// marshalJSON encodes a tool response, indented for readability unless the
+// request sets compact, which saves tokens on large configs and results.
+func marshalJSON(request mcp.CallToolRequest, v interface{}) ([]byte, error) {
+	if mcp.ParseBoolean(request, "compact", false) {
+		return json.Marshal(v)
+	}
+	return json.MarshalIndent(v, "", "  ")
+}
+
+// jsonResult returns v as a tool's JSON text result.
+func jsonResult(request mcp.CallToolRequest, v interface{}) (*mcp.CallToolResult, error) {
+	res, err := marshalJSON(request, v)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to marshal response: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(res)), nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,1012 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fakoli/temporal-terraform-orchestrator/activities"
+	"github.com/fakoli/temporal-terraform-orchestrator/workflow"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/api/enums/v1"
+	workflowpb "go.temporal.io/api/workflow/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/mocks"
+	"go.temporal.io/sdk/temporal"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newToolRequest builds a CallToolRequest with the given arguments.
+func newToolRequest(args map[string]interface{}) mcp.CallToolRequest {
+	var request mcp.CallToolRequest
+	request.Params.Arguments = args
+	return request
+}
+
+// resultText returns the text content of a tool result.
+func resultText(t *testing.T, result *mcp.CallToolResult) string {
+	t.Helper()
+
+	require.NotNil(t, result)
+	require.NotEmpty(t, result.Content)
+	text, ok := result.Content[0].(mcp.TextContent)
+	require.True(t, ok, "expected text content")
+	return text.Text
+}
+
+func inlineConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"workspace_root": "/tmp/infra",
+		"workspaces": []interface{}{
+			map[string]interface{}{"name": "vpc", "dir": "vpc"},
+			map[string]interface{}{"name": "subnets", "dir": "subnets", "dependsOn": []interface{}{"vpc"}},
+		},
+	}
+}
+
+func TestExecuteWorkflowHandler_EnforcesWorkspaceLimit(t *testing.T) {
+	c := mocks.NewClient(t)
+	maxWorkspaces = 1
+	t.Cleanup(func() { maxWorkspaces = 0 })
+
+	result, err := executeWorkflowHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+		"workflow_name": "ParentWorkflow",
+		"config":        inlineConfig(),
+	}))
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	require.Contains(t, resultText(t, result), "config defines 2 workspaces, exceeding the limit of 1")
+
+	c.AssertNotCalled(t, "ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExecuteWorkflowHandler_DryRunDoesNotStartWorkflow(t *testing.T) {
+	c := mocks.NewClient(t)
+
+	result, err := executeWorkflowHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+		"workflow_name": "ParentWorkflow",
+		"config":        inlineConfig(),
+		"dry_run":       true,
+	}))
+	require.NoError(t, err)
+	require.False(t, result.IsError, resultText(t, result))
+
+	var preview struct {
+		DryRun   bool       `json:"dry_run"`
+		Schedule [][]string `json:"schedule"`
+		Config   struct {
+			Workspaces []struct {
+				Name       string   `json:"name"`
+				Dir        string   `json:"dir"`
+				Operations []string `json:"operations"`
+			} `json:"workspaces"`
+		} `json:"config"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, result)), &preview))
+	require.True(t, preview.DryRun)
+	require.Equal(t, [][]string{{"vpc"}, {"subnets"}}, preview.Schedule)
+	require.Equal(t, "/tmp/infra/vpc", preview.Config.Workspaces[0].Dir)
+	require.Equal(t, []string{"init", "validate", "plan", "apply"}, preview.Config.Workspaces[0].Operations)
+
+	c.AssertNotCalled(t, "ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestExecuteWorkflowHandler_DryRunRejectsInvalidConfig(t *testing.T) {
+	c := mocks.NewClient(t)
+
+	cfg := inlineConfig()
+	cfg["workspaces"] = []interface{}{
+		map[string]interface{}{"name": "a", "dir": "a", "dependsOn": []interface{}{"b"}},
+		map[string]interface{}{"name": "b", "dir": "b", "dependsOn": []interface{}{"a"}},
+	}
+
+	result, err := executeWorkflowHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+		"workflow_name": "ParentWorkflow",
+		"config":        cfg,
+		"dry_run":       true,
+	}))
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	require.Contains(t, resultText(t, result), "cycle")
+}
+
+func TestExecuteWorkflowHandler_StartsWorkflow(t *testing.T) {
+	c := mocks.NewClient(t)
+	run := mocks.NewWorkflowRun(t)
+	run.On("GetID").Return("terraform-parent-workflow-1")
+	run.On("GetRunID").Return("run-1")
+	c.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(run, nil).Once()
+
+	result, err := executeWorkflowHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+		"workflow_name": "ParentWorkflow",
+		"config":        inlineConfig(),
+	}))
+	require.NoError(t, err)
+	require.False(t, result.IsError, resultText(t, result))
+	require.Contains(t, resultText(t, result), "RunID: run-1")
+}
+
+func TestPlanPreviewHandler_RunsPlanOnlyAndReturnsPreview(t *testing.T) {
+	c := mocks.NewClient(t)
+	run := mocks.NewWorkflowRun(t)
+	run.On("GetID").Return("terraform-parent-workflow-preview-1")
+	run.On("GetRunID").Return("run-1")
+	run.On("Get", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(1).(*workflow.OrchestrationResult) = workflow.OrchestrationResult{
+			Preview: &workflow.PlanPreview{
+				Workspaces: []workflow.WorkspacePlanPreview{
+					{Name: "vpc", Changes: true, Add: 2},
+					{Name: "subnets"},
+				},
+				Add: 2,
+			},
+		}
+	}).Return(nil)
+	c.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.MatchedBy(func(cfg workflow.InfrastructureConfig) bool {
+		for _, ws := range cfg.Workspaces {
+			if slices.Contains(ws.Operations, "apply") {
+				return false
+			}
+		}
+		return cfg.PlanPreview != nil && cfg.PlanPreview.IncludeText
+	})).Return(run, nil).Once()
+
+	cfg := inlineConfig()
+	cfg["workspaces"].([]interface{})[0].(map[string]interface{})["operations"] = []interface{}{"init", "validate", "plan", "apply"}
+
+	result, err := planPreviewHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+		"config":       cfg,
+		"include_text": true,
+	}))
+	require.NoError(t, err)
+	require.False(t, result.IsError, resultText(t, result))
+
+	var resp planPreviewResponse
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, result)), &resp))
+	require.Equal(t, "run-1", resp.RunID)
+	require.Equal(t, []string{"vpc", "subnets"}, resp.Downgraded)
+	require.Equal(t, 2, resp.Preview.Add)
+	require.Len(t, resp.Preview.Workspaces, 2)
+}
+
+func TestPlanPreviewAndDetectDrift_UseUniqueWorkflowIDs(t *testing.T) {
+	c := mocks.NewClient(t)
+	run := mocks.NewWorkflowRun(t)
+	run.On("GetID").Return("id")
+	run.On("GetRunID").Return("run-1")
+	run.On("Get", mock.Anything, mock.Anything).Return(nil)
+
+	var ids []string
+	c.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		ids = append(ids, args.Get(1).(client.StartWorkflowOptions).ID)
+	}).Return(run, nil).Times(4)
+
+	// Concurrent previews and drift checks from one server must not collide
+	for i := 0; i < 2; i++ {
+		for _, handler := range []func(context.Context, client.Client, mcp.CallToolRequest) (*mcp.CallToolResult, error){planPreviewHandler, detectDriftHandler} {
+			result, err := handler(context.Background(), c, newToolRequest(map[string]interface{}{"config": inlineConfig()}))
+			require.NoError(t, err)
+			require.False(t, result.IsError, resultText(t, result))
+		}
+	}
+
+	require.Len(t, ids, 4)
+	require.True(t, strings.HasPrefix(ids[0], "terraform-parent-workflow-preview-"), ids[0])
+	require.True(t, strings.HasPrefix(ids[1], "terraform-parent-workflow-drift-"), ids[1])
+	slices.Sort(ids)
+	require.Len(t, slices.Compact(ids), 4)
+}
+
+func TestDetectDriftHandler_RunsDriftCheckAndReturnsDrift(t *testing.T) {
+	c := mocks.NewClient(t)
+	run := mocks.NewWorkflowRun(t)
+	run.On("GetID").Return("terraform-parent-workflow-drift-1")
+	run.On("GetRunID").Return("run-1")
+	run.On("Get", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(1).(*workflow.OrchestrationResult) = workflow.OrchestrationResult{
+			Outputs: map[string]map[string]interface{}{"vpc": {}, "subnets": {}},
+			Details: map[string]activities.WorkspaceDetails{
+				"vpc":     {Drift: &activities.DriftResult{Drifted: true, Changed: 1}},
+				"subnets": {Drift: &activities.DriftResult{}},
+			},
+			DriftedWorkspaces: []string{"vpc"},
+		}
+	}).Return(nil)
+	c.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.MatchedBy(func(cfg workflow.InfrastructureConfig) bool {
+		for _, ws := range cfg.Workspaces {
+			if !ws.DriftCheck || slices.Contains(ws.Operations, "apply") {
+				return false
+			}
+		}
+		return true
+	})).Return(run, nil).Once()
+
+	result, err := detectDriftHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+		"config": inlineConfig(),
+	}))
+	require.NoError(t, err)
+	require.False(t, result.IsError, resultText(t, result))
+
+	var resp detectDriftResponse
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, result)), &resp))
+	require.Equal(t, "run-1", resp.RunID)
+	require.Equal(t, []string{"vpc", "subnets"}, resp.Downgraded)
+	require.Equal(t, []string{"vpc"}, resp.DriftedWorkspaces)
+	require.Equal(t, map[string]activities.DriftResult{
+		"vpc":     {Drifted: true, Changed: 1},
+		"subnets": {},
+	}, resp.Workspaces)
+}
+
+func TestExecuteWorkflowsHandler_MixedBatch(t *testing.T) {
+	c := mocks.NewClient(t)
+
+	prodRun := mocks.NewWorkflowRun(t)
+	prodRun.On("GetID").Return("terraform-parent-workflow-prod")
+	prodRun.On("GetRunID").Return("run-prod")
+	devRun := mocks.NewWorkflowRun(t)
+	devRun.On("GetID").Return("terraform-parent-workflow-dev")
+	devRun.On("GetRunID").Return("run-dev")
+
+	c.On("ExecuteWorkflow", mock.Anything, mock.MatchedBy(func(o client.StartWorkflowOptions) bool {
+		return o.ID == "terraform-parent-workflow-prod"
+	}), mock.Anything, mock.Anything).Return(prodRun, nil).Once()
+	c.On("ExecuteWorkflow", mock.Anything, mock.MatchedBy(func(o client.StartWorkflowOptions) bool {
+		return o.ID == "terraform-parent-workflow-dev"
+	}), mock.Anything, mock.Anything).Return(devRun, nil).Once()
+
+	invalid := inlineConfig()
+	invalid["workspaces"] = []interface{}{
+		map[string]interface{}{"name": "a", "dir": "a", "dependsOn": []interface{}{"missing"}},
+	}
+
+	result, err := executeWorkflowsHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+		"workflows": []interface{}{
+			map[string]interface{}{"config": inlineConfig(), "workflow_id_suffix": "prod"},
+			map[string]interface{}{"config": invalid, "workflow_id_suffix": "broken"},
+			map[string]interface{}{"config": inlineConfig(), "workflow_id_suffix": "dev"},
+			map[string]interface{}{"workflow_id_suffix": "empty"},
+		},
+	}))
+	require.NoError(t, err)
+	require.False(t, result.IsError, resultText(t, result))
+
+	var items []batchWorkflowResult
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, result)), &items))
+	require.Len(t, items, 4)
+
+	require.Equal(t, "terraform-parent-workflow-prod", items[0].WorkflowID)
+	require.Equal(t, "run-prod", items[0].RunID)
+	require.Empty(t, items[0].Error)
+
+	require.Contains(t, items[1].Error, "unknown workspace missing")
+	require.Empty(t, items[1].WorkflowID)
+
+	require.Equal(t, "terraform-parent-workflow-dev", items[2].WorkflowID)
+	require.Equal(t, "run-dev", items[2].RunID)
+
+	require.Equal(t, "Provide config_path or config", items[3].Error)
+}
+
+func TestExecuteWorkflowsHandler_DefaultSuffixesAreUnique(t *testing.T) {
+	c := mocks.NewClient(t)
+	run := mocks.NewWorkflowRun(t)
+	run.On("GetID").Return("id")
+	run.On("GetRunID").Return("run-1")
+
+	var ids []string
+	c.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		ids = append(ids, args.Get(1).(client.StartWorkflowOptions).ID)
+	}).Return(run, nil).Times(4)
+
+	// Two batches from the same server must not reuse workflow IDs
+	for i := 0; i < 2; i++ {
+		result, err := executeWorkflowsHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+			"workflows": []interface{}{
+				map[string]interface{}{"config": inlineConfig()},
+				map[string]interface{}{"config": inlineConfig()},
+			},
+		}))
+		require.NoError(t, err)
+		require.False(t, result.IsError, resultText(t, result))
+	}
+
+	require.Len(t, ids, 4)
+	slices.Sort(ids)
+	require.Len(t, slices.Compact(ids), 4)
+}
+
+func TestExecuteWorkflowsHandler_RequiresItems(t *testing.T) {
+	c := mocks.NewClient(t)
+
+	result, err := executeWorkflowsHandler(context.Background(), c, newToolRequest(map[string]interface{}{}))
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+}
+
+func describeStatus(status enums.WorkflowExecutionStatus) *workflowservice.DescribeWorkflowExecutionResponse {
+	return &workflowservice.DescribeWorkflowExecutionResponse{
+		WorkflowExecutionInfo: &workflowpb.WorkflowExecutionInfo{
+			Status:    status,
+			StartTime: timestamppb.Now(),
+		},
+	}
+}
+
+func TestGetWorkflowStatusHandler_IncludesOutputs(t *testing.T) {
+	c := mocks.NewClient(t)
+	c.On("DescribeWorkflowExecution", mock.Anything, "wf-1", "").
+		Return(describeStatus(enums.WORKFLOW_EXECUTION_STATUS_COMPLETED), nil)
+
+	run := mocks.NewWorkflowRun(t)
+	run.On("Get", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(1).(*workflow.OrchestrationResult) = workflow.OrchestrationResult{
+			Outputs: map[string]map[string]interface{}{
+				"a.b": {"c": "first"},
+				"a":   {"b.c": "second", "region": "us-west-2"},
+			},
+		}
+	}).Return(nil)
+	c.On("GetWorkflow", mock.Anything, "wf-1", "").Return(run)
+
+	result, err := getWorkflowStatusHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+		"workflow_id":     "wf-1",
+		"include_outputs": true,
+		"flatten_outputs": true,
+	}))
+	require.NoError(t, err)
+	require.False(t, result.IsError, resultText(t, result))
+
+	text := resultText(t, result)
+	require.Contains(t, text, "Status: Completed")
+	require.Contains(t, text, `"a.region": "us-west-2"`)
+	require.Contains(t, text, `"a.b.c (from a/b.c, a.b/c)"`)
+}
+
+func TestGetWorkflowStatusHandler_OutputsPendingWhileRunning(t *testing.T) {
+	c := mocks.NewClient(t)
+	c.On("DescribeWorkflowExecution", mock.Anything, "wf-1", "").
+		Return(describeStatus(enums.WORKFLOW_EXECUTION_STATUS_RUNNING), nil)
+	c.On("QueryWorkflow", mock.Anything, "wf-1", "", workflow.QueryProgress).
+		Return(nil, errors.New("unknown queryType progress"))
+
+	result, err := getWorkflowStatusHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+		"workflow_id":     "wf-1",
+		"include_outputs": true,
+	}))
+	require.NoError(t, err)
+	require.Contains(t, resultText(t, result), "Outputs: not available until the workflow completes")
+}
+
+func TestGetWorkflowStatusHandler_IncludesProgressWhileRunning(t *testing.T) {
+	c := mocks.NewClient(t)
+	c.On("DescribeWorkflowExecution", mock.Anything, "wf-1", "").
+		Return(describeStatus(enums.WORKFLOW_EXECUTION_STATUS_RUNNING), nil)
+
+	value := mocks.NewEncodedValue(t)
+	value.On("Get", mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(0).(*workflow.OrchestrationProgress) = workflow.OrchestrationProgress{
+			Total:     3,
+			Completed: []string{"vpc"},
+			Running:   []string{"subnets"},
+			Pending:   []string{"eks"},
+		}
+	}).Return(nil)
+	c.On("QueryWorkflow", mock.Anything, "wf-1", "", workflow.QueryProgress).Return(value, nil)
+
+	result, err := getWorkflowStatusHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+		"workflow_id": "wf-1",
+	}))
+	require.NoError(t, err)
+
+	text := resultText(t, result)
+	require.Contains(t, text, "Progress: 1/3 workspaces completed")
+	require.Contains(t, text, "Running: subnets")
+	require.Contains(t, text, "Pending: eks")
+	require.NotContains(t, text, "Failed:")
+}
+
+func TestGetWorkflowStatusHandler_ReportsPartialResultOnFailure(t *testing.T) {
+	c := mocks.NewClient(t)
+	c.On("DescribeWorkflowExecution", mock.Anything, "wf-1", "").
+		Return(describeStatus(enums.WORKFLOW_EXECUTION_STATUS_FAILED), nil)
+
+	partial := workflow.OrchestrationResult{
+		Outputs: map[string]map[string]interface{}{"vpc": {"vpc_id": "vpc-123"}},
+		Failed:  map[string]string{"subnets": "plan failed"},
+		Skipped: map[string]string{"eks": "dependency subnets failed"},
+	}
+	run := mocks.NewWorkflowRun(t)
+	run.On("Get", mock.Anything, mock.Anything).
+		Return(temporal.NewApplicationError("workspace subnets failed: plan failed", workflow.OrchestrationFailedErrorType, partial))
+	c.On("GetWorkflow", mock.Anything, "wf-1", "").Return(run)
+
+	result, err := getWorkflowStatusHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+		"workflow_id":     "wf-1",
+		"include_outputs": true,
+	}))
+	require.NoError(t, err)
+	require.False(t, result.IsError, resultText(t, result))
+
+	text := resultText(t, result)
+	require.Contains(t, text, "Failed: subnets: plan failed")
+	require.Contains(t, text, "Skipped: eks: dependency subnets failed")
+	require.Contains(t, text, "Outputs of succeeded workspaces:")
+	require.Contains(t, text, `"vpc_id": "vpc-123"`)
+}
+
+func TestExecuteWorkflowHandler_StartsValidateOnlyWorkflow(t *testing.T) {
+	c := mocks.NewClient(t)
+	run := mocks.NewWorkflowRun(t)
+	run.On("GetID").Return("terraform-parent-workflow-validate-1")
+	run.On("GetRunID").Return("run-1")
+	c.On("ExecuteWorkflow", mock.Anything, mock.MatchedBy(func(o client.StartWorkflowOptions) bool {
+		return strings.HasPrefix(o.ID, "terraform-parent-workflow-validate-")
+	}), mock.Anything, mock.Anything).Return(run, nil).Once()
+
+	result, err := executeWorkflowHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+		"workflow_name": "ValidateOnlyWorkflow",
+		"config":        inlineConfig(),
+	}))
+	require.NoError(t, err)
+	require.False(t, result.IsError, resultText(t, result))
+	require.Contains(t, resultText(t, result), "WorkflowID: terraform-parent-workflow-validate-1")
+}
+
+func TestExecuteWorkflowHandler_RejectsUnknownWorkflow(t *testing.T) {
+	c := mocks.NewClient(t)
+
+	result, err := executeWorkflowHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+		"workflow_name": "DestroyEverything",
+		"config":        inlineConfig(),
+	}))
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	require.Contains(t, resultText(t, result), "Unsupported workflow: DestroyEverything")
+}
+
+// callbackReceiver records callback payloads POSTed to an httptest server,
+// allowing callbacks to it.
+func callbackReceiver(t *testing.T) (*httptest.Server, chan callbackPayload) {
+	t.Helper()
+
+	callbackAllowedHosts = []string{"127.0.0.1"}
+	t.Cleanup(func() { callbackAllowedHosts = nil })
+
+	received := make(chan callbackPayload, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload callbackPayload
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		received <- payload
+	}))
+	t.Cleanup(srv.Close)
+	return srv, received
+}
+
+func TestExecuteWorkflowHandler_CallbackPostsResult(t *testing.T) {
+	srv, received := callbackReceiver(t)
+
+	c := mocks.NewClient(t)
+	run := mocks.NewWorkflowRun(t)
+	run.On("GetID").Return("terraform-parent-workflow-1")
+	run.On("GetRunID").Return("run-1")
+	run.On("Get", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(1).(*json.RawMessage) = json.RawMessage(`{"outputs":{"vpc":{"vpc_id":"vpc-123"}}}`)
+	}).Return(nil)
+	c.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(run, nil).Once()
+
+	result, err := executeWorkflowHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+		"workflow_name": "ParentWorkflow",
+		"config":        inlineConfig(),
+		"callback_url":  srv.URL,
+	}))
+	require.NoError(t, err)
+	require.False(t, result.IsError, resultText(t, result))
+	require.Contains(t, resultText(t, result), "Callback: final status will be POSTed to "+srv.URL)
+
+	callbacks.wait()
+	payload := <-received
+	require.Equal(t, "terraform-parent-workflow-1", payload.WorkflowID)
+	require.Equal(t, "run-1", payload.RunID)
+	require.Equal(t, "Completed", payload.Status)
+	require.JSONEq(t, `{"outputs":{"vpc":{"vpc_id":"vpc-123"}}}`, string(payload.Result))
+}
+
+func TestExecuteWorkflowHandler_CallbackReportsFailure(t *testing.T) {
+	srv, received := callbackReceiver(t)
+
+	c := mocks.NewClient(t)
+	run := mocks.NewWorkflowRun(t)
+	run.On("GetID").Return("terraform-parent-workflow-1")
+	run.On("GetRunID").Return("run-1")
+	run.On("Get", mock.Anything, mock.Anything).Return(errors.New("workspace vpc failed: plan failed"))
+	c.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(run, nil).Once()
+
+	result, err := executeWorkflowHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+		"workflow_name": "ParentWorkflow",
+		"config":        inlineConfig(),
+		"callback_url":  srv.URL,
+	}))
+	require.NoError(t, err)
+	require.False(t, result.IsError, resultText(t, result))
+
+	callbacks.wait()
+	payload := <-received
+	require.Equal(t, "Failed", payload.Status)
+	require.Contains(t, payload.Error, "plan failed")
+	require.Empty(t, payload.Result)
+}
+
+func TestExecuteWorkflowHandler_CallbackIncludesPartialResult(t *testing.T) {
+	srv, received := callbackReceiver(t)
+
+	partial := workflow.OrchestrationResult{
+		Outputs: map[string]map[string]interface{}{"vpc": {"vpc_id": "vpc-123"}},
+		Failed:  map[string]string{"subnets": "plan failed"},
+	}
+	c := mocks.NewClient(t)
+	run := mocks.NewWorkflowRun(t)
+	run.On("GetID").Return("terraform-parent-workflow-1")
+	run.On("GetRunID").Return("run-1")
+	run.On("Get", mock.Anything, mock.Anything).
+		Return(temporal.NewApplicationError("workspace subnets failed: plan failed", workflow.OrchestrationFailedErrorType, partial))
+	c.On("ExecuteWorkflow", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(run, nil).Once()
+
+	_, err := executeWorkflowHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+		"workflow_name": "ParentWorkflow",
+		"config":        inlineConfig(),
+		"callback_url":  srv.URL,
+	}))
+	require.NoError(t, err)
+
+	callbacks.wait()
+	payload := <-received
+	require.Equal(t, "Failed", payload.Status)
+	require.JSONEq(t, `{"outputs":{"vpc":{"vpc_id":"vpc-123"}},"failed":{"subnets":"plan failed"}}`, string(payload.Result))
+}
+
+func TestExecuteWorkflowHandler_RejectsInvalidCallbackURL(t *testing.T) {
+	c := mocks.NewClient(t)
+
+	result, err := executeWorkflowHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+		"workflow_name": "ParentWorkflow",
+		"config":        inlineConfig(),
+		"callback_url":  "ftp://example.com/hook",
+	}))
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	require.Contains(t, resultText(t, result), "invalid callback_url")
+}
+
+func TestValidateCallbackURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		url     string
+		errMsg  string
+	}{
+		{name: "public address", url: "https://203.0.113.10/hook"},
+		{name: "not http", url: "ftp://203.0.113.10/hook", errMsg: "expected an absolute http(s) URL"},
+		{name: "loopback", url: "http://127.0.0.1:8080/hook", errMsg: "not allowed"},
+		{name: "loopback v6", url: "http://[::1]/hook", errMsg: "not allowed"},
+		{name: "link-local metadata", url: "http://169.254.169.254/latest/meta-data", errMsg: "not allowed"},
+		{name: "private", url: "http://10.0.0.5/hook", errMsg: "not allowed"},
+		{name: "unspecified", url: "http://0.0.0.0/hook", errMsg: "not allowed"},
+		{name: "allowed host", allowed: []string{"hooks.internal"}, url: "http://HOOKS.internal:9000/done"},
+		{name: "allowed private host", allowed: []string{"10.0.0.5"}, url: "http://10.0.0.5/hook"},
+		{name: "host not in allowlist", allowed: []string{"hooks.internal"}, url: "https://203.0.113.10/hook", errMsg: "not in CALLBACK_ALLOWED_HOSTS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callbackAllowedHosts = tt.allowed
+			t.Cleanup(func() { callbackAllowedHosts = nil })
+
+			err := validateCallbackURL(context.Background(), tt.url)
+			if tt.errMsg == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid callback_url")
+			require.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestCallbackClient_RefusesInternalAddresses(t *testing.T) {
+	posted := false
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted = true
+	}))
+	defer internal.Close()
+
+	// Addresses are checked again at dial time, for URLs that get past
+	// validation through DNS or a redirect
+	d := newCallbackDispatcher(newCallbackClient(), 0)
+	err := d.post(context.Background(), internal.URL, callbackPayload{WorkflowID: "wf-1", Status: "Completed"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not allowed")
+	require.False(t, posted)
+}
+
+func TestCallbackDispatcher_RetriesFailedDelivery(t *testing.T) {
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	d := newCallbackDispatcher(srv.Client(), 0)
+	require.NoError(t, d.post(context.Background(), srv.URL, callbackPayload{WorkflowID: "wf-1", Status: "Completed"}))
+	require.Equal(t, 2, attempts)
+}
+
+func TestCallbackDispatcher_ShutdownDropsPendingCallbacks(t *testing.T) {
+	srv, received := callbackReceiver(t)
+
+	// The workflow never closes, so Get only returns once shutdown cancels it
+	run := mocks.NewWorkflowRun(t)
+	run.On("GetID").Return("terraform-parent-workflow-1")
+	run.On("GetRunID").Return("run-1")
+	run.On("Get", mock.Anything, mock.Anything).Return(func(ctx context.Context, _ interface{}) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	d := newCallbackDispatcher(srv.Client(), 0)
+	d.dispatch(run, srv.URL)
+
+	start := time.Now()
+	d.shutdown(50 * time.Millisecond)
+	require.Less(t, time.Since(start), 5*time.Second)
+	require.Empty(t, received, "an undelivered callback must not be POSTed")
+}
+
+// listWorkflows runs list_workflows for configPath and decodes the JSON result.
+func listWorkflows(t *testing.T, configPath string) map[string]interface{} {
+	t.Helper()
+
+	result, err := listWorkflowsHandler(context.Background(), newToolRequest(map[string]interface{}{
+		"config_path": configPath,
+	}))
+	require.NoError(t, err)
+	require.False(t, result.IsError, resultText(t, result))
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, result)), &response))
+	return response
+}
+
+func keysOf(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestListWorkflowsHandler_ConsistentShape(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "infra.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+workspace_root: /tmp/infra
+workspaces:
+  - name: vpc
+    dir: vpc
+  - name: subnets
+    dir: subnets
+    dependsOn: [vpc]
+`), 0o644))
+
+	present := listWorkflows(t, configPath)
+	absent := listWorkflows(t, filepath.Join(dir, "missing.yaml"))
+
+	require.Equal(t, "ok", present["status"])
+	require.Equal(t, "no_config", absent["status"])
+	require.NotContains(t, absent, "error")
+	require.Contains(t, absent["message"], "Config file not found")
+	require.Contains(t, absent, "input_schema")
+
+	// Both responses share the required fields and per-workflow shape
+	for _, response := range []map[string]interface{}{present, absent} {
+		require.Contains(t, response, "config_path")
+		workflows, ok := response["workflows"].([]interface{})
+		require.True(t, ok)
+		require.Len(t, workflows, 2)
+		for _, wf := range workflows {
+			require.Equal(t, []string{"configured_workspaces", "description", "name", "workspace_count"}, keysOf(wf.(map[string]interface{})))
+		}
+	}
+
+	presentParent := present["workflows"].([]interface{})[0].(map[string]interface{})
+	require.Equal(t, float64(2), presentParent["workspace_count"])
+	require.Len(t, presentParent["configured_workspaces"], 2)
+
+	absentParent := absent["workflows"].([]interface{})[0].(map[string]interface{})
+	require.Equal(t, float64(0), absentParent["workspace_count"])
+	require.Equal(t, []interface{}{}, absentParent["configured_workspaces"])
+}
+
+func TestListWorkflowsHandler_ReportsEffectiveOperations(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "infra.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+workspace_root: /tmp/infra
+workspaces:
+  - name: vpc
+    dir: vpc
+  - name: subnets
+    dir: subnets
+    dependsOn: [vpc]
+    operations: [init, validate, plan]
+  - name: drift
+    dir: drift
+    detectOnly: true
+`), 0o644))
+
+	response := listWorkflows(t, configPath)
+	parent := response["workflows"].([]interface{})[0].(map[string]interface{})
+	workspaces := parent["configured_workspaces"].([]interface{})
+	require.Len(t, workspaces, 3)
+
+	operations := make(map[string]interface{})
+	for _, ws := range workspaces {
+		entry := ws.(map[string]interface{})
+		require.Equal(t, "terraform", entry["kind"])
+		operations[entry["name"].(string)] = entry["operations"]
+	}
+	require.Equal(t, []interface{}{"init", "validate", "plan", "apply"}, operations["vpc"])
+	require.Equal(t, []interface{}{"init", "validate", "plan"}, operations["subnets"])
+	require.Equal(t, []interface{}{"init", "validate", "plan"}, operations["drift"])
+}
+
+func TestListWorkflowsHandler_InvalidConfigIsAnError(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "infra.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte("workspaces: [}"), 0o644))
+
+	result, err := listWorkflowsHandler(context.Background(), newToolRequest(map[string]interface{}{
+		"config_path": configPath,
+	}))
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	require.Contains(t, resultText(t, result), "Failed to load config")
+}
+
+func TestLoadWorkflowConfig_InlineMatchesFile(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "infra.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+workspace_root: /tmp/infra
+workspaces:
+  - name: vpc
+    dir: vpc
+  - name: subnets
+    dir: subnets
+    dependsOn: [vpc]
+    operations: [init, validate, plan]
+`), 0o644))
+
+	fromFile, err := loadWorkflowConfig(configPath, nil)
+	require.NoError(t, err)
+
+	inline := map[string]interface{}{
+		"workspace_root": "/tmp/infra",
+		"workspaces": []interface{}{
+			map[string]interface{}{"name": "vpc", "dir": "vpc"},
+			map[string]interface{}{"name": "subnets", "dir": "subnets", "dependsOn": []interface{}{"vpc"}, "operations": []interface{}{"init", "validate", "plan"}},
+		},
+	}
+	fromInline, err := loadWorkflowConfig("", inline)
+	require.NoError(t, err)
+
+	require.Equal(t, fromFile, fromInline)
+
+	// Missing operations get the defaults; kind and dirs are normalized
+	require.Equal(t, []string{"init", "validate", "plan", "apply"}, fromInline.Workspaces[0].Operations)
+	require.Equal(t, "terraform", fromInline.Workspaces[0].Kind)
+	require.Equal(t, "/tmp/infra/vpc", fromInline.Workspaces[0].Dir)
+}
+
+func TestLoadWorkflowConfig_InlineRunsValidation(t *testing.T) {
+	_, err := loadWorkflowConfig("", map[string]interface{}{
+		"workspaces": []interface{}{
+			map[string]interface{}{"name": "vpc", "dir": "vpc", "operations": []interface{}{"apply"}},
+		},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Invalid config")
+
+	_, err = loadWorkflowConfig("", map[string]interface{}{"workspaces": "not-a-list"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Invalid config format")
+}
+
+func TestLintConfigHandler_ReturnsWarnings(t *testing.T) {
+	result, err := lintConfigHandler(context.Background(), newToolRequest(map[string]interface{}{
+		"config": inlineConfig(),
+	}))
+	require.NoError(t, err)
+	require.False(t, result.IsError, resultText(t, result))
+
+	var response struct {
+		Warnings []workflow.LintWarning `json:"warnings"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, result)), &response))
+
+	// Neither workspace has variables, and subnets never maps vpc's outputs
+	rules := make([]string, 0, len(response.Warnings))
+	for _, w := range response.Warnings {
+		rules = append(rules, w.Rule)
+	}
+	require.Equal(t, []string{workflow.LintNoVariables, workflow.LintNoVariables, workflow.LintUnconsumedOutputs}, rules)
+}
+
+func TestLintConfigHandler_CompactOutput(t *testing.T) {
+	indented, err := lintConfigHandler(context.Background(), newToolRequest(map[string]interface{}{
+		"config": inlineConfig(),
+	}))
+	require.NoError(t, err)
+	compact, err := lintConfigHandler(context.Background(), newToolRequest(map[string]interface{}{
+		"config":  inlineConfig(),
+		"compact": true,
+	}))
+	require.NoError(t, err)
+
+	indentedText, compactText := resultText(t, indented), resultText(t, compact)
+	require.Contains(t, indentedText, "\n  ")
+	require.NotContains(t, compactText, "\n")
+	require.Less(t, len(compactText), len(indentedText))
+
+	// Same data either way
+	var fromIndented, fromCompact interface{}
+	require.NoError(t, json.Unmarshal([]byte(indentedText), &fromIndented))
+	require.NoError(t, json.Unmarshal([]byte(compactText), &fromCompact))
+	require.Equal(t, fromIndented, fromCompact)
+}
+
+func TestValidateConfigHandler_ReportsDepthsAndOrder(t *testing.T) {
+	config := inlineConfig()
+	config["workspaces"] = append(config["workspaces"].([]interface{}),
+		map[string]interface{}{"name": "sg", "dir": "sg", "dependsOn": []interface{}{"vpc"}},
+		map[string]interface{}{"name": "app", "dir": "app", "dependsOn": []interface{}{"subnets", "sg"}},
+	)
+	result, err := validateConfigHandler(context.Background(), newToolRequest(map[string]interface{}{
+		"config": config,
+	}))
+	require.NoError(t, err)
+	require.False(t, result.IsError, resultText(t, result))
+
+	var response validateConfigResponse
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, result)), &response))
+	require.True(t, response.Valid)
+	require.Empty(t, response.Error)
+	require.Equal(t, map[string]int{"vpc": 0, "subnets": 1, "sg": 1, "app": 2}, response.Depths)
+	require.Equal(t, []string{"vpc", "subnets", "sg", "app"}, response.ExecutionOrder)
+	require.Equal(t, [][]string{{"vpc"}, {"subnets", "sg"}, {"app"}}, response.Levels)
+}
+
+func TestValidateConfigHandler_ReportsScheduledOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   string
+		depths map[string]int
+		levels [][]string
+	}{
+		{name: "destroy runs dependents first", mode: "destroy", depths: map[string]int{"subnets": 0, "vpc": 1}, levels: [][]string{{"subnets"}, {"vpc"}}},
+		{name: "speculative plan runs everything at once", mode: "speculativePlan", depths: map[string]int{"vpc": 0, "subnets": 0}, levels: [][]string{{"vpc", "subnets"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := inlineConfig()
+			config[tt.mode] = true
+			result, err := validateConfigHandler(context.Background(), newToolRequest(map[string]interface{}{
+				"config": config,
+			}))
+			require.NoError(t, err)
+
+			var response validateConfigResponse
+			require.NoError(t, json.Unmarshal([]byte(resultText(t, result)), &response))
+			require.True(t, response.Valid, response.Error)
+			require.Equal(t, tt.depths, response.Depths)
+			require.Equal(t, tt.levels, response.Levels)
+		})
+	}
+}
+
+func TestValidateConfigHandler_ReportsValidationErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		workspaces []interface{}
+		wantErr    string
+	}{
+		{
+			name: "cycle",
+			workspaces: []interface{}{
+				map[string]interface{}{"name": "a", "dir": "a", "dependsOn": []interface{}{"b"}},
+				map[string]interface{}{"name": "b", "dir": "b", "dependsOn": []interface{}{"a"}},
+			},
+			wantErr: "dependency cycle detected at workspace a",
+		},
+		{
+			name: "unknown dependency",
+			workspaces: []interface{}{
+				map[string]interface{}{"name": "a", "dir": "a", "dependsOn": []interface{}{"missing"}},
+			},
+			wantErr: "workspace a depends on unknown workspace missing",
+		},
+		{
+			name: "bad operation",
+			workspaces: []interface{}{
+				map[string]interface{}{"name": "a", "dir": "a", "operations": []interface{}{"deploy"}},
+			},
+			wantErr: "unknown operation 'deploy'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := validateConfigHandler(context.Background(), newToolRequest(map[string]interface{}{
+				"config": map[string]interface{}{"workspaces": tt.workspaces},
+			}))
+			require.NoError(t, err)
+			require.False(t, result.IsError, resultText(t, result))
+
+			var response validateConfigResponse
+			require.NoError(t, json.Unmarshal([]byte(resultText(t, result)), &response))
+			require.False(t, response.Valid)
+			require.Contains(t, response.Error, tt.wantErr)
+			require.Empty(t, response.Depths)
+			require.Empty(t, response.ExecutionOrder)
+		})
+	}
+}
+
+func TestValidateConfigHandler_MissingConfig(t *testing.T) {
+	result, err := validateConfigHandler(context.Background(), newToolRequest(map[string]interface{}{}))
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	require.Contains(t, resultText(t, result), "Provide config_path or config")
+}
+
+func TestGetWorkflowStatusHandler_CompactOutputs(t *testing.T) {
+	c := mocks.NewClient(t)
+	c.On("DescribeWorkflowExecution", mock.Anything, "wf-1", "").
+		Return(describeStatus(enums.WORKFLOW_EXECUTION_STATUS_COMPLETED), nil)
+	run := mocks.NewWorkflowRun(t)
+	run.On("Get", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(1).(*workflow.OrchestrationResult) = workflow.OrchestrationResult{
+			Outputs: map[string]map[string]interface{}{"vpc": {"id": "vpc-1"}},
+		}
+	}).Return(nil)
+	c.On("GetWorkflow", mock.Anything, "wf-1", "").Return(run)
+
+	result, err := getWorkflowStatusHandler(context.Background(), c, newToolRequest(map[string]interface{}{
+		"workflow_id":     "wf-1",
+		"include_outputs": true,
+		"compact":         true,
+	}))
+	require.NoError(t, err)
+	require.Contains(t, resultText(t, result), `{"vpc":{"id":"vpc-1"}}`)
+}
+
+func TestLintConfigHandler_InvalidConfig(t *testing.T) {
+	result, err := lintConfigHandler(context.Background(), newToolRequest(map[string]interface{}{}))
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	require.Contains(t, resultText(t, result), "Provide config_path or config")
+}
@@ -4,8 +4,12 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"log"
+	"os"
+	"sort"
+	"strings"
 
 	"github.com/fakoli/temporal-terraform-orchestrator/utils"
 	"github.com/fakoli/temporal-terraform-orchestrator/workflow"
@@ -16,17 +20,44 @@ func main() {
 	configPath := flag.String("config", "infra.yaml", "path to infrastructure YAML config")
 	taskQueue := flag.String("task-queue", utils.TaskQueue, "Temporal task queue to use")
 	workflowID := flag.String("workflow-id", utils.WorkflowID, "Temporal workflow ID")
+	outputsFile := flag.String("outputs-file", "", "write workspace outputs as JSON to this path after completion")
+	flattenOutputs := flag.Bool("flatten-outputs", false, "key outputs as workspace.output in the outputs file")
+	lintOnly := flag.Bool("lint", false, "print config lint warnings and exit without starting the workflow")
+	planOnly := flag.Bool("plan-only", false, "drop refresh, apply and destroy from every workspace, so the run only plans")
+	strictEnv := flag.Bool("strict-env", false, "fail if the config references an unset environment variable")
+	maxWorkspaces := flag.Int("max-workspaces", workflow.DefaultMaxWorkspaces, "reject configs with more workspaces")
 	flag.Parse()
 
-	cfg, err := workflow.LoadConfigFromFile(*configPath)
+	cfg, err := workflow.LoadConfigFromFileWithOptions(*configPath, workflow.LoadOptions{Strict: *strictEnv})
 	if err != nil {
 		log.Fatalf("Unable to load config file %s: %v", *configPath, err)
 	}
 
-	if err := workflow.ValidateInfrastructureConfig(cfg); err != nil {
+	cfg, err = workflow.PrepareConfig(cfg)
+	if err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+	if err := workflow.CheckWorkspaceLimit(cfg, *maxWorkspaces); err != nil {
 		log.Fatalf("Invalid config: %v", err)
 	}
-	cfg = workflow.NormalizeInfrastructureConfig(cfg)
+
+	if *planOnly {
+		var downgraded []string
+		cfg, downgraded = workflow.StripMutatingOperations(cfg)
+		if len(downgraded) > 0 {
+			log.Println("Plan-only: dropped apply/destroy from workspaces:", strings.Join(downgraded, ", "))
+		}
+	}
+
+	// Lint warnings never block a run
+	warnings := workflow.LintConfig(cfg)
+	for _, w := range warnings {
+		log.Println("Lint warning:", w)
+	}
+	if *lintOnly {
+		log.Printf("Lint finished with %d warning(s)", len(warnings))
+		return
+	}
 
 	c, err := client.Dial(client.Options{})
 	if err != nil {
@@ -35,8 +66,9 @@ func main() {
 	defer c.Close()
 
 	workflowOptions := client.StartWorkflowOptions{
-		ID:        *workflowID,
-		TaskQueue: *taskQueue,
+		ID:                       *workflowID,
+		TaskQueue:                *taskQueue,
+		WorkflowExecutionTimeout: workflow.WorkflowExecutionTimeout(cfg),
 	}
 
 	we, err := c.ExecuteWorkflow(context.Background(), workflowOptions, workflow.ParentWorkflow, cfg)
@@ -46,11 +78,75 @@ func main() {
 
 	log.Println("Started workflow", "WorkflowID", we.GetID(), "RunID", we.GetRunID())
 
-	var result error
+	var result workflow.OrchestrationResult
 	err = we.Get(context.Background(), &result)
 	if err != nil {
+		if partial, ok := workflow.PartialResult(err); ok {
+			reportPartialResult(partial)
+			if *outputsFile != "" {
+				if err := writeOutputs(*outputsFile, partial, *flattenOutputs); err != nil {
+					log.Printf("Unable to write outputs file %s: %v", *outputsFile, err)
+				} else {
+					log.Println("Wrote outputs of succeeded workspaces", "path", *outputsFile)
+				}
+			}
+		}
 		log.Fatalln("Workflow failed", err)
 	}
 
 	log.Println("Workflow completed successfully")
+
+	if *outputsFile != "" {
+		if err := writeOutputs(*outputsFile, result, *flattenOutputs); err != nil {
+			log.Fatalf("Unable to write outputs file %s: %v", *outputsFile, err)
+		}
+		log.Println("Wrote workspace outputs", "path", *outputsFile)
+	}
+}
+
+// reportPartialResult logs which workspaces succeeded, failed and were
+// skipped in a failed orchestration.
+func reportPartialResult(partial workflow.OrchestrationResult) {
+	succeeded := make([]string, 0, len(partial.Outputs))
+	for name := range partial.Outputs {
+		succeeded = append(succeeded, name)
+	}
+	sort.Strings(succeeded)
+	log.Println("Succeeded workspaces:", strings.Join(succeeded, ", "))
+
+	for _, name := range sortedNames(partial.Failed) {
+		log.Printf("Failed workspace %s: %s", name, partial.Failed[name])
+	}
+	for _, name := range sortedNames(partial.Skipped) {
+		log.Printf("Skipped workspace %s: %s", name, partial.Skipped[name])
+	}
+}
+
+func sortedNames(m map[string]string) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeOutputs saves the workspace outputs as JSON, namespaced by workspace.
+// With flatten, a "workspace.output" keyed view is written instead and any
+// colliding keys are reported.
+func writeOutputs(path string, result workflow.OrchestrationResult, flatten bool) error {
+	var payload interface{} = result.Outputs
+	if flatten {
+		flat := result.Flattened()
+		for _, collision := range flat.Collisions {
+			log.Println("Output key collision, omitted from flattened view:", collision)
+		}
+		payload = flat
+	}
+
+	data, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
 }
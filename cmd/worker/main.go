@@ -4,8 +4,11 @@ package main
 
 import (
 	"log"
+	"os"
+	"strings"
 
 	"github.com/fakoli/temporal-terraform-orchestrator/activities"
+	"github.com/fakoli/temporal-terraform-orchestrator/utils"
 	orchestrator "github.com/fakoli/temporal-terraform-orchestrator/workflow"
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/worker"
@@ -18,16 +21,36 @@ func main() {
 	}
 	defer c.Close()
 
-	w := worker.New(c, "terraform-task-queue", worker.Options{})
+	// Saved plans are discarded unless PLAN_STORE_DIR names a directory to keep them in
+	var plans activities.PlanStore = activities.NoopPlanStore{}
+	if dir := os.Getenv("PLAN_STORE_DIR"); dir != "" {
+		plans = activities.FilePlanStore{Root: dir}
+	}
 
-	w.RegisterWorkflow(orchestrator.ParentWorkflow)
-	w.RegisterWorkflow(orchestrator.TerraformWorkflow)
+	// Remote vars may only reach Terraform Cloud with TFE_TOKEN unless the
+	// operator allows more addresses and token variables
+	remoteVars := activities.RemoteVarsPolicy{
+		Addresses: splitList(os.Getenv("REMOTE_VARS_ADDRESSES")),
+		TokenEnvs: splitList(os.Getenv("REMOTE_VARS_TOKEN_ENVS")),
+	}
 
-	var a *activities.TerraformActivities
-	w.RegisterActivity(a)
+	// Must match the queue the starter and MCP server submit to
+	w := worker.New(c, utils.TaskQueue, worker.Options{})
+	orchestrator.RegisterWorker(w, c, &activities.TerraformActivities{PlanStore: plans, RemoteVars: remoteVars})
 
 	err = w.Run(worker.InterruptCh())
 	if err != nil {
 		log.Fatalln("Unable to start worker", err)
 	}
 }
+
+// splitList parses a comma-separated environment variable, ignoring blanks.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
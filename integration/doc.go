@@ -0,0 +1,11 @@
+// Package integration holds end-to-end tests that run the real worker
+// against a Temporal server, with a fake terraform binary on PATH.
+//
+// The tests are behind the "integration" build tag:
+//
+//	go test -tags integration ./integration/...
+//
+// Set TEMPORAL_ADDRESS to use a running server (e.g. `temporal server
+// start-dev`); otherwise a dev server is started, using TEMPORAL_CLI_PATH
+// when set and downloading the Temporal CLI when not.
+package integration
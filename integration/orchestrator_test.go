@@ -0,0 +1,135 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fakoli/temporal-terraform-orchestrator/activities"
+	"github.com/fakoli/temporal-terraform-orchestrator/utils"
+	"github.com/fakoli/temporal-terraform-orchestrator/workflow"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+)
+
+// fakeTerraform succeeds for every subcommand the workflows run. plan writes
+// the -out file and reports changes; output echoes TF_FAKE_OUTPUTS.
+const fakeTerraform = `#!/bin/sh
+while [ "$#" -gt 0 ]; do
+  case "$1" in
+    -chdir=*) shift ;;
+    *) break ;;
+  esac
+done
+cmd="$1"; shift
+case "$cmd" in
+  init|validate|apply) exit 0 ;;
+  plan)
+    while [ "$#" -gt 0 ]; do
+      if [ "$1" = "-out" ]; then : > "$2"; fi
+      shift
+    done
+    exit 2
+    ;;
+  show) echo '{"format_version":"1.0"}' ;;
+  output) echo "$TF_FAKE_OUTPUTS" ;;
+  *) echo "unexpected terraform $cmd" >&2; exit 1 ;;
+esac
+`
+
+// temporalClient connects to TEMPORAL_ADDRESS, or starts a dev server for
+// the duration of the test.
+func temporalClient(t *testing.T) client.Client {
+	t.Helper()
+
+	if addr := os.Getenv("TEMPORAL_ADDRESS"); addr != "" {
+		c, err := client.Dial(client.Options{HostPort: addr})
+		require.NoError(t, err)
+		t.Cleanup(c.Close)
+		return c
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	server, err := testsuite.StartDevServer(ctx, testsuite.DevServerOptions{
+		ExistingPath: os.Getenv("TEMPORAL_CLI_PATH"),
+		LogLevel:     "error",
+	})
+	require.NoError(t, err, "start Temporal dev server (or set TEMPORAL_ADDRESS)")
+	t.Cleanup(func() { require.NoError(t, server.Stop()) })
+
+	c := server.Client()
+	t.Cleanup(c.Close)
+	return c
+}
+
+// startWorker runs the production worker registration on utils.TaskQueue,
+// the queue the starter and MCP server submit to.
+func startWorker(t *testing.T, c client.Client) {
+	t.Helper()
+
+	w := worker.New(c, utils.TaskQueue, worker.Options{})
+	workflow.RegisterWorker(w, c, &activities.TerraformActivities{PlanStore: activities.NoopPlanStore{}})
+	require.NoError(t, w.Start())
+	t.Cleanup(w.Stop)
+}
+
+// installFakeTerraform puts the fake terraform first on PATH.
+func installFakeTerraform(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "terraform"), []byte(fakeTerraform), 0o755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("TF_FAKE_OUTPUTS", `{"vpc_id":{"value":"vpc-123"}}`)
+}
+
+func TestParentWorkflow_EndToEnd(t *testing.T) {
+	installFakeTerraform(t)
+	c := temporalClient(t)
+	startWorker(t, c)
+
+	root := t.TempDir()
+	for _, name := range []string{"vpc", "subnets", "eks"} {
+		require.NoError(t, os.Mkdir(filepath.Join(root, name), 0o755))
+	}
+
+	cfg := workflow.InfrastructureConfig{
+		WorkspaceRoot: root,
+		TempRoot:      t.TempDir(),
+		Workspaces: []workflow.WorkspaceConfig{
+			{Name: "vpc", Dir: "vpc"},
+			{
+				Name:      "subnets",
+				Dir:       "subnets",
+				DependsOn: []string{"vpc"},
+				Inputs:    []workflow.InputMapping{{SourceWorkspace: "vpc", SourceOutput: "vpc_id", TargetVar: "vpc_id"}},
+			},
+			{Name: "eks", Dir: "eks", DependsOn: []string{"vpc", "subnets"}},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	run, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:        fmt.Sprintf("%s-integration-%d", utils.WorkflowID, time.Now().UnixNano()),
+		TaskQueue: utils.TaskQueue,
+	}, workflow.ParentWorkflow, cfg)
+	require.NoError(t, err)
+
+	var result workflow.OrchestrationResult
+	require.NoError(t, run.Get(ctx, &result))
+
+	require.Len(t, result.Outputs, 3)
+	for name, outputs := range result.Outputs {
+		require.Equal(t, "vpc-123", outputs["vpc_id"], name)
+	}
+}
@@ -8,4 +8,8 @@ const (
 
 	// WorkflowID is the default identifier for the parent orchestrator workflow.
 	WorkflowID = "terraform-parent-workflow"
+
+	// MaxWorkspacesEnv names the environment variable the MCP server reads
+	// the operator's workspace limit from.
+	MaxWorkspacesEnv = "MAX_WORKSPACES"
 )
@@ -0,0 +1,93 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+)
+
+// conditionCostLimit caps the work a single `when` expression may do, so an
+// expensive expression (e.g. nested macros over a huge list) fails instead of
+// stalling the workflow. Conditions run in workflow code, so the limit is
+// CEL's deterministic cost budget rather than a wall-clock timeout.
+const conditionCostLimit = 100_000
+
+// compileCondition parses and type-checks a workspace `when` expression.
+// Conditions see the config's global vars as `vars` and must return a bool.
+func compileCondition(expr string) (cel.Program, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("vars", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create condition environment: %v", err)
+	}
+
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid condition %q: %v", expr, issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("condition %q must return bool, got %s", expr, ast.OutputType())
+	}
+
+	prg, err := env.Program(ast, cel.CostLimit(conditionCostLimit))
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %v", expr, err)
+	}
+	return prg, nil
+}
+
+// EvaluateCondition evaluates a workspace `when` expression against the
+// config's global vars. An empty expression always evaluates to true.
+func EvaluateCondition(expr string, vars map[string]interface{}) (bool, error) {
+	if expr == "" {
+		return true, nil
+	}
+
+	prg, err := compileCondition(expr)
+	if err != nil {
+		return false, err
+	}
+
+	if vars == nil {
+		vars = map[string]interface{}{}
+	}
+	out, _, err := prg.Eval(map[string]interface{}{"vars": vars})
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate condition %q: %v", expr, err)
+	}
+
+	result, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("condition %q did not return a bool", expr)
+	}
+	return result, nil
+}
+
+// skippedWorkspaces evaluates every workspace condition and returns the set
+// of workspaces whose condition is false. Workspaces that still run may not
+// consume outputs from a skipped workspace, since those outputs never exist.
+func skippedWorkspaces(cfg InfrastructureConfig) (map[string]bool, error) {
+	skipped := make(map[string]bool)
+	for _, ws := range cfg.Workspaces {
+		run, err := EvaluateCondition(ws.When, cfg.Vars)
+		if err != nil {
+			return nil, fmt.Errorf("workspace %s: %v", ws.Name, err)
+		}
+		if !run {
+			skipped[ws.Name] = true
+		}
+	}
+
+	for _, ws := range cfg.Workspaces {
+		if skipped[ws.Name] {
+			continue
+		}
+		for _, input := range ws.Inputs {
+			if skipped[input.SourceWorkspace] {
+				return nil, fmt.Errorf("workspace %s needs output %s from %s, which was skipped by its condition", ws.Name, input.SourceOutput, input.SourceWorkspace)
+			}
+		}
+	}
+	return skipped, nil
+}
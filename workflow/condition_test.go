@@ -0,0 +1,96 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateCondition(t *testing.T) {
+	vars := map[string]interface{}{
+		"env":     "prod",
+		"enabled": true,
+		"regions": []interface{}{"us-east-1", "eu-west-1"},
+	}
+
+	tests := []struct {
+		name    string
+		expr    string
+		want    bool
+		wantErr bool
+	}{
+		{"empty always runs", "", true, false},
+		{"string comparison", `vars.env == "prod"`, true, false},
+		{"bool var", `vars.enabled`, true, false},
+		{"list membership", `"eu-west-1" in vars.regions`, true, false},
+		{"has macro on missing var", `has(vars.feature) && vars.feature`, false, false},
+		{"missing var", `vars.feature == "on"`, false, true},
+		{"syntax error", `vars.env ==`, false, true},
+		{"non-bool result", `"prod"`, false, true},
+	}
+
+	for _, tt := range tests {
+		got, err := EvaluateCondition(tt.expr, vars)
+		if tt.wantErr {
+			assert.Error(t, err, tt.name)
+			continue
+		}
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.want, got, tt.name)
+	}
+}
+
+func TestValidateInfrastructureConfig_InvalidCondition(t *testing.T) {
+	cfg := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "a", Dir: "/tmp/a", When: `vars.env ==`},
+		},
+	}
+
+	err := ValidateInfrastructureConfig(cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "workspace a: invalid condition")
+}
+
+func TestEvaluateCondition_CostLimit(t *testing.T) {
+	items := make([]interface{}, 2000)
+	for i := range items {
+		items[i] = i
+	}
+	vars := map[string]interface{}{"items": items}
+
+	// Nested macros over a large list: ~4M comparisons, far over the budget
+	_, err := EvaluateCondition(`vars.items.all(x, vars.items.all(y, x >= 0 || y >= 0))`, vars)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cost limit exceeded")
+
+	// A single pass over the same list stays within budget
+	ok, err := EvaluateCondition(`vars.items.exists(x, x == 1999)`, vars)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestEvaluateCondition_NestedVars(t *testing.T) {
+	// Nested maps arrive as map[string]interface{} from every config format
+	bodies := map[string]string{
+		".yaml": "vars:\n  tags:\n    Environment: prod\n",
+		".json": `{"vars": {"tags": {"Environment": "prod"}}}`,
+		".hcl":  "vars = { tags = { Environment = \"prod\" } }\n",
+	}
+	for ext, body := range bodies {
+		cfg, err := ParseConfig([]byte(body), ext)
+		assert.NoError(t, err, ext)
+
+		ok, err := EvaluateCondition(`vars.tags["Environment"] == "prod"`, cfg.Vars)
+		assert.NoError(t, err, ext)
+		assert.True(t, ok, ext)
+
+		ok, err = EvaluateCondition(`vars.tags.Environment == "staging"`, cfg.Vars)
+		assert.NoError(t, err, ext)
+		assert.False(t, ok, ext)
+
+		ok, err = EvaluateCondition(`has(vars.tags.Owner) && vars.tags.Owner == "net"`, cfg.Vars)
+		assert.NoError(t, err, ext)
+		assert.False(t, ok, ext)
+	}
+}
@@ -8,9 +8,14 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/fakoli/temporal-terraform-orchestrator/activities"
 	"gopkg.in/yaml.v3"
 )
 
@@ -19,6 +24,118 @@ import (
 type InfrastructureConfig struct {
 	WorkspaceRoot string            `json:"workspace_root" yaml:"workspace_root"`
 	Workspaces    []WorkspaceConfig `json:"workspaces" yaml:"workspaces"`
+
+	// TempRoot is the default directory for generated artifacts (combined
+	// tfvars, plan files) for workspaces that don't set their own.
+	TempRoot string `json:"tempRoot,omitempty" yaml:"tempRoot,omitempty"`
+
+	// Vars are global values available to workspace `when` conditions.
+	Vars map[string]interface{} `json:"vars,omitempty" yaml:"vars,omitempty"`
+
+	// Providers is the default provider policy for workspaces without their own.
+	Providers *ProviderPolicy `json:"providers,omitempty" yaml:"providers,omitempty"`
+
+	// MaxDepth caps the longest dependency chain (dependsOn and waitFor, as
+	// counted by CalculateDepths). Deep chains run serially and nest hosting
+	// workflows deeply. Zero uses DefaultMaxDepth.
+	MaxDepth int `json:"maxDepth,omitempty" yaml:"maxDepth,omitempty"`
+
+	// Timeout bounds the whole orchestration as a Go duration (e.g. "2h").
+	// When it expires ParentWorkflow fails, listing the pending workspaces.
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	// ShutdownGracePeriod delays the shutdown signal to hosting workflows
+	// after the last workspace finishes, as a Go duration (e.g. "30s"), so
+	// work they do after reporting completion isn't cut short.
+	ShutdownGracePeriod string `json:"shutdownGracePeriod,omitempty" yaml:"shutdownGracePeriod,omitempty"`
+
+	// MaxConcurrency limits how many workspaces run at once. Ready workspaces
+	// beyond the limit wait, highest Priority first. Zero means unlimited.
+	MaxConcurrency int `json:"maxConcurrency,omitempty" yaml:"maxConcurrency,omitempty"`
+
+	// ContinueOnError keeps running branches unrelated to a failed workspace.
+	// By default a failure stops new workspaces from starting; running ones
+	// finish first. Dependents of a failed workspace are skipped either way.
+	ContinueOnError bool `json:"continueOnError,omitempty" yaml:"continueOnError,omitempty"`
+
+	// Destroy tears the infrastructure down instead of building it. The DAG
+	// runs in reverse, each workspace waiting for everything that depends on
+	// it, and workspaces without explicit operations run init, validate and
+	// destroy. Input mappings are not resolved, since their sources haven't
+	// been torn down yet; supply any required variables through tfvars.
+	Destroy bool `json:"destroy,omitempty" yaml:"destroy,omitempty"`
+
+	// SpeculativePlan plans every workspace at once, ignoring dependsOn and
+	// waitFor, for previews where nothing is applied. Input mappings are not
+	// resolved; each workspace plans with its own tfvars only. Workspaces
+	// without explicit operations run init, validate and plan.
+	SpeculativePlan bool `json:"speculativePlan,omitempty" yaml:"speculativePlan,omitempty"`
+
+	// OutputRemap renames keys of the flattened outputs view, from
+	// "workspace.output" to the name downstream tooling expects (e.g.
+	// "vpc.vpc_id" to "network_vpc_id"). Unmapped keys keep their name and
+	// the namespaced outputs are unchanged.
+	OutputRemap map[string]string `json:"outputRemap,omitempty" yaml:"outputRemap,omitempty"`
+
+	// PlanPreview makes the run plan-only, capturing every workspace's plan
+	// and aggregating them in OrchestrationResult.Preview. Workspaces without
+	// explicit operations run init, validate and plan.
+	PlanPreview *PlanPreviewConfig `json:"planPreview,omitempty" yaml:"planPreview,omitempty"`
+
+	// BackendCheck flags workspaces that apply while their module keeps
+	// state locally (no backend, or the "local" backend), which an ephemeral
+	// worker loses. BackendCheckWarn logs them; BackendCheckError fails the
+	// run before anything starts. Unset performs no check.
+	BackendCheck string `json:"backendCheck,omitempty" yaml:"backendCheck,omitempty"`
+
+	// RetryBudget caps the activity retries of all workspaces together;
+	// first attempts don't count. Once it is spent, failed activities are no
+	// longer retried, so a flaky dependency can't multiply retries across the
+	// orchestration. Zero means unlimited.
+	RetryBudget int `json:"retryBudget,omitempty" yaml:"retryBudget,omitempty"`
+}
+
+// BackendCheck modes
+const (
+	BackendCheckWarn  = "warn"
+	BackendCheckError = "error"
+)
+
+// timeoutGracePeriod is added to Timeout for the Temporal execution timeout,
+// so the workflow's own watchdog fires first and can report what was pending.
+const timeoutGracePeriod = 5 * time.Minute
+
+// DefaultMaxWorkspaces is the workspace limit CheckWorkspaceLimit applies
+// when the operator sets none.
+const DefaultMaxWorkspaces = 500
+
+// DefaultMaxDepth is the dependency depth limit when MaxDepth is unset.
+const DefaultMaxDepth = 50
+
+// maxParallelism bounds WorkspaceConfig.Parallelism; higher values mostly
+// trade speed for provider API rate limiting.
+const maxParallelism = 256
+
+// RemoteVarSet reads terraform variables from Terraform Cloud/Enterprise,
+// either a workspace's variables or a variable set. Values are merged below
+// the workspace's mapped inputs and above its tfvars file.
+type RemoteVarSet struct {
+	Address     string `json:"address,omitempty" yaml:"address,omitempty"` // Defaults to https://app.terraform.io
+	WorkspaceID string `json:"workspaceId,omitempty" yaml:"workspaceId,omitempty"`
+	VarSetID    string `json:"varSetId,omitempty" yaml:"varSetId,omitempty"`
+
+	// TokenEnv names the worker environment variable holding the API token
+	// (default TFE_TOKEN). The token itself never goes in the config.
+	TokenEnv string `json:"tokenEnv,omitempty" yaml:"tokenEnv,omitempty"`
+}
+
+// ProviderPolicy restricts the terraform providers a workspace may install.
+// Entries are provider sources ("hashicorp/aws" or a full registry address)
+// and may use path.Match wildcards such as "hashicorp/*". Deny wins over allow;
+// an empty allow list permits anything not denied.
+type ProviderPolicy struct {
+	Allow []string `json:"allow,omitempty" yaml:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty" yaml:"deny,omitempty"`
 }
 
 // WorkspaceConfig defines a single workspace/run target.
@@ -31,11 +148,166 @@ type WorkspaceConfig struct {
 	Inputs     []InputMapping `json:"inputs,omitempty" yaml:"inputs,omitempty"`
 	TaskQueue  string         `json:"taskQueue,omitempty" yaml:"taskQueue,omitempty"`
 	Operations []string       `json:"operations,omitempty" yaml:"operations,omitempty"`
+	TempRoot   string         `json:"tempRoot,omitempty" yaml:"tempRoot,omitempty"`
+
+	// Vars are static variable values set directly in the config. They
+	// override TFVars and remote variables, and are overridden by values
+	// mapped from dependency outputs through Inputs.
+	Vars map[string]interface{} `json:"vars,omitempty" yaml:"vars,omitempty"`
+
+	// CIDRVars names the variables (set in TFVars or Vars) holding the
+	// address ranges this workspace allocates, e.g. a VPC CIDR. They are
+	// checked pre-flight for overlaps with other workspaces' ranges.
+	CIDRVars []string `json:"cidrVars,omitempty" yaml:"cidrVars,omitempty"`
+
+	// WaitFor lists workspaces that must finish first without implying a
+	// data dependency: the workspace doesn't nest under them and can't map
+	// their outputs (inputs still require dependsOn).
+	WaitFor []string `json:"waitFor,omitempty" yaml:"waitFor,omitempty"`
+
+	// When is an optional CEL expression evaluated against the global vars
+	// (e.g. `vars.env == "prod"`). Workspaces whose condition is false are
+	// skipped and treated as completed with no outputs.
+	When string `json:"when,omitempty" yaml:"when,omitempty"`
+
+	// Priority orders workspaces that become ready at the same time: higher
+	// values start first, ties keep config order.
+	Priority int `json:"priority,omitempty" yaml:"priority,omitempty"`
+
+	// Chdir is a subdirectory of Dir passed to terraform's -chdir flag for
+	// modules that reference paths relative to the invocation directory.
+	Chdir string `json:"chdir,omitempty" yaml:"chdir,omitempty"`
+
+	// DetectOnly plans without saving a plan file, only reporting whether
+	// changes exist. Useful for drift checks; incompatible with apply.
+	DetectOnly bool `json:"detectOnly,omitempty" yaml:"detectOnly,omitempty"`
+
+	// DriftCheck turns the plan operation into a refresh-only plan that
+	// reports, in the workspace details, whether the infrastructure changed
+	// outside terraform. Nothing is saved or applied.
+	DriftCheck bool `json:"driftCheck,omitempty" yaml:"driftCheck,omitempty"`
+
+	// LayerVarFiles passes tfvars and propagated inputs to terraform as
+	// separate -var-file flags instead of merging them into one file.
+	LayerVarFiles bool `json:"layerVarFiles,omitempty" yaml:"layerVarFiles,omitempty"`
+
+	// PreferAutoTFVars lets terraform.tfvars and *.auto.tfvars in the module
+	// directory override values from TFVars. Inputs still override both.
+	PreferAutoTFVars bool `json:"preferAutoTfvars,omitempty" yaml:"preferAutoTfvars,omitempty"`
+
+	// Targets limits plan to the given resource addresses (e.g.
+	// "module.vpc.aws_subnet.private[0]") via -target flags, for surgical
+	// changes to large workspaces. Apply uses the targeted plan.
+	Targets []string `json:"targets,omitempty" yaml:"targets,omitempty"`
+
+	// Parallelism sets terraform's -parallelism for plan, apply and destroy
+	// (1-256). Zero keeps terraform's default of 10.
+	Parallelism int `json:"parallelism,omitempty" yaml:"parallelism,omitempty"`
+
+	// LockTimeout is how long plan, apply and destroy wait for a state lock
+	// held by another run, as a duration such as "30s". Unset fails
+	// immediately (terraform's 0s default); lock failures are retried.
+	LockTimeout string `json:"lockTimeout,omitempty" yaml:"lockTimeout,omitempty"`
+
+	// LegacyRefresh runs the "refresh" operation as `terraform refresh`
+	// instead of `terraform apply -refresh-only`, for terraform versions
+	// before 0.15.4.
+	LegacyRefresh bool `json:"legacyRefresh,omitempty" yaml:"legacyRefresh,omitempty"`
+
+	// DisableLock runs detectOnly plans and drift checks with -lock=false so
+	// change detection never waits on or blocks another run's state lock.
+	// Only read-only plans qualify: it requires detectOnly or driftCheck and
+	// never affects apply or destroy.
+	DisableLock bool `json:"disableLock,omitempty" yaml:"disableLock,omitempty"`
+
+	// MaxOutputBytes bounds the terraform output kept in error messages
+	// (default 16 KiB); OutputLogDir, if set, receives the full output.
+	MaxOutputBytes int    `json:"maxOutputBytes,omitempty" yaml:"maxOutputBytes,omitempty"`
+	OutputLogDir   string `json:"outputLogDir,omitempty" yaml:"outputLogDir,omitempty"`
+
+	// RemoteVarSet optionally fetches variables from Terraform Cloud.
+	RemoteVarSet *RemoteVarSet `json:"remoteVarSet,omitempty" yaml:"remoteVarSet,omitempty"`
+
+	// IncludeEffectiveVars reports the merged variables terraform sees (tfvars
+	// plus inputs) in the workspace details, and in the error if plan
+	// fails. SensitiveVars, names that look like secrets and inputs mapped
+	// from outputs terraform marks sensitive are redacted.
+	IncludeEffectiveVars bool     `json:"includeEffectiveVars,omitempty" yaml:"includeEffectiveVars,omitempty"`
+	SensitiveVars        []string `json:"sensitiveVars,omitempty" yaml:"sensitiveVars,omitempty"`
+
+	// CacheValidation skips init and validate when the module files, lock
+	// file, installed providers, tfvars and inputs all match the last
+	// successful validation. Plan and apply always run.
+	CacheValidation bool `json:"cacheValidation,omitempty" yaml:"cacheValidation,omitempty"`
+
+	// CaptureInitInfo records the providers and backend reported by
+	// terraform init in the workspace details.
+	CaptureInitInfo bool `json:"captureInitInfo,omitempty" yaml:"captureInitInfo,omitempty"`
+
+	// SkipInitIfInitialized skips init when the module already has a
+	// .terraform directory and a readable lock file, for fast local
+	// iteration. InitUpgrade forces init to run.
+	SkipInitIfInitialized bool `json:"skipInitIfInitialized,omitempty" yaml:"skipInitIfInitialized,omitempty"`
+
+	// InitUpgrade runs init with -upgrade to pick newer provider and module
+	// versions within their constraints.
+	InitUpgrade bool `json:"initUpgrade,omitempty" yaml:"initUpgrade,omitempty"`
+
+	// CapturePlan records the plan text and resource counts in the workspace
+	// details when the plan has changes, so they can be reviewed before
+	// apply. It needs a saved plan file, so not detectOnly.
+	CapturePlan bool `json:"capturePlan,omitempty" yaml:"capturePlan,omitempty"`
+
+	// PersistPlan uploads the saved plan file to the worker's plan store
+	// after each successful plan, under <workflow id>/<run id>/<name>.tfplan,
+	// for auditing. It needs a saved plan file, so not detectOnly.
+	PersistPlan bool `json:"persistPlan,omitempty" yaml:"persistPlan,omitempty"`
+
+	// ProtectedResources are resource address patterns ("*" wildcards) that
+	// the plan may not delete or replace. The workspace fails after plan,
+	// before apply, listing the offending resources. It needs a saved plan
+	// file, so not detectOnly, and can't be combined with destroy.
+	ProtectedResources []string `json:"protectedResources,omitempty" yaml:"protectedResources,omitempty"`
+
+	// AllowLocalState exempts this workspace from the backendCheck, for
+	// modules that deliberately keep state on the worker.
+	AllowLocalState bool `json:"allowLocalState,omitempty" yaml:"allowLocalState,omitempty"`
+
+	// Providers overrides the config-level provider policy. It is checked
+	// against .terraform.lock.hcl after init.
+	Providers *ProviderPolicy `json:"providers,omitempty" yaml:"providers,omitempty"`
+
+	// ExpectedOutputs are asserted against the workspace's terraform outputs
+	// after apply; any mismatch fails the workspace.
+	ExpectedOutputs map[string]interface{} `json:"expectedOutputs,omitempty" yaml:"expectedOutputs,omitempty"`
+
+	// FailOnOutputError controls whether a failure to read terraform outputs
+	// after the operations fails the workspace (the default). Set it to false
+	// on leaf workspaces so an output error doesn't fail a successful apply;
+	// the workspace then finishes with no outputs. Workspaces whose outputs
+	// are mapped or asserted must keep the default.
+	FailOnOutputError *bool `json:"failOnOutputError,omitempty" yaml:"failOnOutputError,omitempty"`
+
+	// Outputs declares the outputs the workspace produces. When set, input
+	// mappings reading from this workspace may only name declared outputs,
+	// so typos fail validation instead of silently resolving to nothing.
+	Outputs []string `json:"outputs,omitempty" yaml:"outputs,omitempty"`
 
 	// ExtraVars are populated at runtime by the parent workflow
 	// from resolved InputMappings. Values preserve their original JSON types
 	// (string, number, bool, array, object) to match Terraform variable types.
 	ExtraVars map[string]interface{} `json:"extraVars,omitempty" yaml:"extraVars,omitempty"`
+
+	// RetriesLeft is set at runtime by the parent workflow to the
+	// workspace's share of the retries left in the orchestration's
+	// RetryBudget when it starts. Nil means no budget.
+	RetriesLeft *int `json:"retriesLeft,omitempty" yaml:"retriesLeft,omitempty"`
+}
+
+// failOnOutputError reports whether an output read failure fails the
+// workspace; it does unless FailOnOutputError is explicitly false.
+func (ws WorkspaceConfig) failOnOutputError() bool {
+	return ws.FailOnOutputError == nil || *ws.FailOnOutputError
 }
 
 // Signal names
@@ -45,6 +317,25 @@ const (
 	SignalShutdown          = "shutdown"
 )
 
+// Query names
+const (
+	// QueryWorkspaceState reports a TerraformWorkflow's activities.WorkspaceState
+	QueryWorkspaceState = "workspace-state"
+	// QueryProgress reports a ParentWorkflow's OrchestrationProgress
+	QueryProgress = "progress"
+	// QueryHealth reports a ParentWorkflow's OrchestrationHealth
+	QueryHealth = "health"
+)
+
+// Workspace statuses reported by QueryHealth
+const (
+	WorkspaceStatusCompleted = "completed"
+	WorkspaceStatusRunning   = "running"
+	WorkspaceStatusPending   = "pending"
+	WorkspaceStatusFailed    = "failed"
+	WorkspaceStatusSkipped   = "skipped"
+)
+
 // StartChildSignal payload
 type StartChildSignal struct {
 	Workspace WorkspaceConfig
@@ -52,8 +343,47 @@ type StartChildSignal struct {
 
 // WorkspaceFinishedSignal payload
 type WorkspaceFinishedSignal struct {
-	Name    string
-	Outputs map[string]interface{}
+	Name             string
+	Outputs          map[string]interface{}
+	SensitiveOutputs []string                     // Names of Outputs terraform marks sensitive, sorted
+	Details          *activities.WorkspaceDetails // Nil when the workspace recorded none
+	Error            string                       // Set when the workspace failed; Outputs are then incomplete
+
+	// Retries counts the workspace's activity retries. It is only counted
+	// under a retry budget.
+	Retries int
+}
+
+// OrchestrationProgress is a snapshot of a running ParentWorkflow, returned
+// by the QueryProgress query. Names are in config order.
+type OrchestrationProgress struct {
+	Total     int      `json:"total"`
+	Completed []string `json:"completed"`
+	Running   []string `json:"running"`
+	Pending   []string `json:"pending"`
+	// Failed and Skipped workspaces are finished but not completed
+	Failed  []string `json:"failed,omitempty"`
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// WorkspaceHealth is one workspace's entry in OrchestrationHealth.
+type WorkspaceHealth struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Outputs are set once the workspace has completed
+	Outputs map[string]interface{} `json:"outputs,omitempty"`
+	// BlockedBy lists the unfinished dependencies (dependsOn and waitFor)
+	// a pending workspace is waiting on
+	BlockedBy []string `json:"blockedBy,omitempty"`
+	// Reason explains a failed or skipped status
+	Reason string `json:"reason,omitempty"`
+}
+
+// OrchestrationHealth combines each workspace's status, outputs and blockers
+// in one snapshot, returned by the QueryHealth query. Workspaces are in
+// config order.
+type OrchestrationHealth struct {
+	Workspaces []WorkspaceHealth `json:"workspaces"`
 }
 
 // InputMapping defines how to map an output from a dependency workspace
@@ -62,6 +392,11 @@ type InputMapping struct {
 	SourceWorkspace string `json:"sourceWorkspace" yaml:"sourceWorkspace"`
 	SourceOutput    string `json:"sourceOutput" yaml:"sourceOutput"`
 	TargetVar       string `json:"targetVar" yaml:"targetVar"`
+
+	// Type optionally coerces the resolved value before it is passed to
+	// Terraform (string, number or bool), e.g. a port output of "8080"
+	// feeding a number variable.
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
 }
 
 // NormalizeInfrastructureConfig applies defaults (e.g., kind) and resolves
@@ -91,9 +426,37 @@ func NormalizeInfrastructureConfig(cfg InfrastructureConfig) InfrastructureConfi
 		if ws.TFVars != "" && !filepath.IsAbs(ws.TFVars) {
 			ws.TFVars = filepath.Join(base, ws.TFVars)
 		}
+		if ws.TempRoot == "" {
+			ws.TempRoot = cfg.TempRoot
+		}
+		if ws.OutputLogDir != "" && !filepath.IsAbs(ws.OutputLogDir) {
+			ws.OutputLogDir = filepath.Join(base, ws.OutputLogDir)
+		}
+		if ws.Providers == nil {
+			ws.Providers = cfg.Providers
+		}
+		if ws.RemoteVarSet != nil && ws.RemoteVarSet.TokenEnv == "" {
+			remote := *ws.RemoteVarSet
+			remote.TokenEnv = activities.DefaultTFCTokenEnv
+			ws.RemoteVarSet = &remote
+		}
+		if ws.TempRoot != "" && !filepath.IsAbs(ws.TempRoot) {
+			ws.TempRoot = filepath.Join(base, ws.TempRoot)
+		}
 		// Apply default operations if not specified
 		if len(ws.Operations) == 0 {
 			ws.Operations = getDefaultOperations(ws.Kind)
+			if cfg.Destroy {
+				ws.Operations = getDestroyOperations(ws.Kind)
+			} else if ws.DetectOnly || ws.DriftCheck || cfg.SpeculativePlan || cfg.PlanPreview != nil {
+				// Detect-only plans and drift checks write no plan file, and
+				// speculative plans and plan previews are previews, so there
+				// is nothing to apply
+				ws.Operations = slices.DeleteFunc(ws.Operations, func(op string) bool { return op == "apply" })
+			}
+		}
+		if cfg.PlanPreview != nil {
+			ws.CapturePlan = true
 		}
 		cfg.Workspaces[i] = ws
 	}
@@ -101,12 +464,60 @@ func NormalizeInfrastructureConfig(cfg InfrastructureConfig) InfrastructureConfi
 }
 
 // ValidateInfrastructureConfig performs structural checks (duplicates, cycles,
-// missing dependencies, unsupported kinds). Keep it deterministic and pure so
-// it can be reused by CLI/MCP before workflow execution.
+// missing dependencies, unsupported kinds), then runs every registered
+// ConfigValidator, returning all failures joined. Keep it deterministic and
+// pure so it can be reused by CLI/MCP before workflow execution.
 func ValidateInfrastructureConfig(cfg InfrastructureConfig) error {
+	errs := []error{validateStructure(cfg)}
+	return errors.Join(append(errs, runConfigValidators(cfg)...)...)
+}
+
+// CheckWorkspaceLimit rejects a config with more than limit workspaces, a
+// guard against runaway generated configs. The limit is the operator's, not
+// the config's, so clients check it before starting a workflow rather than
+// the workflow checking it on whichever worker runs it. A limit of zero or
+// less means DefaultMaxWorkspaces.
+func CheckWorkspaceLimit(cfg InfrastructureConfig, limit int) error {
+	if limit <= 0 {
+		limit = DefaultMaxWorkspaces
+	}
+	if len(cfg.Workspaces) > limit {
+		return fmt.Errorf("config defines %d workspaces, exceeding the limit of %d set by the operator", len(cfg.Workspaces), limit)
+	}
+	return nil
+}
+
+// validateStructure holds the built-in checks, stopping at the first failure.
+func validateStructure(cfg InfrastructureConfig) error {
 	if len(cfg.Workspaces) == 0 {
 		return errors.New("no workspaces defined")
 	}
+	if _, err := orchestrationTimeout(cfg); err != nil {
+		return err
+	}
+	if _, err := shutdownGracePeriod(cfg); err != nil {
+		return err
+	}
+	if cfg.MaxDepth < 0 {
+		return fmt.Errorf("maxDepth must not be negative, got %d", cfg.MaxDepth)
+	}
+	if cfg.MaxConcurrency < 0 {
+		return fmt.Errorf("maxConcurrency must not be negative, got %d", cfg.MaxConcurrency)
+	}
+	if cfg.RetryBudget < 0 {
+		return fmt.Errorf("retryBudget must not be negative, got %d", cfg.RetryBudget)
+	}
+	if cfg.Destroy && cfg.SpeculativePlan {
+		return errors.New("destroy and speculativePlan cannot be combined")
+	}
+	if cfg.Destroy && cfg.PlanPreview != nil {
+		return errors.New("destroy and planPreview cannot be combined")
+	}
+	switch cfg.BackendCheck {
+	case "", BackendCheckWarn, BackendCheckError:
+	default:
+		return fmt.Errorf("backendCheck must be %q or %q, got %q", BackendCheckWarn, BackendCheckError, cfg.BackendCheck)
+	}
 
 	// index by name
 	index := make(map[string]WorkspaceConfig, len(cfg.Workspaces))
@@ -127,8 +538,51 @@ func ValidateInfrastructureConfig(cfg InfrastructureConfig) error {
 		if !isSupportedKind(kind) {
 			return fmt.Errorf("unsupported kind %s for workspace %s", kind, ws.Name)
 		}
+		if ws.When != "" {
+			if _, err := compileCondition(ws.When); err != nil {
+				return fmt.Errorf("workspace %s: %v", ws.Name, err)
+			}
+		}
+		if ws.MaxOutputBytes < 0 {
+			return fmt.Errorf("workspace %s: maxOutputBytes cannot be negative", ws.Name)
+		}
+		for name := range ws.Vars {
+			if strings.TrimSpace(name) == "" {
+				return fmt.Errorf("workspace %s has a variable with an empty name", ws.Name)
+			}
+		}
+		for _, name := range ws.CIDRVars {
+			if strings.TrimSpace(name) == "" {
+				return fmt.Errorf("workspace %s has an empty cidrVars entry", ws.Name)
+			}
+		}
+		if !ws.failOnOutputError() && len(ws.ExpectedOutputs) > 0 {
+			return fmt.Errorf("workspace %s: expectedOutputs cannot be checked when failOnOutputError is false", ws.Name)
+		}
+		if ws.Parallelism < 0 || ws.Parallelism > maxParallelism {
+			return fmt.Errorf("workspace %s: parallelism must be between 1 and %d, got %d", ws.Name, maxParallelism, ws.Parallelism)
+		}
+		for _, target := range ws.Targets {
+			if !isResourceAddress(target) {
+				return fmt.Errorf("workspace %s: invalid target %q: expected a resource address such as aws_instance.web or module.vpc", ws.Name, target)
+			}
+		}
+		if ws.LockTimeout != "" {
+			if d, err := time.ParseDuration(ws.LockTimeout); err != nil || d < 0 {
+				return fmt.Errorf("workspace %s: invalid lockTimeout %q: must be a non-negative duration such as 30s", ws.Name, ws.LockTimeout)
+			}
+		}
+		if err := validateProviderPolicy(ws.Providers); err != nil {
+			return fmt.Errorf("workspace %s: %v", ws.Name, err)
+		}
+		if ws.RemoteVarSet != nil && (ws.RemoteVarSet.WorkspaceID == "") == (ws.RemoteVarSet.VarSetID == "") {
+			return fmt.Errorf("workspace %s: remoteVarSet requires exactly one of workspaceId or varSetId", ws.Name)
+		}
 		index[ws.Name] = ws
 	}
+	if err := validateProviderPolicy(cfg.Providers); err != nil {
+		return err
+	}
 
 	// cycle detection via DFS (deterministic using slice order)
 	visiting := make(map[string]bool, len(index))
@@ -144,7 +598,7 @@ func ValidateInfrastructureConfig(cfg InfrastructureConfig) error {
 		}
 		visiting[name] = true
 		ws := index[name]
-		for _, dep := range ws.DependsOn {
+		for _, dep := range orderingDependencies(ws) {
 			if err := dfs(dep); err != nil {
 				return err
 			}
@@ -170,27 +624,147 @@ func ValidateInfrastructureConfig(cfg InfrastructureConfig) error {
 				return fmt.Errorf("workspace %s cannot depend on itself", ws.Name)
 			}
 		}
+		for _, dep := range ws.WaitFor {
+			if _, ok := index[dep]; !ok {
+				return fmt.Errorf("workspace %s waits for unknown workspace %s", ws.Name, dep)
+			}
+			if dep == ws.Name {
+				return fmt.Errorf("workspace %s cannot wait for itself", ws.Name)
+			}
+		}
+		for _, output := range ws.Outputs {
+			if strings.TrimSpace(output) == "" {
+				return fmt.Errorf("workspace %s has an empty output declaration", ws.Name)
+			}
+		}
 		for _, input := range ws.Inputs {
-			if _, ok := index[input.SourceWorkspace]; !ok {
+			source, ok := index[input.SourceWorkspace]
+			if !ok {
 				return fmt.Errorf("workspace %s input mapping source %s not found", ws.Name, input.SourceWorkspace)
 			}
+			if !source.failOnOutputError() {
+				return fmt.Errorf("workspace %s maps outputs of %s, which sets failOnOutputError to false; only workspaces whose outputs nothing reads may tolerate output errors", ws.Name, input.SourceWorkspace)
+			}
+			if len(source.Outputs) > 0 && !slices.Contains(source.Outputs, input.SourceOutput) {
+				return fmt.Errorf("workspace %s input mapping %s references output %s, which %s does not declare (declared: %s)",
+					ws.Name, input.TargetVar, input.SourceOutput, input.SourceWorkspace, strings.Join(source.Outputs, ", "))
+			}
 			// ensure the source workspace is actually a dependency (direct or transitive)
 			if !isTransitivelyDependent(ws.Name, input.SourceWorkspace, index) {
 				return fmt.Errorf("workspace %s must depend (directly or transitively) on %s to use its outputs in mapping", ws.Name, input.SourceWorkspace)
 			}
+			if !isSupportedVarType(input.Type) {
+				return fmt.Errorf("workspace %s input mapping %s has unsupported type %s", ws.Name, input.TargetVar, input.Type)
+			}
 		}
 	}
 
+	if err := validateDepth(cfg); err != nil {
+		return err
+	}
+	if err := validateOutputRemap(cfg.OutputRemap, index); err != nil {
+		return err
+	}
+
 	// Validate operations for each workspace
 	for _, ws := range cfg.Workspaces {
 		if err := ValidateWorkspaceOperations(ws); err != nil {
 			return err
 		}
+		if cfg.SpeculativePlan && hasMutatingOperation(ws.Operations) {
+			return fmt.Errorf("workspace %s: speculativePlan only plans; remove 'refresh', 'apply' and 'destroy' from its operations", ws.Name)
+		}
+		if cfg.PlanPreview != nil {
+			if hasMutatingOperation(ws.Operations) {
+				return fmt.Errorf("workspace %s: planPreview only plans; remove 'refresh', 'apply' and 'destroy' from its operations", ws.Name)
+			}
+			if ws.DetectOnly {
+				return fmt.Errorf("workspace %s: planPreview captures saved plans and cannot be combined with detectOnly", ws.Name)
+			}
+		}
+		if cfg.Destroy && len(ws.ProtectedResources) > 0 {
+			return fmt.Errorf("workspace %s: protectedResources can't guard destroy, which runs without a reviewed plan", ws.Name)
+		}
+		// Destroying in forward DAG order would pull resources out from
+		// under their dependents, and applying mid-teardown rebuilds them
+		if cfg.Destroy && containsOperation(ws.Operations, "apply") {
+			return fmt.Errorf("workspace %s: operation 'apply' cannot be used when destroy is set", ws.Name)
+		}
+		if !cfg.Destroy && containsOperation(ws.Operations, "destroy") {
+			return fmt.Errorf("workspace %s: operation 'destroy' requires destroy to be set so dependents are torn down first", ws.Name)
+		}
+	}
+
+	return nil
+}
+
+// validateDepth rejects configs whose longest dependency chain exceeds the
+// depth limit, naming the deepest workspace. Cycles must be ruled out first.
+func validateDepth(cfg InfrastructureConfig) error {
+	limit := cfg.MaxDepth
+	if limit == 0 {
+		limit = DefaultMaxDepth
+	}
+	depths := CalculateDepths(cfg.Workspaces)
+	deepest := ""
+	for _, ws := range cfg.Workspaces {
+		if deepest == "" || depths[ws.Name] > depths[deepest] {
+			deepest = ws.Name
+		}
+	}
+	if depths[deepest] > limit {
+		return fmt.Errorf("workspace %s sits %d dependencies deep, exceeding the limit of %d (flatten the chain or raise maxDepth)", deepest, depths[deepest], limit)
 	}
+	return nil
+}
 
+// validateOutputRemap checks that each outputRemap key names an output of a
+// configured workspace (a declared one, when the workspace declares outputs)
+// and that no two keys are renamed to the same name.
+func validateOutputRemap(remap map[string]string, index map[string]WorkspaceConfig) error {
+	keys := make([]string, 0, len(remap))
+	for key := range remap {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	renamedFrom := make(map[string]string, len(remap))
+	for _, key := range keys {
+		to := remap[key]
+		if strings.TrimSpace(to) == "" {
+			return fmt.Errorf("outputRemap %s has an empty name", key)
+		}
+		if other, ok := renamedFrom[to]; ok {
+			return fmt.Errorf("outputRemap renames both %s and %s to %s", other, key, to)
+		}
+		renamedFrom[to] = key
+
+		if !isWorkspaceOutputKey(key, index) {
+			return fmt.Errorf("outputRemap key %s must be workspace.output for a configured workspace and declared output", key)
+		}
+	}
 	return nil
 }
 
+// isWorkspaceOutputKey reports whether key splits into a configured
+// workspace and one of its outputs. Workspace names may contain dots, so
+// every split is tried.
+func isWorkspaceOutputKey(key string, index map[string]WorkspaceConfig) bool {
+	for i := strings.Index(key, "."); i >= 0; {
+		ws, ok := index[key[:i]]
+		output := key[i+1:]
+		if ok && output != "" && (len(ws.Outputs) == 0 || slices.Contains(ws.Outputs, output)) {
+			return true
+		}
+		next := strings.Index(key[i+1:], ".")
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return false
+}
+
 // ValidateWorkspaceOperations validates that the operations list for a workspace
 // is valid based on its kind (e.g., terraform requires init and validate).
 func ValidateWorkspaceOperations(ws WorkspaceConfig) error {
@@ -199,6 +773,51 @@ func ValidateWorkspaceOperations(ws WorkspaceConfig) error {
 		kind = "terraform"
 	}
 
+	if ws.DetectOnly && ws.CapturePlan {
+		return fmt.Errorf("workspace %s: capturePlan needs a saved plan file and cannot be combined with detectOnly", ws.Name)
+	}
+	if ws.DisableLock && !ws.DetectOnly && !ws.DriftCheck {
+		return fmt.Errorf("workspace %s: disableLock only applies to read-only detectOnly plans and drift checks; plans that are applied must lock the state", ws.Name)
+	}
+	if ws.DisableLock && ws.LockTimeout != "" {
+		return fmt.Errorf("workspace %s: lockTimeout has no effect with disableLock", ws.Name)
+	}
+	if ws.LegacyRefresh && !containsOperation(ws.Operations, "refresh") {
+		return fmt.Errorf("workspace %s: legacyRefresh needs the 'refresh' operation", ws.Name)
+	}
+	if ws.DisableLock && containsOperation(ws.Operations, "refresh") {
+		return fmt.Errorf("workspace %s: operation 'refresh' writes the state and cannot be combined with disableLock", ws.Name)
+	}
+	if ws.DriftCheck {
+		if ws.CapturePlan || ws.PersistPlan || len(ws.ProtectedResources) > 0 {
+			return fmt.Errorf("workspace %s: driftCheck saves no plan file and cannot be combined with capturePlan, persistPlan or protectedResources", ws.Name)
+		}
+		for _, op := range []string{"refresh", "apply", "destroy"} {
+			if containsOperation(ws.Operations, op) {
+				return fmt.Errorf("workspace %s: driftCheck only reports drift and cannot be combined with operation '%s'", ws.Name, op)
+			}
+		}
+		if len(ws.Operations) > 0 && !containsOperation(ws.Operations, "plan") {
+			return fmt.Errorf("workspace %s: driftCheck needs the 'plan' operation", ws.Name)
+		}
+	}
+	if ws.SkipInitIfInitialized && ws.CaptureInitInfo {
+		return fmt.Errorf("workspace %s: captureInitInfo reports what init installs and cannot be combined with skipInitIfInitialized", ws.Name)
+	}
+	if ws.DetectOnly && ws.PersistPlan {
+		return fmt.Errorf("workspace %s: persistPlan needs a saved plan file and cannot be combined with detectOnly", ws.Name)
+	}
+	if len(ws.ProtectedResources) > 0 {
+		if ws.DetectOnly {
+			return fmt.Errorf("workspace %s: protectedResources needs a saved plan file and cannot be combined with detectOnly", ws.Name)
+		}
+		for _, pattern := range ws.ProtectedResources {
+			if strings.TrimSpace(pattern) == "" {
+				return fmt.Errorf("workspace %s has an empty protectedResources entry", ws.Name)
+			}
+		}
+	}
+
 	// If no operations specified, use default based on kind
 	if len(ws.Operations) == 0 {
 		// Default is fine, will be handled by NormalizeInfrastructureConfig
@@ -207,7 +826,16 @@ func ValidateWorkspaceOperations(ws WorkspaceConfig) error {
 
 	switch kind {
 	case "terraform":
-		return validateTerraformOperations(ws.Name, ws.Operations)
+		if err := validateTerraformOperations(ws.Name, ws.Operations); err != nil {
+			return err
+		}
+		if ws.DetectOnly && containsOperation(ws.Operations, "apply") {
+			return fmt.Errorf("workspace %s: detectOnly cannot be combined with operation 'apply'", ws.Name)
+		}
+		if ws.DetectOnly && containsOperation(ws.Operations, "refresh") {
+			return fmt.Errorf("workspace %s: detectOnly cannot be combined with operation 'refresh', which writes the state", ws.Name)
+		}
+		return nil
 	default:
 		return fmt.Errorf("workspace %s: validation not implemented for kind %s", ws.Name, kind)
 	}
@@ -219,8 +847,10 @@ func validateTerraformOperations(name string, operations []string) error {
 	validOps := map[string]bool{
 		"init":     true,
 		"validate": true,
+		"refresh":  true,
 		"plan":     true,
 		"apply":    true,
+		"destroy":  true,
 	}
 
 	// Check for unknown operations
@@ -235,6 +865,7 @@ func validateTerraformOperations(name string, operations []string) error {
 	hasValidate := false
 	hasPlan := false
 	hasApply := false
+	hasDestroy := false
 
 	for _, op := range operations {
 		switch op {
@@ -246,6 +877,8 @@ func validateTerraformOperations(name string, operations []string) error {
 			hasPlan = true
 		case "apply":
 			hasApply = true
+		case "destroy":
+			hasDestroy = true
 		}
 	}
 
@@ -257,17 +890,21 @@ func validateTerraformOperations(name string, operations []string) error {
 	}
 
 	// Validate ordering constraints
-	initIdx, validateIdx, planIdx, applyIdx := -1, -1, -1, -1
+	initIdx, validateIdx, refreshIdx, planIdx, applyIdx, destroyIdx := -1, -1, -1, -1, -1, -1
 	for i, op := range operations {
 		switch op {
 		case "init":
 			initIdx = i
 		case "validate":
 			validateIdx = i
+		case "refresh":
+			refreshIdx = i
 		case "plan":
 			planIdx = i
 		case "apply":
 			applyIdx = i
+		case "destroy":
+			destroyIdx = i
 		}
 	}
 
@@ -276,6 +913,19 @@ func validateTerraformOperations(name string, operations []string) error {
 		return fmt.Errorf("workspace %s: operation 'validate' must come after 'init'", name)
 	}
 
+	// refresh reconciles the state that plan and destroy then read
+	if refreshIdx >= 0 {
+		if refreshIdx < initIdx {
+			return fmt.Errorf("workspace %s: operation 'refresh' must come after 'init'", name)
+		}
+		if hasPlan && refreshIdx > planIdx {
+			return fmt.Errorf("workspace %s: operation 'refresh' must come before 'plan'", name)
+		}
+		if hasDestroy && refreshIdx > destroyIdx {
+			return fmt.Errorf("workspace %s: operation 'refresh' must come before 'destroy'", name)
+		}
+	}
+
 	// plan must come after validate (if present)
 	if hasPlan && planIdx < validateIdx {
 		return fmt.Errorf("workspace %s: operation 'plan' must come after 'validate'", name)
@@ -291,9 +941,32 @@ func validateTerraformOperations(name string, operations []string) error {
 		}
 	}
 
+	// destroy tears down what apply builds, so the two never share a run
+	if hasDestroy {
+		if hasApply {
+			return fmt.Errorf("workspace %s: operation 'destroy' cannot be combined with 'apply'", name)
+		}
+		if destroyIdx < validateIdx {
+			return fmt.Errorf("workspace %s: operation 'destroy' must come after 'validate'", name)
+		}
+		if hasPlan && destroyIdx < planIdx {
+			return fmt.Errorf("workspace %s: operation 'destroy' must come after 'plan'", name)
+		}
+	}
+
 	return nil
 }
 
+// containsOperation reports whether op is present in operations.
+func containsOperation(operations []string, op string) bool {
+	for _, o := range operations {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
 // isTransitivelyDependent returns true if target depends on source (directly or transitively)
 func isTransitivelyDependent(target, source string, index map[string]WorkspaceConfig) bool {
 	ws, ok := index[target]
@@ -312,8 +985,20 @@ func isTransitivelyDependent(target, source string, index map[string]WorkspaceCo
 	return false
 }
 
+// orderingDependencies returns every workspace that must finish before ws
+// starts: its dependsOn and waitFor entries.
+func orderingDependencies(ws WorkspaceConfig) []string {
+	if len(ws.WaitFor) == 0 {
+		return ws.DependsOn
+	}
+	deps := make([]string, 0, len(ws.DependsOn)+len(ws.WaitFor))
+	deps = append(deps, ws.DependsOn...)
+	return append(deps, ws.WaitFor...)
+}
+
 // CalculateDepths returns a map of workspace names to their depth in the DAG.
 // Depth is defined as the length of the longest path from a root (no dependencies) to that node.
+// Both dependsOn and waitFor edges count.
 func CalculateDepths(workspaces []WorkspaceConfig) map[string]int {
 	index := make(map[string]WorkspaceConfig)
 	for _, ws := range workspaces {
@@ -327,14 +1012,14 @@ func CalculateDepths(workspaces []WorkspaceConfig) map[string]int {
 			return d
 		}
 
-		ws := index[name]
-		if len(ws.DependsOn) == 0 {
+		deps := orderingDependencies(index[name])
+		if len(deps) == 0 {
 			depths[name] = 0
 			return 0
 		}
 
 		maxDepDepth := -1
-		for _, dep := range ws.DependsOn {
+		for _, dep := range deps {
 			d := getDepth(dep)
 			if d > maxDepDepth {
 				maxDepDepth = d
@@ -351,6 +1036,27 @@ func CalculateDepths(workspaces []WorkspaceConfig) map[string]int {
 	return depths
 }
 
+// ExecutionLevels groups workspace names by DAG depth. Workspaces in the same
+// level have no dependencies on each other and can run in parallel; within a
+// level, names keep their config order.
+func ExecutionLevels(workspaces []WorkspaceConfig) [][]string {
+	depths := CalculateDepths(workspaces)
+
+	maxDepth := -1
+	for _, d := range depths {
+		if d > maxDepth {
+			maxDepth = d
+		}
+	}
+
+	levels := make([][]string, maxDepth+1)
+	for _, ws := range workspaces {
+		d := depths[ws.Name]
+		levels[d] = append(levels[d], ws.Name)
+	}
+	return levels
+}
+
 func isSupportedKind(kind string) bool {
 	switch kind {
 	case "", "terraform":
@@ -360,6 +1066,45 @@ func isSupportedKind(kind string) bool {
 	}
 }
 
+// resourceAddress roughly matches terraform resource addresses: any number of
+// module.NAME[KEY] steps followed by a module, a resource TYPE.NAME[KEY] or a
+// data.TYPE.NAME[KEY]. Keys are integers or quoted strings.
+var resourceAddress = regexp.MustCompile(
+	`^(module\.[A-Za-z_][\w-]*(\[(\d+|"[^"]*")\])?\.)*` +
+		`(module\.[A-Za-z_][\w-]*(\[(\d+|"[^"]*")\])?|(data\.)?[A-Za-z_][\w-]*\.[A-Za-z_][\w-]*(\[(\d+|"[^"]*")\])?)$`)
+
+// isResourceAddress reports whether addr looks like a -target address.
+func isResourceAddress(addr string) bool {
+	return resourceAddress.MatchString(addr)
+}
+
+// isSupportedVarType reports whether an input mapping type hint can be
+// coerced by the activities. An empty type disables coercion.
+func isSupportedVarType(typ string) bool {
+	switch typ {
+	case "", "string", "number", "bool":
+		return true
+	default:
+		return false
+	}
+}
+
+// inputVarTypes collects the type hints declared on input mappings,
+// keyed by target variable.
+func inputVarTypes(inputs []InputMapping) map[string]string {
+	var types map[string]string
+	for _, input := range inputs {
+		if input.Type == "" {
+			continue
+		}
+		if types == nil {
+			types = make(map[string]string)
+		}
+		types[input.TargetVar] = input.Type
+	}
+	return types
+}
+
 // getDefaultOperations returns the default operations list for a given kind.
 func getDefaultOperations(kind string) []string {
 	if kind == "" {
@@ -373,18 +1118,199 @@ func getDefaultOperations(kind string) []string {
 	}
 }
 
+// getDestroyOperations returns the default operations in destroy mode.
+func getDestroyOperations(kind string) []string {
+	if kind == "" {
+		kind = "terraform"
+	}
+	switch kind {
+	case "terraform":
+		return []string{"init", "validate", "destroy"}
+	default:
+		return []string{}
+	}
+}
+
+// isMutatingOperation reports whether op writes state or infrastructure:
+// refresh (apply -refresh-only), apply and destroy.
+func isMutatingOperation(op string) bool {
+	return op == "refresh" || op == "apply" || op == "destroy"
+}
+
+// hasMutatingOperation reports whether any of operations is mutating.
+func hasMutatingOperation(operations []string) bool {
+	for _, op := range operations {
+		if isMutatingOperation(op) {
+			return true
+		}
+	}
+	return false
+}
+
+// StripMutatingOperations returns a copy of a normalized config in which no
+// workspace refreshes, applies or destroys, for previews such as CI plan
+// jobs, along with the names of the workspaces that lost an operation. The
+// remaining operations keep their order, so the config stays valid.
+func StripMutatingOperations(cfg InfrastructureConfig) (InfrastructureConfig, []string) {
+	var downgraded []string
+	workspaces := make([]WorkspaceConfig, len(cfg.Workspaces))
+	for i, ws := range cfg.Workspaces {
+		ops := make([]string, 0, len(ws.Operations))
+		for _, op := range ws.Operations {
+			if !isMutatingOperation(op) {
+				ops = append(ops, op)
+			}
+		}
+		if len(ops) != len(ws.Operations) {
+			downgraded = append(downgraded, ws.Name)
+		}
+		ws.Operations = ops
+		ws.LegacyRefresh = false
+		workspaces[i] = ws
+	}
+	cfg.Workspaces = workspaces
+	return cfg, downgraded
+}
+
+// DriftCheckConfig returns a copy of a normalized config that only checks
+// for drift: every workspace sets driftCheck, keeps its operations up to and
+// including plan, and drops refresh, apply and destroy, along with the plan
+// options a drift check can't honour. It also returns the names of the
+// workspaces that lost an operation. A destroy config is checked in normal
+// DAG order.
+func DriftCheckConfig(cfg InfrastructureConfig) (InfrastructureConfig, []string) {
+	var downgraded []string
+	workspaces := make([]WorkspaceConfig, len(cfg.Workspaces))
+	for i, ws := range cfg.Workspaces {
+		ops := make([]string, 0, len(ws.Operations))
+		for _, op := range ws.Operations {
+			if !isMutatingOperation(op) {
+				ops = append(ops, op)
+			}
+		}
+		if len(ops) != len(ws.Operations) {
+			downgraded = append(downgraded, ws.Name)
+		}
+		if !containsOperation(ops, "plan") {
+			ops = append(ops, "plan")
+		}
+		ws.Operations = ops
+		ws.DriftCheck = true
+		ws.CapturePlan = false
+		ws.PersistPlan = false
+		ws.ProtectedResources = nil
+		ws.LegacyRefresh = false
+		workspaces[i] = ws
+	}
+	cfg.Workspaces = workspaces
+	cfg.Destroy = false
+	cfg.PlanPreview = nil
+	return cfg, downgraded
+}
+
+// ScheduledWorkspaces returns the workspaces as ParentWorkflow schedules them.
+// In destroy mode the edges are reversed: each workspace waits for its
+// dependents (through dependsOn or waitFor) instead of its dependencies. A
+// speculative plan drops every edge so all workspaces start at once. Both
+// modes drop input mappings.
+func ScheduledWorkspaces(cfg InfrastructureConfig) []WorkspaceConfig {
+	if cfg.SpeculativePlan {
+		independent := make([]WorkspaceConfig, len(cfg.Workspaces))
+		for i, ws := range cfg.Workspaces {
+			ws.DependsOn = nil
+			ws.WaitFor = nil
+			ws.Inputs = nil
+			independent[i] = ws
+		}
+		return independent
+	}
+	if !cfg.Destroy {
+		return cfg.Workspaces
+	}
+
+	dependents := make(map[string][]string)
+	for _, ws := range cfg.Workspaces {
+		for _, dep := range orderingDependencies(ws) {
+			dependents[dep] = append(dependents[dep], ws.Name)
+		}
+	}
+
+	reversed := make([]WorkspaceConfig, len(cfg.Workspaces))
+	for i, ws := range cfg.Workspaces {
+		ws.DependsOn = nil
+		ws.Inputs = nil
+		ws.WaitFor = dependents[ws.Name]
+		reversed[i] = ws
+	}
+	return reversed
+}
+
+// LoadOptions controls how LoadConfigFromFileWithOptions reads a file.
+type LoadOptions struct {
+	// Strict fails loading when the file references an unset environment
+	// variable, instead of expanding it to the empty string.
+	Strict bool
+}
+
 // LoadConfigFromFile reads and parses an infrastructure configuration file.
-// Supports both YAML and JSON formats based on file extension.
+// Supports both YAML and JSON formats based on file extension. Environment
+// variables are expanded first; see LoadConfigFromFileWithOptions.
 func LoadConfigFromFile(path string) (InfrastructureConfig, error) {
-	var config InfrastructureConfig
+	return LoadConfigFromFileWithOptions(path, LoadOptions{})
+}
 
+// LoadConfigFromFileWithOptions reads a config file, expanding $VAR and
+// ${VAR} from the environment before parsing, so a config can use e.g.
+// ${HOME}/infra. Values are inserted as-is, so they must be valid where they
+// appear (e.g. no unescaped quotes inside a JSON string). Write $$ for a
+// literal $. Unset variables expand to the empty string unless opts.Strict.
+func LoadConfigFromFileWithOptions(path string, opts LoadOptions) (InfrastructureConfig, error) {
 	body, err := os.ReadFile(path)
 	if err != nil {
-		return config, fmt.Errorf("failed to read config file: %v", err)
+		return InfrastructureConfig{}, fmt.Errorf("failed to read config file: %v", err)
 	}
+	body, err = expandEnv(body, opts.Strict)
+	if err != nil {
+		return InfrastructureConfig{}, err
+	}
+	return ParseConfig(body, filepath.Ext(path))
+}
 
-	ext := strings.ToLower(filepath.Ext(path))
-	switch ext {
+// envReference matches an escaped $$ or a $NAME / ${NAME} reference whose
+// NAME is a valid identifier.
+var envReference = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// expandEnv expands environment variables in a config document. $$ is a
+// literal $, and a $ not followed by a valid name (e.g. $5 or $-) is left
+// as is, unlike os.Expand, which reads those as shell special variables.
+func expandEnv(body []byte, strict bool) ([]byte, error) {
+	var unset []string
+	expanded := envReference.ReplaceAllStringFunc(string(body), func(ref string) string {
+		if ref == "$$" {
+			return "$"
+		}
+		name := strings.Trim(ref, "${}")
+		value, ok := os.LookupEnv(name)
+		if !ok && !slices.Contains(unset, name) {
+			unset = append(unset, name)
+		}
+		return value
+	})
+	if strict && len(unset) > 0 {
+		return nil, fmt.Errorf("config references unset environment variables: %s", strings.Join(unset, ", "))
+	}
+	return []byte(expanded), nil
+}
+
+// ParseConfig parses a config document. ext selects the format like a file
+// extension (".yaml", ".yml", ".json", or ".hcl" and ".tf" for HCL); anything
+// else is parsed as JSON.
+// Inline configs (e.g. from MCP) go through here as ".json" so they decode
+// exactly like config files.
+func ParseConfig(body []byte, ext string) (InfrastructureConfig, error) {
+	var config InfrastructureConfig
+
+	switch strings.ToLower(ext) {
 	case ".yaml", ".yml":
 		if err := yaml.Unmarshal(body, &config); err != nil {
 			return config, fmt.Errorf("invalid YAML config: %v", err)
@@ -393,6 +1319,8 @@ func LoadConfigFromFile(path string) (InfrastructureConfig, error) {
 		if err := json.Unmarshal(body, &config); err != nil {
 			return config, fmt.Errorf("invalid JSON config: %v", err)
 		}
+	case ".hcl", ".tf":
+		return parseHCLConfig(body, "config"+strings.ToLower(ext))
 	default:
 		// Try JSON as fallback for files without extension
 		if err := json.Unmarshal(body, &config); err != nil {
@@ -402,3 +1330,65 @@ func LoadConfigFromFile(path string) (InfrastructureConfig, error) {
 
 	return config, nil
 }
+
+// PrepareConfig validates a parsed config and, if valid, normalizes it.
+// Every entry point (CLI, MCP, workflows) uses this sequence so a config
+// behaves the same however it was supplied.
+func PrepareConfig(cfg InfrastructureConfig) (InfrastructureConfig, error) {
+	if err := ValidateInfrastructureConfig(cfg); err != nil {
+		return cfg, err
+	}
+	return NormalizeInfrastructureConfig(cfg), nil
+}
+
+// validateProviderPolicy rejects empty or malformed provider patterns.
+func validateProviderPolicy(policy *ProviderPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	for _, pattern := range append(append([]string{}, policy.Allow...), policy.Deny...) {
+		if strings.TrimSpace(pattern) == "" {
+			return errors.New("provider policy entries cannot be empty")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid provider pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// orchestrationTimeout parses cfg.Timeout, returning zero when unset.
+func orchestrationTimeout(cfg InfrastructureConfig) (time.Duration, error) {
+	if cfg.Timeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(cfg.Timeout)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q: must be a positive duration such as 90m", cfg.Timeout)
+	}
+	return d, nil
+}
+
+// shutdownGracePeriod parses cfg.ShutdownGracePeriod, returning zero when unset.
+func shutdownGracePeriod(cfg InfrastructureConfig) (time.Duration, error) {
+	if cfg.ShutdownGracePeriod == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(cfg.ShutdownGracePeriod)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid shutdownGracePeriod %q: must be a non-negative duration such as 30s", cfg.ShutdownGracePeriod)
+	}
+	return d, nil
+}
+
+// WorkflowExecutionTimeout returns the StartWorkflowOptions.WorkflowExecutionTimeout
+// for a validated config, or zero (no limit) when Timeout is unset. The
+// shutdown grace period comes on top of Timeout.
+func WorkflowExecutionTimeout(cfg InfrastructureConfig) time.Duration {
+	d, err := orchestrationTimeout(cfg)
+	if err != nil || d == 0 {
+		return 0
+	}
+	grace, _ := shutdownGracePeriod(cfg)
+	return d + grace + timeoutGracePeriod
+}
@@ -0,0 +1,102 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// parseHCLConfig parses a config written in HCL. Attributes use the JSON
+// field names, and each workspace is a block labeled with its name:
+//
+//	workspace_root = "/infra"
+//
+//	workspace "subnets" {
+//	  dir       = "terraform/subnets"
+//	  dependsOn = ["vpc"]
+//	  inputs    = [{ sourceWorkspace = "vpc", sourceOutput = "vpc_id", targetVar = "vpc_id" }]
+//	}
+//
+// Values must be literals; there are no variables or functions. The document
+// is converted to JSON and decoded like a JSON config, so every field is
+// supported without a separate schema.
+func parseHCLConfig(body []byte, filename string) (InfrastructureConfig, error) {
+	var config InfrastructureConfig
+
+	file, diags := hclsyntax.ParseConfig(body, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return config, fmt.Errorf("invalid HCL config: %v", diags.Error())
+	}
+	root := file.Body.(*hclsyntax.Body)
+
+	doc, err := hclAttributes(root.Attributes)
+	if err != nil {
+		return config, err
+	}
+	var workspaces []json.RawMessage
+	for _, block := range root.Blocks {
+		if block.Type != "workspace" {
+			return config, fmt.Errorf("invalid HCL config: %s: unexpected %q block (only workspace blocks are allowed)", block.DefRange(), block.Type)
+		}
+		if len(block.Labels) != 1 {
+			return config, fmt.Errorf("invalid HCL config: %s: workspace block needs exactly one label, its name", block.DefRange())
+		}
+		if len(block.Body.Blocks) > 0 {
+			nested := block.Body.Blocks[0]
+			return config, fmt.Errorf("invalid HCL config: %s: unexpected %q block in workspace %s (use an attribute, e.g. %s = { ... })", nested.DefRange(), nested.Type, block.Labels[0], nested.Type)
+		}
+		if _, ok := block.Body.Attributes["name"]; ok {
+			return config, fmt.Errorf("invalid HCL config: %s: workspace %s sets name; the block label is its name", block.DefRange(), block.Labels[0])
+		}
+
+		ws, err := hclAttributes(block.Body.Attributes)
+		if err != nil {
+			return config, err
+		}
+		ws["name"], _ = json.Marshal(block.Labels[0])
+		encoded, err := json.Marshal(ws)
+		if err != nil {
+			return config, fmt.Errorf("invalid HCL config: %v", err)
+		}
+		workspaces = append(workspaces, encoded)
+	}
+	if len(workspaces) > 0 {
+		if _, ok := doc["workspaces"]; ok {
+			return config, fmt.Errorf("invalid HCL config: use either workspace blocks or a workspaces attribute, not both")
+		}
+		doc["workspaces"], _ = json.Marshal(workspaces)
+	}
+
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return config, fmt.Errorf("invalid HCL config: %v", err)
+	}
+	if err := json.Unmarshal(encoded, &config); err != nil {
+		return config, fmt.Errorf("invalid HCL config: %v", err)
+	}
+	return config, nil
+}
+
+// hclAttributes evaluates literal attributes and encodes each value as JSON.
+func hclAttributes(attrs hclsyntax.Attributes) (map[string]json.RawMessage, error) {
+	values := make(map[string]json.RawMessage, len(attrs))
+	for name, attr := range attrs {
+		value, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("invalid HCL config: %v", diags.Error())
+		}
+		if value.IsNull() {
+			values[name] = json.RawMessage("null")
+			continue
+		}
+		encoded, err := ctyjson.Marshal(value, value.Type())
+		if err != nil {
+			return nil, fmt.Errorf("invalid HCL config: %s: %v", attr.SrcRange, err)
+		}
+		values[name] = encoded
+	}
+	return values, nil
+}
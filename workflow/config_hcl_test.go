@@ -0,0 +1,103 @@
+package workflow
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigFromFile_HCLMatchesYAML(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	yamlPath := filepath.Join(tmpDir, "config.yaml")
+	require.NoError(t, os.WriteFile(yamlPath, []byte(`workspace_root: /test
+workspaces:
+  - name: vpc
+    dir: terraform/vpc
+    kind: terraform
+  - name: subnets
+    dir: terraform/subnets
+    dependsOn:
+      - vpc
+`), 0o644))
+
+	hclPath := filepath.Join(tmpDir, "config.hcl")
+	require.NoError(t, os.WriteFile(hclPath, []byte(`workspace_root = "/test"
+
+workspace "vpc" {
+  dir  = "terraform/vpc"
+  kind = "terraform"
+}
+
+workspace "subnets" {
+  dir       = "terraform/subnets"
+  dependsOn = ["vpc"]
+}
+`), 0o644))
+
+	fromYAML, err := LoadConfigFromFile(yamlPath)
+	require.NoError(t, err)
+	fromHCL, err := LoadConfigFromFile(hclPath)
+	require.NoError(t, err)
+	require.Equal(t, fromYAML, fromHCL)
+	require.Equal(t, []string{"vpc"}, fromHCL.Workspaces[1].DependsOn)
+}
+
+func TestParseConfig_HCLNestedValues(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`
+maxConcurrency = 2
+providers = {
+  allow = ["hashicorp/*"]
+}
+
+workspace "vpc" {
+  dir  = "vpc"
+  vars = { cidr = "10.0.0.0/16", azs = 3, tags = { team = "net" } }
+}
+
+workspace "eks" {
+  dir       = "eks"
+  dependsOn = ["vpc"]
+  inputs = [
+    { sourceWorkspace = "vpc", sourceOutput = "vpc_id", targetVar = "vpc_id" },
+  ]
+}
+`), ".tf")
+	require.NoError(t, err)
+	require.Equal(t, 2, cfg.MaxConcurrency)
+	require.Equal(t, []string{"hashicorp/*"}, cfg.Providers.Allow)
+	require.Equal(t, map[string]interface{}{
+		"cidr": "10.0.0.0/16",
+		"azs":  float64(3),
+		"tags": map[string]interface{}{"team": "net"},
+	}, cfg.Workspaces[0].Vars)
+	require.Equal(t, []InputMapping{{SourceWorkspace: "vpc", SourceOutput: "vpc_id", TargetVar: "vpc_id"}}, cfg.Workspaces[1].Inputs)
+	require.NoError(t, ValidateInfrastructureConfig(cfg))
+}
+
+func TestParseConfig_HCLErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"syntax", `workspace "vpc" {`, "invalid HCL config"},
+		{"unknown block", `module "vpc" {}`, `unexpected "module" block`},
+		{"missing label", `workspace { dir = "vpc" }`, "workspace block needs exactly one label"},
+		{"nested block", "workspace \"vpc\" {\n  providers {\n    allow = []\n  }\n}\n", `use an attribute, e.g. providers = { ... }`},
+		{"name attribute", `workspace "vpc" { name = "other" }`, "the block label is its name"},
+		{"variable reference", `workspace_root = var.root`, "Variables not allowed"},
+		{"both forms", "workspaces = []\nworkspace \"vpc\" {}\n", "either workspace blocks or a workspaces attribute"},
+		{"wrong type", `maxDepth = "deep"`, "invalid HCL config"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(tt.body), ".hcl")
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
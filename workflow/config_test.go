@@ -1,8 +1,11 @@
 package workflow
 
 import (
+	"fmt"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -109,6 +112,83 @@ func TestValidateInfrastructureConfig(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "invalid input mapping - output not declared by source",
+			cfg: InfrastructureConfig{
+				Workspaces: []WorkspaceConfig{
+					{Name: "a", Dir: "/tmp/a", Outputs: []string{"vpc_id"}},
+					{
+						Name:      "b",
+						Dir:       "/tmp/b",
+						DependsOn: []string{"a"},
+						Inputs: []InputMapping{
+							{SourceWorkspace: "a", SourceOutput: "vcp_id", TargetVar: "vpc_id"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid input mapping - output declared by source",
+			cfg: InfrastructureConfig{
+				Workspaces: []WorkspaceConfig{
+					{Name: "a", Dir: "/tmp/a", Outputs: []string{"vpc_id", "subnet_ids"}},
+					{
+						Name:      "b",
+						Dir:       "/tmp/b",
+						DependsOn: []string{"a"},
+						Inputs: []InputMapping{
+							{SourceWorkspace: "a", SourceOutput: "vpc_id", TargetVar: "vpc_id"},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "empty output declaration",
+			cfg: InfrastructureConfig{
+				Workspaces: []WorkspaceConfig{
+					{Name: "a", Dir: "/tmp/a", Outputs: []string{""}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid input mapping - unsupported type hint",
+			cfg: InfrastructureConfig{
+				Workspaces: []WorkspaceConfig{
+					{Name: "a", Dir: "/tmp/a"},
+					{
+						Name:      "b",
+						Dir:       "/tmp/b",
+						DependsOn: []string{"a"},
+						Inputs: []InputMapping{
+							{SourceWorkspace: "a", SourceOutput: "port", TargetVar: "port", Type: "list"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid input mapping with type hint",
+			cfg: InfrastructureConfig{
+				Workspaces: []WorkspaceConfig{
+					{Name: "a", Dir: "/tmp/a"},
+					{
+						Name:      "b",
+						Dir:       "/tmp/b",
+						DependsOn: []string{"a"},
+						Inputs: []InputMapping{
+							{SourceWorkspace: "a", SourceOutput: "port", TargetVar: "port", Type: "number"},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name: "valid transitive input mapping",
 			cfg: InfrastructureConfig{
@@ -171,6 +251,23 @@ func TestCalculateDepths(t *testing.T) {
 	assert.Equal(t, 3, depths["app"])
 }
 
+func TestExecutionLevels(t *testing.T) {
+	workspaces := []WorkspaceConfig{
+		{Name: "vpc"},
+		{Name: "vpc-2"},
+		{Name: "subnets", DependsOn: []string{"vpc"}},
+		{Name: "eks", DependsOn: []string{"vpc", "subnets"}},
+		{Name: "db", DependsOn: []string{"vpc"}},
+	}
+
+	levels := ExecutionLevels(workspaces)
+	assert.Equal(t, [][]string{
+		{"vpc", "vpc-2"},
+		{"subnets", "db"},
+		{"eks"},
+	}, levels)
+}
+
 func TestLoadConfigFromFile_YAML(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := tmpDir + "/config.yaml"
@@ -313,6 +410,108 @@ func TestLoadConfigFromFile_NoExtensionDefaultsToJSON(t *testing.T) {
 	assert.Equal(t, 1, len(cfg.Workspaces))
 }
 
+func TestLoadConfigFromFile_ExpandsEnvYAML(t *testing.T) {
+	t.Setenv("INFRA_HOME", "/home/ci")
+	t.Setenv("ENV_NAME", "staging")
+	tmpDir := t.TempDir()
+	configPath := tmpDir + "/config.yaml"
+
+	yamlContent := `workspace_root: ${INFRA_HOME}/infra
+workspaces:
+  - name: vpc
+    dir: vpc-$ENV_NAME
+    vars:
+      price: $$5
+      template: "$${name}"
+      missing: "${INFRA_UNSET_VAR}"
+`
+	err := os.WriteFile(configPath, []byte(yamlContent), 0o644)
+	assert.NoError(t, err)
+
+	cfg, err := LoadConfigFromFile(configPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "/home/ci/infra", cfg.WorkspaceRoot)
+	assert.Equal(t, "vpc-staging", cfg.Workspaces[0].Dir)
+	assert.Equal(t, "$5", cfg.Workspaces[0].Vars["price"])
+	assert.Equal(t, "${name}", cfg.Workspaces[0].Vars["template"])
+	assert.Equal(t, "", cfg.Workspaces[0].Vars["missing"])
+}
+
+func TestLoadConfigFromFile_ExpandsEnvJSON(t *testing.T) {
+	t.Setenv("INFRA_HOME", "/home/ci")
+	tmpDir := t.TempDir()
+	configPath := tmpDir + "/config.json"
+
+	jsonContent := `{
+  "workspace_root": "${INFRA_HOME}/infra",
+  "workspaces": [
+    {"name": "vpc", "dir": "vpc", "tfvars": "$INFRA_HOME/vpc.tfvars"}
+  ]
+}`
+	err := os.WriteFile(configPath, []byte(jsonContent), 0o644)
+	assert.NoError(t, err)
+
+	cfg, err := LoadConfigFromFile(configPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "/home/ci/infra", cfg.WorkspaceRoot)
+	assert.Equal(t, "/home/ci/vpc.tfvars", cfg.Workspaces[0].TFVars)
+}
+
+func TestLoadConfigFromFileWithOptions_StrictUnsetEnv(t *testing.T) {
+	t.Setenv("INFRA_HOME", "/home/ci")
+	t.Setenv("INFRA_EMPTY", "")
+	tmpDir := t.TempDir()
+	configPath := tmpDir + "/config.yaml"
+
+	yamlContent := `workspace_root: ${INFRA_HOME}/infra$INFRA_EMPTY
+workspaces:
+  - name: vpc
+    dir: ${INFRA_UNSET_A}/vpc
+    tfvars: $INFRA_UNSET_B.tfvars
+    vars:
+      again: ${INFRA_UNSET_A}
+      literal: $$INFRA_UNSET_C
+`
+	err := os.WriteFile(configPath, []byte(yamlContent), 0o644)
+	assert.NoError(t, err)
+
+	_, err = LoadConfigFromFileWithOptions(configPath, LoadOptions{Strict: true})
+	assert.Error(t, err)
+	assert.Equal(t, "config references unset environment variables: INFRA_UNSET_A, INFRA_UNSET_B", err.Error())
+
+	// Without strict, unset variables are empty
+	cfg, err := LoadConfigFromFileWithOptions(configPath, LoadOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "/vpc", cfg.Workspaces[0].Dir)
+	assert.Equal(t, "$INFRA_UNSET_C", cfg.Workspaces[0].Vars["literal"])
+}
+
+func TestExpandEnv_LeavesNonIdentifiersAlone(t *testing.T) {
+	t.Setenv("INFRA_HOME", "/home/ci")
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "digit", in: "price: $5", want: "price: $5"},
+		{name: "braced digit", in: "price: ${5}", want: "price: ${5}"},
+		{name: "shell special", in: "flags: $- $@ $* $# $? $!", want: "flags: $- $@ $* $# $? $!"},
+		{name: "trailing dollar", in: "cost: 5$", want: "cost: 5$"},
+		{name: "terraform expression", in: "name: ${var.env}-vpc", want: "name: ${var.env}-vpc"},
+		{name: "unclosed brace", in: "dir: ${INFRA_HOME", want: "dir: ${INFRA_HOME"},
+		{name: "next to a reference", in: "dir: ${INFRA_HOME}/$1", want: "dir: /home/ci/$1"},
+		{name: "escaped", in: "price: $$INFRA_HOME", want: "price: $INFRA_HOME"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandEnv([]byte(tt.in), true)
+			assert.NoError(t, err, "strict mode must not report non-identifiers as unset")
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
 func TestValidateWorkspaceOperations(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -340,6 +539,55 @@ func TestValidateWorkspaceOperations(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "detect only with plan",
+			ws: WorkspaceConfig{
+				Name:       "test",
+				Kind:       "terraform",
+				Dir:        "/tmp/test",
+				Operations: []string{"init", "validate", "plan"},
+				DetectOnly: true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "detect only with apply",
+			ws: WorkspaceConfig{
+				Name:       "test",
+				Kind:       "terraform",
+				Dir:        "/tmp/test",
+				Operations: []string{"init", "validate", "plan", "apply"},
+				DetectOnly: true,
+			},
+			wantErr: true,
+			errMsg:  "detectOnly cannot be combined with operation 'apply'",
+		},
+		{
+			name: "capture plan with detect only",
+			ws: WorkspaceConfig{
+				Name:        "test",
+				Kind:        "terraform",
+				Dir:         "/tmp/test",
+				Operations:  []string{"init", "validate", "plan"},
+				DetectOnly:  true,
+				CapturePlan: true,
+			},
+			wantErr: true,
+			errMsg:  "capturePlan needs a saved plan file",
+		},
+		{
+			name: "persist plan with detect only",
+			ws: WorkspaceConfig{
+				Name:        "test",
+				Kind:        "terraform",
+				Dir:         "/tmp/test",
+				Operations:  []string{"init", "validate", "plan"},
+				DetectOnly:  true,
+				PersistPlan: true,
+			},
+			wantErr: true,
+			errMsg:  "persistPlan needs a saved plan file",
+		},
 		{
 			name: "missing init",
 			ws: WorkspaceConfig{
@@ -368,10 +616,42 @@ func TestValidateWorkspaceOperations(t *testing.T) {
 				Name:       "test",
 				Kind:       "terraform",
 				Dir:        "/tmp/test",
-				Operations: []string{"init", "validate", "plan", "destroy"},
+				Operations: []string{"init", "validate", "plan", "taint"},
+			},
+			wantErr: true,
+			errMsg:  "unknown operation 'taint'",
+		},
+		{
+			name: "valid operations - destroy",
+			ws: WorkspaceConfig{
+				Name:       "test",
+				Kind:       "terraform",
+				Dir:        "/tmp/test",
+				Operations: []string{"init", "validate", "destroy"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "destroy with apply",
+			ws: WorkspaceConfig{
+				Name:       "test",
+				Kind:       "terraform",
+				Dir:        "/tmp/test",
+				Operations: []string{"init", "validate", "plan", "apply", "destroy"},
+			},
+			wantErr: true,
+			errMsg:  "'destroy' cannot be combined with 'apply'",
+		},
+		{
+			name: "wrong order - destroy before validate",
+			ws: WorkspaceConfig{
+				Name:       "test",
+				Kind:       "terraform",
+				Dir:        "/tmp/test",
+				Operations: []string{"init", "destroy", "validate"},
 			},
 			wantErr: true,
-			errMsg:  "unknown operation 'destroy'",
+			errMsg:  "operation 'destroy' must come after 'validate'",
 		},
 		{
 			name: "wrong order - validate before init",
@@ -462,6 +742,159 @@ func TestNormalizeInfrastructureConfig_DefaultOperations(t *testing.T) {
 	assert.Equal(t, []string{"init", "validate", "plan"}, got.Workspaces[1].Operations)
 }
 
+func TestNormalizeInfrastructureConfig_DetectOnlyDefaultOperations(t *testing.T) {
+	cfg := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "a", Dir: "/tmp/a", DetectOnly: true},
+		},
+	}
+
+	got := NormalizeInfrastructureConfig(cfg)
+	assert.Equal(t, []string{"init", "validate", "plan"}, got.Workspaces[0].Operations)
+}
+
+func TestNormalizeInfrastructureConfig_DestroyDefaultOperations(t *testing.T) {
+	cfg := InfrastructureConfig{
+		Destroy: true,
+		Workspaces: []WorkspaceConfig{
+			{Name: "a", Dir: "/tmp/a"},
+		},
+	}
+
+	got := NormalizeInfrastructureConfig(cfg)
+	assert.Equal(t, []string{"init", "validate", "destroy"}, got.Workspaces[0].Operations)
+}
+
+func TestValidateInfrastructureConfig_DestroyMode(t *testing.T) {
+	apply := InfrastructureConfig{
+		Destroy: true,
+		Workspaces: []WorkspaceConfig{
+			{Name: "a", Dir: "/tmp/a", Operations: []string{"init", "validate", "plan", "apply"}},
+		},
+	}
+	err := ValidateInfrastructureConfig(apply)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "operation 'apply' cannot be used when destroy is set")
+
+	forward := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "a", Dir: "/tmp/a", Operations: []string{"init", "validate", "destroy"}},
+		},
+	}
+	err = ValidateInfrastructureConfig(forward)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "requires destroy to be set")
+
+	forward.Destroy = true
+	assert.NoError(t, ValidateInfrastructureConfig(forward))
+}
+
+func TestStripMutatingOperations(t *testing.T) {
+	cfg, err := PrepareConfig(InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "subnets", Dir: "/tmp/subnets", DependsOn: []string{"vpc"}, Operations: []string{"init", "validate", "plan"}},
+			{Name: "eks", Dir: "/tmp/eks", DependsOn: []string{"subnets"}, Operations: []string{"init", "validate", "plan", "apply"}},
+		},
+	})
+	assert.NoError(t, err)
+
+	stripped, downgraded := StripMutatingOperations(cfg)
+
+	assert.Equal(t, []string{"vpc", "eks"}, downgraded)
+	assert.Equal(t, []string{"init", "validate", "plan"}, stripped.Workspaces[0].Operations)
+	assert.Equal(t, []string{"init", "validate", "plan"}, stripped.Workspaces[1].Operations)
+	assert.Equal(t, []string{"init", "validate", "plan"}, stripped.Workspaces[2].Operations)
+	assert.NoError(t, ValidateInfrastructureConfig(stripped))
+
+	// The original config is left untouched
+	assert.Equal(t, []string{"init", "validate", "plan", "apply"}, cfg.Workspaces[0].Operations)
+	assert.Equal(t, []string{"init", "validate", "plan", "apply"}, cfg.Workspaces[2].Operations)
+}
+
+func TestStripMutatingOperations_Destroy(t *testing.T) {
+	cfg, err := PrepareConfig(InfrastructureConfig{
+		Destroy: true,
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "subnets", Dir: "/tmp/subnets", DependsOn: []string{"vpc"}, Operations: []string{"init", "validate", "plan", "destroy"}},
+		},
+	})
+	assert.NoError(t, err)
+
+	stripped, downgraded := StripMutatingOperations(cfg)
+
+	assert.Equal(t, []string{"vpc", "subnets"}, downgraded)
+	assert.Equal(t, []string{"init", "validate"}, stripped.Workspaces[0].Operations)
+	assert.Equal(t, []string{"init", "validate", "plan"}, stripped.Workspaces[1].Operations)
+	assert.NoError(t, ValidateInfrastructureConfig(stripped))
+}
+
+func TestScheduledWorkspaces_DestroyReversesEdges(t *testing.T) {
+	cfg := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "subnets", Dir: "/tmp/subnets", DependsOn: []string{"vpc"}, Inputs: []InputMapping{
+				{SourceWorkspace: "vpc", SourceOutput: "vpc_id", TargetVar: "vpc_id"},
+			}},
+			{Name: "dns", Dir: "/tmp/dns", WaitFor: []string{"vpc"}},
+		},
+	}
+	assert.Equal(t, cfg.Workspaces, ScheduledWorkspaces(cfg))
+
+	cfg.Destroy = true
+	got := ScheduledWorkspaces(cfg)
+	assert.Equal(t, []string{"subnets", "dns"}, got[0].WaitFor)
+	for _, ws := range got {
+		assert.Empty(t, ws.DependsOn)
+		assert.Empty(t, ws.Inputs)
+	}
+	assert.Equal(t, [][]string{{"subnets", "dns"}, {"vpc"}}, ExecutionLevels(got))
+
+	// The caller's config is left as it was
+	assert.Equal(t, []string{"vpc"}, cfg.Workspaces[1].DependsOn)
+}
+
+func TestValidateInfrastructureConfig_SpeculativePlan(t *testing.T) {
+	cfg := InfrastructureConfig{
+		SpeculativePlan: true,
+		Workspaces: []WorkspaceConfig{
+			{Name: "a", Dir: "/tmp/a", Operations: []string{"init", "validate", "plan", "apply"}},
+		},
+	}
+	err := ValidateInfrastructureConfig(cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "speculativePlan only plans")
+
+	cfg.Workspaces[0].Operations = nil
+	assert.NoError(t, ValidateInfrastructureConfig(cfg))
+	assert.Equal(t, []string{"init", "validate", "plan"}, NormalizeInfrastructureConfig(cfg).Workspaces[0].Operations)
+
+	cfg.Destroy = true
+	err = ValidateInfrastructureConfig(cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "destroy and speculativePlan cannot be combined")
+}
+
+func TestScheduledWorkspaces_SpeculativePlanDropsEdges(t *testing.T) {
+	cfg := InfrastructureConfig{
+		SpeculativePlan: true,
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "subnets", Dir: "/tmp/subnets", DependsOn: []string{"vpc"}, Inputs: []InputMapping{
+				{SourceWorkspace: "vpc", SourceOutput: "vpc_id", TargetVar: "vpc_id"},
+			}},
+			{Name: "dns", Dir: "/tmp/dns", WaitFor: []string{"subnets"}},
+		},
+	}
+
+	got := ScheduledWorkspaces(cfg)
+	assert.Equal(t, [][]string{{"vpc", "subnets", "dns"}}, ExecutionLevels(got))
+	for _, ws := range got {
+		assert.Empty(t, ws.Inputs)
+	}
+}
+
 func TestValidateInfrastructureConfig_WithOperations(t *testing.T) {
 	// Valid config with operations
 	validCfg := InfrastructureConfig{
@@ -507,6 +940,15 @@ func TestGetDefaultOperations_UnknownKind(t *testing.T) {
 	assert.Empty(t, ops)
 }
 
+func TestNormalizeInfrastructureConfig_PlanOnlyDefaultsWithoutApply(t *testing.T) {
+	// Kinds without default operations have no apply to drop
+	cfg := NormalizeInfrastructureConfig(InfrastructureConfig{
+		SpeculativePlan: true,
+		Workspaces:      []WorkspaceConfig{{Name: "chart", Dir: "/tmp/chart", Kind: "helm"}},
+	})
+	assert.Empty(t, cfg.Workspaces[0].Operations)
+}
+
 func TestNormalizeInfrastructureConfig_AbsolutePaths(t *testing.T) {
 	cfg := InfrastructureConfig{
 		WorkspaceRoot: "/root",
@@ -525,16 +967,31 @@ func TestNormalizeInfrastructureConfig_AbsolutePaths(t *testing.T) {
 	assert.Equal(t, "/absolute/path/vpc.tfvars", got.Workspaces[0].TFVars)
 }
 
-func TestValidateInfrastructureConfig_EmptyWorkspaceName(t *testing.T) {
+func TestNormalizeInfrastructureConfig_TempRootInheritance(t *testing.T) {
 	cfg := InfrastructureConfig{
+		WorkspaceRoot: "/root",
+		TempRoot:      "/mnt/scratch",
 		Workspaces: []WorkspaceConfig{
-			{Name: "", Dir: "/tmp/test"},
+			{Name: "a", Dir: "a"},
+			{Name: "b", Dir: "b", TempRoot: "tmp"},
 		},
 	}
 
-	err := ValidateInfrastructureConfig(cfg)
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "name cannot be empty")
+	got := NormalizeInfrastructureConfig(cfg)
+	assert.Equal(t, "/mnt/scratch", got.Workspaces[0].TempRoot)
+	assert.Equal(t, "/root/tmp", got.Workspaces[1].TempRoot)
+}
+
+func TestValidateInfrastructureConfig_EmptyWorkspaceName(t *testing.T) {
+	cfg := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "", Dir: "/tmp/test"},
+		},
+	}
+
+	err := ValidateInfrastructureConfig(cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "name cannot be empty")
 }
 
 func TestValidateInfrastructureConfig_MissingDir(t *testing.T) {
@@ -561,3 +1018,872 @@ func TestValidateInfrastructureConfig_SelfDependency(t *testing.T) {
 	// Self-dependency is detected as a cycle
 	assert.Contains(t, err.Error(), "cycle")
 }
+
+func TestNormalizeInfrastructureConfig_ProviderPolicyInheritance(t *testing.T) {
+	global := &ProviderPolicy{Allow: []string{"hashicorp/*"}}
+	own := &ProviderPolicy{Deny: []string{"hashicorp/random"}}
+	cfg := InfrastructureConfig{
+		Providers: global,
+		Workspaces: []WorkspaceConfig{
+			{Name: "a", Dir: "/tmp/a"},
+			{Name: "b", Dir: "/tmp/b", Providers: own},
+		},
+	}
+
+	got := NormalizeInfrastructureConfig(cfg)
+	assert.Equal(t, global, got.Workspaces[0].Providers)
+	assert.Equal(t, own, got.Workspaces[1].Providers)
+}
+
+func TestValidateInfrastructureConfig_ProviderPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		global    *ProviderPolicy
+		workspace *ProviderPolicy
+		errMsg    string
+	}{
+		{name: "no policy"},
+		{name: "valid patterns", global: &ProviderPolicy{Allow: []string{"hashicorp/*"}, Deny: []string{"registry.terraform.io/hashicorp/random"}}},
+		{name: "empty entry", workspace: &ProviderPolicy{Allow: []string{" "}}, errMsg: "workspace a: provider policy entries cannot be empty"},
+		{name: "malformed pattern", global: &ProviderPolicy{Deny: []string{"hashicorp/[aws"}}, errMsg: "invalid provider pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := InfrastructureConfig{
+				Providers: tt.global,
+				Workspaces: []WorkspaceConfig{
+					{Name: "a", Dir: "/tmp/a", Providers: tt.workspace},
+				},
+			}
+
+			err := ValidateInfrastructureConfig(cfg)
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestCheckWorkspaceLimit(t *testing.T) {
+	workspaces := func(n int) []WorkspaceConfig {
+		out := make([]WorkspaceConfig, n)
+		for i := range out {
+			out[i] = WorkspaceConfig{Name: fmt.Sprintf("ws-%d", i), Dir: "/tmp/ws"}
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		count  int
+		limit  int
+		errMsg string
+	}{
+		{name: "at default limit", count: DefaultMaxWorkspaces},
+		{name: "over default limit", count: DefaultMaxWorkspaces + 1, errMsg: "config defines 501 workspaces, exceeding the limit of 500"},
+		{name: "under custom limit", count: 3, limit: 5},
+		{name: "over custom limit", count: 6, limit: 5, errMsg: "exceeding the limit of 5"},
+		{name: "custom limit above default", count: DefaultMaxWorkspaces + 1, limit: 1000},
+		{name: "negative limit uses default", count: DefaultMaxWorkspaces + 1, limit: -1, errMsg: "exceeding the limit of 500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := InfrastructureConfig{Workspaces: workspaces(tt.count)}
+
+			// The workflow doesn't enforce the limit; only clients do
+			assert.NoError(t, ValidateInfrastructureConfig(cfg))
+
+			err := CheckWorkspaceLimit(cfg, tt.limit)
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestValidateInfrastructureConfig_Timeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout string
+		wantErr bool
+	}{
+		{name: "unset", timeout: ""},
+		{name: "valid", timeout: "90m"},
+		{name: "unparseable", timeout: "soon", wantErr: true},
+		{name: "zero", timeout: "0s", wantErr: true},
+		{name: "negative", timeout: "-5m", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := InfrastructureConfig{
+				Timeout:    tt.timeout,
+				Workspaces: []WorkspaceConfig{{Name: "a", Dir: "/tmp/a"}},
+			}
+
+			err := ValidateInfrastructureConfig(cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "invalid timeout")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWorkflowExecutionTimeout(t *testing.T) {
+	assert.Equal(t, time.Duration(0), WorkflowExecutionTimeout(InfrastructureConfig{}))
+	assert.Equal(t, 2*time.Hour+timeoutGracePeriod, WorkflowExecutionTimeout(InfrastructureConfig{Timeout: "2h"}))
+	assert.Equal(t, 2*time.Hour+time.Minute+timeoutGracePeriod, WorkflowExecutionTimeout(InfrastructureConfig{Timeout: "2h", ShutdownGracePeriod: "1m"}))
+}
+
+func TestValidateInfrastructureConfig_ShutdownGracePeriod(t *testing.T) {
+	tests := []struct {
+		name    string
+		grace   string
+		wantErr bool
+	}{
+		{name: "unset", grace: ""},
+		{name: "valid", grace: "30s"},
+		{name: "zero", grace: "0s"},
+		{name: "unparseable", grace: "later", wantErr: true},
+		{name: "negative", grace: "-1s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := InfrastructureConfig{
+				ShutdownGracePeriod: tt.grace,
+				Workspaces:          []WorkspaceConfig{{Name: "a", Dir: "/tmp/a"}},
+			}
+
+			err := ValidateInfrastructureConfig(cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "invalid shutdownGracePeriod")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNormalizeInfrastructureConfig_OutputLogDir(t *testing.T) {
+	cfg := InfrastructureConfig{
+		WorkspaceRoot: "/root",
+		Workspaces: []WorkspaceConfig{
+			{Name: "a", Dir: "a", OutputLogDir: "logs/a"},
+			{Name: "b", Dir: "b", OutputLogDir: "/var/log/tf"},
+		},
+	}
+
+	got := NormalizeInfrastructureConfig(cfg)
+	assert.Equal(t, "/root/logs/a", got.Workspaces[0].OutputLogDir)
+	assert.Equal(t, "/var/log/tf", got.Workspaces[1].OutputLogDir)
+}
+
+func TestValidateInfrastructureConfig_NegativeMaxOutputBytes(t *testing.T) {
+	cfg := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{{Name: "a", Dir: "/tmp/a", MaxOutputBytes: -1}},
+	}
+
+	err := ValidateInfrastructureConfig(cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "maxOutputBytes cannot be negative")
+}
+
+func TestValidateInfrastructureConfig_LockTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		lockTimeout string
+		wantErr     bool
+	}{
+		{"unset", "", false},
+		{"seconds", "30s", false},
+		{"zero", "0s", false},
+		{"negative", "-5s", true},
+		{"not a duration", "soon", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := InfrastructureConfig{
+				Workspaces: []WorkspaceConfig{{Name: "a", Dir: "/tmp/a", LockTimeout: tt.lockTimeout}},
+			}
+			err := ValidateInfrastructureConfig(cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "invalid lockTimeout")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateInfrastructureConfig_DisableLock(t *testing.T) {
+	tests := []struct {
+		name    string
+		ws      WorkspaceConfig
+		wantErr string
+	}{
+		{"detect only", WorkspaceConfig{Name: "a", Dir: "/tmp/a", DetectOnly: true, DisableLock: true}, ""},
+		{"drift check", WorkspaceConfig{Name: "a", Dir: "/tmp/a", DriftCheck: true, DisableLock: true}, ""},
+		{"applied plan", WorkspaceConfig{Name: "a", Dir: "/tmp/a", DisableLock: true}, "disableLock only applies to read-only detectOnly plans"},
+		{"with lockTimeout", WorkspaceConfig{Name: "a", Dir: "/tmp/a", DetectOnly: true, DisableLock: true, LockTimeout: "30s"}, "lockTimeout has no effect with disableLock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateInfrastructureConfig(InfrastructureConfig{Workspaces: []WorkspaceConfig{tt.ws}})
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateInfrastructureConfig_BackendCheck(t *testing.T) {
+	for _, mode := range []string{"", BackendCheckWarn, BackendCheckError} {
+		cfg := InfrastructureConfig{
+			BackendCheck: mode,
+			Workspaces:   []WorkspaceConfig{{Name: "a", Dir: "/tmp/a"}},
+		}
+		assert.NoError(t, ValidateInfrastructureConfig(cfg), mode)
+	}
+
+	cfg := InfrastructureConfig{
+		BackendCheck: "strict",
+		Workspaces:   []WorkspaceConfig{{Name: "a", Dir: "/tmp/a"}},
+	}
+	err := ValidateInfrastructureConfig(cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `backendCheck must be "warn" or "error", got "strict"`)
+}
+
+func TestValidateInfrastructureConfig_NegativeRetryBudget(t *testing.T) {
+	cfg := InfrastructureConfig{
+		RetryBudget: -1,
+		Workspaces:  []WorkspaceConfig{{Name: "a", Dir: "/tmp/a"}},
+	}
+	err := ValidateInfrastructureConfig(cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "retryBudget must not be negative")
+}
+
+func TestValidateInfrastructureConfig_FailOnOutputError(t *testing.T) {
+	leaf := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "dns", Dir: "/tmp/dns", DependsOn: []string{"vpc"}, FailOnOutputError: boolPtr(false)},
+		},
+	}
+	assert.NoError(t, ValidateInfrastructureConfig(leaf))
+
+	consumed := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc", FailOnOutputError: boolPtr(false)},
+			{Name: "dns", Dir: "/tmp/dns", DependsOn: []string{"vpc"}, Inputs: []InputMapping{
+				{SourceWorkspace: "vpc", SourceOutput: "vpc_id", TargetVar: "vpc_id"},
+			}},
+		},
+	}
+	err := ValidateInfrastructureConfig(consumed)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "sets failOnOutputError to false")
+
+	asserted := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "dns", Dir: "/tmp/dns", FailOnOutputError: boolPtr(false), ExpectedOutputs: map[string]interface{}{"zone": "example.com"}},
+		},
+	}
+	err = ValidateInfrastructureConfig(asserted)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "expectedOutputs cannot be checked")
+}
+
+func TestValidateInfrastructureConfig_Targets(t *testing.T) {
+	tests := []struct {
+		target  string
+		wantErr bool
+	}{
+		{"aws_instance.web", false},
+		{"aws_instance.web[0]", false},
+		{`aws_subnet.private["us-east-1a"]`, false},
+		{"data.aws_ami.ubuntu", false},
+		{"module.vpc", false},
+		{`module.vpc["blue"].aws_subnet.private[1]`, false},
+		{"module.network.module.subnets.aws_subnet.this", false},
+		{"", true},
+		{"aws_instance", true},
+		{"aws_instance.web.", true},
+		{"aws_instance.web[x]", true},
+		{"module.vpc.", true},
+		{"aws instance.web", true},
+		{"-destroy", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			cfg := InfrastructureConfig{
+				Workspaces: []WorkspaceConfig{{Name: "a", Dir: "/tmp/a", Targets: []string{tt.target}}},
+			}
+			err := ValidateInfrastructureConfig(cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "invalid target")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateInfrastructureConfig_Parallelism(t *testing.T) {
+	tests := []struct {
+		name        string
+		parallelism int
+		wantErr     bool
+	}{
+		{"unset", 0, false},
+		{"minimum", 1, false},
+		{"maximum", 256, false},
+		{"negative", -1, true},
+		{"too high", 257, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := InfrastructureConfig{
+				Workspaces: []WorkspaceConfig{{Name: "a", Dir: "/tmp/a", Parallelism: tt.parallelism}},
+			}
+			err := ValidateInfrastructureConfig(cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "parallelism must be between 1 and 256")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateInfrastructureConfig_RemoteVarSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		remote  *RemoteVarSet
+		wantErr bool
+	}{
+		{name: "workspace source", remote: &RemoteVarSet{WorkspaceID: "ws-1"}},
+		{name: "variable set source", remote: &RemoteVarSet{VarSetID: "varset-1"}},
+		{name: "no source", remote: &RemoteVarSet{}, wantErr: true},
+		{name: "both sources", remote: &RemoteVarSet{WorkspaceID: "ws-1", VarSetID: "varset-1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := InfrastructureConfig{
+				Workspaces: []WorkspaceConfig{{Name: "a", Dir: "/tmp/a", RemoteVarSet: tt.remote}},
+			}
+
+			err := ValidateInfrastructureConfig(cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "exactly one of workspaceId or varSetId")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNormalizeInfrastructureConfig_RemoteVarSetTokenEnv(t *testing.T) {
+	cfg := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "a", Dir: "/tmp/a", RemoteVarSet: &RemoteVarSet{VarSetID: "varset-1"}},
+			{Name: "b", Dir: "/tmp/b", RemoteVarSet: &RemoteVarSet{VarSetID: "varset-1", TokenEnv: "PROD_TFE_TOKEN"}},
+		},
+	}
+
+	got := NormalizeInfrastructureConfig(cfg)
+	assert.Equal(t, "TFE_TOKEN", got.Workspaces[0].RemoteVarSet.TokenEnv)
+	assert.Equal(t, "PROD_TFE_TOKEN", got.Workspaces[1].RemoteVarSet.TokenEnv)
+}
+
+func TestValidateInfrastructureConfig_MaxConcurrency(t *testing.T) {
+	cfg := InfrastructureConfig{
+		MaxConcurrency: -1,
+		Workspaces:     []WorkspaceConfig{{Name: "vpc", Dir: "/tmp/vpc"}},
+	}
+	err := ValidateInfrastructureConfig(cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "maxConcurrency must not be negative")
+
+	cfg.MaxConcurrency = 2
+	assert.NoError(t, ValidateInfrastructureConfig(cfg))
+}
+
+func TestValidateInfrastructureConfig_WaitFor(t *testing.T) {
+	tests := []struct {
+		name       string
+		workspaces []WorkspaceConfig
+		errMsg     string
+	}{
+		{
+			name: "ordering-only edge",
+			workspaces: []WorkspaceConfig{
+				{Name: "lock", Dir: "/tmp/lock"},
+				{Name: "app", Dir: "/tmp/app", WaitFor: []string{"lock"}},
+			},
+		},
+		{
+			name: "unknown workspace",
+			workspaces: []WorkspaceConfig{
+				{Name: "app", Dir: "/tmp/app", WaitFor: []string{"missing"}},
+			},
+			errMsg: "workspace app waits for unknown workspace missing",
+		},
+		{
+			name: "waits for itself",
+			workspaces: []WorkspaceConfig{
+				{Name: "app", Dir: "/tmp/app", WaitFor: []string{"app"}},
+			},
+			errMsg: "dependency cycle detected",
+		},
+		{
+			name: "cycle through waitFor and dependsOn",
+			workspaces: []WorkspaceConfig{
+				{Name: "a", Dir: "/tmp/a", WaitFor: []string{"b"}},
+				{Name: "b", Dir: "/tmp/b", DependsOn: []string{"a"}},
+			},
+			errMsg: "dependency cycle detected",
+		},
+		{
+			name: "inputs require dependsOn",
+			workspaces: []WorkspaceConfig{
+				{Name: "vpc", Dir: "/tmp/vpc"},
+				{
+					Name:    "app",
+					Dir:     "/tmp/app",
+					WaitFor: []string{"vpc"},
+					Inputs:  []InputMapping{{SourceWorkspace: "vpc", SourceOutput: "vpc_id", TargetVar: "vpc_id"}},
+				},
+			},
+			errMsg: "workspace app must depend (directly or transitively) on vpc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateInfrastructureConfig(InfrastructureConfig{Workspaces: tt.workspaces})
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestValidateInfrastructureConfig_UndeclaredOutputMessage(t *testing.T) {
+	cfg := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc", Outputs: []string{"vpc_id"}},
+			{
+				Name:      "subnets",
+				Dir:       "/tmp/subnets",
+				DependsOn: []string{"vpc"},
+				Inputs:    []InputMapping{{SourceWorkspace: "vpc", SourceOutput: "vcp_id", TargetVar: "vpc_id"}},
+			},
+		},
+	}
+
+	err := ValidateInfrastructureConfig(cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "workspace subnets input mapping vpc_id references output vcp_id, which vpc does not declare (declared: vpc_id)")
+}
+
+func TestExecutionLevels_CountsWaitFor(t *testing.T) {
+	levels := ExecutionLevels([]WorkspaceConfig{
+		{Name: "vpc"},
+		{Name: "lock"},
+		{Name: "app", DependsOn: []string{"vpc"}, WaitFor: []string{"migrate"}},
+		{Name: "migrate", WaitFor: []string{"lock"}},
+	})
+	assert.Equal(t, [][]string{{"vpc", "lock"}, {"migrate"}, {"app"}}, levels)
+}
+
+// withConfigValidators registers validators for the duration of a test.
+func withConfigValidators(t *testing.T, validators ...ConfigValidator) {
+	t.Helper()
+
+	configValidatorsMu.Lock()
+	saved := configValidators
+	configValidatorsMu.Unlock()
+	t.Cleanup(func() {
+		configValidatorsMu.Lock()
+		configValidators = saved
+		configValidatorsMu.Unlock()
+	})
+
+	for _, v := range validators {
+		RegisterConfigValidator(v)
+	}
+}
+
+// dirPrefixValidator requires workspace dirs to start with a prefix.
+type dirPrefixValidator struct{ prefix string }
+
+func (v dirPrefixValidator) Name() string { return "dir-prefix" }
+
+func (v dirPrefixValidator) Validate(cfg InfrastructureConfig) error {
+	for _, ws := range cfg.Workspaces {
+		if !strings.HasPrefix(ws.Dir, v.prefix) {
+			return fmt.Errorf("workspace %s dir %s must start with %s", ws.Name, ws.Dir, v.prefix)
+		}
+	}
+	return nil
+}
+
+func TestValidateInfrastructureConfig_CustomValidators(t *testing.T) {
+	withConfigValidators(t, RequireTaskQueue{}, dirPrefixValidator{prefix: "/infra/"})
+
+	conforming := InfrastructureConfig{Workspaces: []WorkspaceConfig{
+		{Name: "vpc", Dir: "/infra/vpc", TaskQueue: "network"},
+	}}
+	assert.NoError(t, ValidateInfrastructureConfig(conforming))
+
+	// Both custom validators report; neither stops the other
+	nonConforming := InfrastructureConfig{Workspaces: []WorkspaceConfig{
+		{Name: "vpc", Dir: "/infra/vpc", TaskQueue: "network"},
+		{Name: "app", Dir: "/tmp/app"},
+	}}
+	err := ValidateInfrastructureConfig(nonConforming)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "require-task-queue: workspaces without a taskQueue: app")
+	assert.Contains(t, err.Error(), "dir-prefix: workspace app dir /tmp/app must start with /infra/")
+}
+
+func TestValidateInfrastructureConfig_BuiltinChecksRunWithCustomValidators(t *testing.T) {
+	withConfigValidators(t, RequireTaskQueue{})
+
+	cfg := InfrastructureConfig{Workspaces: []WorkspaceConfig{
+		{Name: "vpc", Dir: "/infra/vpc", DependsOn: []string{"missing"}},
+	}}
+	err := ValidateInfrastructureConfig(cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "workspace vpc depends on unknown workspace missing")
+	assert.Contains(t, err.Error(), "workspaces without a taskQueue: vpc")
+}
+
+func TestParseConfig_FormatsDecodeAlike(t *testing.T) {
+	fromYAML, err := ParseConfig([]byte("workspaces:\n  - name: vpc\n    dir: vpc\n    dependsOn: []\n"), ".yml")
+	assert.NoError(t, err)
+	fromJSON, err := ParseConfig([]byte(`{"workspaces":[{"name":"vpc","dir":"vpc","dependsOn":[]}]}`), ".json")
+	assert.NoError(t, err)
+	assert.Equal(t, fromYAML, fromJSON)
+
+	_, err = ParseConfig([]byte("workspaces: ["), ".yaml")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid YAML config")
+}
+
+func TestPrepareConfig(t *testing.T) {
+	cfg, err := PrepareConfig(InfrastructureConfig{
+		WorkspaceRoot: "/tmp/infra",
+		Workspaces:    []WorkspaceConfig{{Name: "vpc", Dir: "vpc"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "/tmp/infra/vpc", cfg.Workspaces[0].Dir)
+	assert.Equal(t, []string{"init", "validate", "plan", "apply"}, cfg.Workspaces[0].Operations)
+
+	_, err = PrepareConfig(InfrastructureConfig{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no workspaces defined")
+}
+
+func TestValidateInfrastructureConfig_OutputRemap(t *testing.T) {
+	workspaces := []WorkspaceConfig{
+		{Name: "vpc", Dir: "/tmp/vpc", Outputs: []string{"vpc_id"}},
+		{Name: "eu.app", Dir: "/tmp/app"},
+	}
+
+	valid := InfrastructureConfig{Workspaces: workspaces, OutputRemap: map[string]string{
+		"vpc.vpc_id":     "network_vpc_id",
+		"eu.app.app_url": "app_url",
+	}}
+	assert.NoError(t, ValidateInfrastructureConfig(valid))
+
+	cases := map[string]map[string]string{
+		"must be workspace.output":              {"eks.cluster_name": "cluster"},
+		"outputRemap key vpc.cidr must be":      {"vpc.cidr": "cidr"},
+		"outputRemap vpc.vpc_id has an empty":   {"vpc.vpc_id": " "},
+		"renames both eu.app.id and vpc.vpc_id": {"vpc.vpc_id": "id", "eu.app.id": "id"},
+	}
+	for want, remap := range cases {
+		err := ValidateInfrastructureConfig(InfrastructureConfig{Workspaces: workspaces, OutputRemap: remap})
+		assert.Error(t, err, want)
+		assert.Contains(t, err.Error(), want)
+	}
+}
+
+// chainConfig builds a linear chain of n workspaces, each depending on the previous one.
+func chainConfig(n int) InfrastructureConfig {
+	cfg := InfrastructureConfig{}
+	for i := 0; i < n; i++ {
+		ws := WorkspaceConfig{Name: fmt.Sprintf("ws%d", i), Dir: fmt.Sprintf("/tmp/ws%d", i)}
+		if i > 0 {
+			ws.DependsOn = []string{fmt.Sprintf("ws%d", i-1)}
+		}
+		cfg.Workspaces = append(cfg.Workspaces, ws)
+	}
+	return cfg
+}
+
+func TestValidateInfrastructureConfig_MaxDepth(t *testing.T) {
+	// Depth counts edges: a chain of 4 workspaces is 3 deep
+	cfg := chainConfig(4)
+	cfg.MaxDepth = 3
+	assert.NoError(t, ValidateInfrastructureConfig(cfg))
+
+	cfg.MaxDepth = 2
+	err := ValidateInfrastructureConfig(cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "workspace ws3 sits 3 dependencies deep, exceeding the limit of 2")
+
+	cfg.MaxDepth = -1
+	err = ValidateInfrastructureConfig(cfg)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "maxDepth must not be negative")
+}
+
+func TestValidateInfrastructureConfig_DefaultMaxDepth(t *testing.T) {
+	assert.NoError(t, ValidateInfrastructureConfig(chainConfig(DefaultMaxDepth+1)))
+
+	err := ValidateInfrastructureConfig(chainConfig(DefaultMaxDepth + 2))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf("exceeding the limit of %d", DefaultMaxDepth))
+}
+
+func TestValidateInfrastructureConfig_ProtectedResources(t *testing.T) {
+	ws := WorkspaceConfig{Name: "db", Dir: "/tmp/db", ProtectedResources: []string{"aws_db_instance.*"}}
+	assert.NoError(t, ValidateInfrastructureConfig(InfrastructureConfig{Workspaces: []WorkspaceConfig{ws}}))
+
+	err := ValidateInfrastructureConfig(InfrastructureConfig{Destroy: true, Workspaces: []WorkspaceConfig{ws}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "protectedResources can't guard destroy")
+
+	detect := ws
+	detect.DetectOnly = true
+	err = ValidateInfrastructureConfig(InfrastructureConfig{Workspaces: []WorkspaceConfig{detect}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "protectedResources needs a saved plan file")
+
+	empty := ws
+	empty.ProtectedResources = []string{""}
+	err = ValidateInfrastructureConfig(InfrastructureConfig{Workspaces: []WorkspaceConfig{empty}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "empty protectedResources entry")
+}
+
+func TestValidateInfrastructureConfig_PlanPreview(t *testing.T) {
+	preview := &PlanPreviewConfig{}
+
+	err := ValidateInfrastructureConfig(InfrastructureConfig{PlanPreview: preview, Workspaces: []WorkspaceConfig{
+		{Name: "vpc", Dir: "/tmp/vpc", Operations: []string{"init", "validate", "plan", "apply"}},
+	}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "planPreview only plans")
+
+	err = ValidateInfrastructureConfig(InfrastructureConfig{PlanPreview: preview, Workspaces: []WorkspaceConfig{
+		{Name: "vpc", Dir: "/tmp/vpc", DetectOnly: true},
+	}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be combined with detectOnly")
+
+	err = ValidateInfrastructureConfig(InfrastructureConfig{PlanPreview: preview, Destroy: true, Workspaces: []WorkspaceConfig{
+		{Name: "vpc", Dir: "/tmp/vpc"},
+	}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "destroy and planPreview cannot be combined")
+}
+
+func TestValidateWorkspaceOperations_SkipInitIfInitialized(t *testing.T) {
+	ws := WorkspaceConfig{Name: "vpc", Dir: "/tmp/vpc", SkipInitIfInitialized: true, InitUpgrade: true}
+	assert.NoError(t, ValidateWorkspaceOperations(ws))
+
+	ws.CaptureInitInfo = true
+	err := ValidateWorkspaceOperations(ws)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be combined with skipInitIfInitialized")
+}
+
+func TestValidateWorkspaceOperations_Refresh(t *testing.T) {
+	tests := []struct {
+		name    string
+		ops     []string
+		wantErr string
+	}{
+		{"before plan", []string{"init", "validate", "refresh", "plan", "apply"}, ""},
+		{"before validate", []string{"init", "refresh", "validate", "plan"}, ""},
+		{"refresh only", []string{"init", "validate", "refresh"}, ""},
+		{"before destroy", []string{"init", "validate", "refresh", "destroy"}, ""},
+		{"before init", []string{"refresh", "init", "validate", "plan"}, "operation 'refresh' must come after 'init'"},
+		{"after plan", []string{"init", "validate", "plan", "refresh", "apply"}, "operation 'refresh' must come before 'plan'"},
+		{"after destroy", []string{"init", "validate", "destroy", "refresh"}, "operation 'refresh' must come before 'destroy'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWorkspaceOperations(WorkspaceConfig{Name: "vpc", Dir: "/tmp/vpc", Operations: tt.ops})
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateWorkspaceOperations_LegacyRefresh(t *testing.T) {
+	ws := WorkspaceConfig{Name: "vpc", Dir: "/tmp/vpc", LegacyRefresh: true}
+	err := ValidateWorkspaceOperations(ws)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "legacyRefresh needs the 'refresh' operation")
+
+	ws.Operations = []string{"init", "validate", "refresh", "plan"}
+	assert.NoError(t, ValidateWorkspaceOperations(ws))
+
+	// Refresh writes the state, so it must take the lock
+	ws = WorkspaceConfig{Name: "vpc", Dir: "/tmp/vpc", DetectOnly: true, DisableLock: true, Operations: []string{"init", "validate", "refresh", "plan"}}
+	err = ValidateWorkspaceOperations(ws)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be combined with disableLock")
+}
+
+func TestValidateWorkspaceOperations_DriftCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		ws      WorkspaceConfig
+		wantErr string
+	}{
+		{"default operations", WorkspaceConfig{Name: "vpc", Dir: "/tmp/vpc", DriftCheck: true}, ""},
+		{"plan only", WorkspaceConfig{Name: "vpc", Dir: "/tmp/vpc", DriftCheck: true, Operations: []string{"init", "validate", "plan"}}, ""},
+		{"apply", WorkspaceConfig{Name: "vpc", Dir: "/tmp/vpc", DriftCheck: true, Operations: []string{"init", "validate", "plan", "apply"}}, "cannot be combined with operation 'apply'"},
+		{"refresh", WorkspaceConfig{Name: "vpc", Dir: "/tmp/vpc", DriftCheck: true, Operations: []string{"init", "validate", "refresh", "plan"}}, "cannot be combined with operation 'refresh'"},
+		{"no plan", WorkspaceConfig{Name: "vpc", Dir: "/tmp/vpc", DriftCheck: true, Operations: []string{"init", "validate"}}, "driftCheck needs the 'plan' operation"},
+		{"capture plan", WorkspaceConfig{Name: "vpc", Dir: "/tmp/vpc", DriftCheck: true, CapturePlan: true}, "driftCheck saves no plan file"},
+		{"protected resources", WorkspaceConfig{Name: "vpc", Dir: "/tmp/vpc", DriftCheck: true, ProtectedResources: []string{"aws_db_instance.*"}}, "driftCheck saves no plan file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWorkspaceOperations(tt.ws)
+			if tt.wantErr != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNormalizeInfrastructureConfig_DriftCheckDefaultOperations(t *testing.T) {
+	cfg := NormalizeInfrastructureConfig(InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{{Name: "vpc", Dir: "/tmp/vpc", DriftCheck: true}},
+	})
+	assert.Equal(t, []string{"init", "validate", "plan"}, cfg.Workspaces[0].Operations)
+}
+
+func TestDriftCheckConfig(t *testing.T) {
+	cfg, err := PrepareConfig(InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc", CapturePlan: true},
+			{Name: "subnets", Dir: "/tmp/subnets", DependsOn: []string{"vpc"}, Operations: []string{"init", "validate", "refresh", "plan"}, LegacyRefresh: true},
+			{Name: "eks", Dir: "/tmp/eks", DependsOn: []string{"subnets"}, Operations: []string{"init", "validate", "plan"}, ProtectedResources: []string{"aws_eks_cluster.*"}},
+		},
+	})
+	assert.NoError(t, err)
+
+	drift, downgraded := DriftCheckConfig(cfg)
+
+	assert.Equal(t, []string{"vpc", "subnets"}, downgraded)
+	for _, ws := range drift.Workspaces {
+		assert.True(t, ws.DriftCheck, ws.Name)
+		assert.Equal(t, []string{"init", "validate", "plan"}, ws.Operations, ws.Name)
+	}
+	assert.NoError(t, ValidateInfrastructureConfig(drift))
+
+	// The original config is left untouched
+	assert.Equal(t, []string{"init", "validate", "plan", "apply"}, cfg.Workspaces[0].Operations)
+	assert.False(t, cfg.Workspaces[0].DriftCheck)
+}
+
+func TestDriftCheckConfig_Destroy(t *testing.T) {
+	cfg, err := PrepareConfig(InfrastructureConfig{
+		Destroy: true,
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "subnets", Dir: "/tmp/subnets", DependsOn: []string{"vpc"}},
+		},
+	})
+	assert.NoError(t, err)
+
+	drift, downgraded := DriftCheckConfig(cfg)
+
+	assert.Equal(t, []string{"vpc", "subnets"}, downgraded)
+	assert.False(t, drift.Destroy)
+	assert.Equal(t, []string{"init", "validate", "plan"}, drift.Workspaces[1].Operations)
+	assert.NoError(t, ValidateInfrastructureConfig(drift))
+}
+
+func TestStripMutatingOperations_Refresh(t *testing.T) {
+	cfg, err := PrepareConfig(InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc", Operations: []string{"init", "validate", "refresh", "plan"}, LegacyRefresh: true},
+		},
+	})
+	assert.NoError(t, err)
+
+	stripped, downgraded := StripMutatingOperations(cfg)
+
+	assert.Equal(t, []string{"vpc"}, downgraded)
+	assert.Equal(t, []string{"init", "validate", "plan"}, stripped.Workspaces[0].Operations)
+	assert.NoError(t, ValidateInfrastructureConfig(stripped))
+}
+
+func TestValidateInfrastructureConfig_RefreshInPlanOnlyModes(t *testing.T) {
+	refresh := []string{"init", "validate", "refresh", "plan"}
+	tests := []struct {
+		name    string
+		cfg     InfrastructureConfig
+		wantErr string
+	}{
+		{"speculativePlan", InfrastructureConfig{SpeculativePlan: true, Workspaces: []WorkspaceConfig{{Name: "vpc", Dir: "/tmp/vpc", Operations: refresh}}}, "speculativePlan only plans"},
+		{"planPreview", InfrastructureConfig{PlanPreview: &PlanPreviewConfig{}, Workspaces: []WorkspaceConfig{{Name: "vpc", Dir: "/tmp/vpc", Operations: refresh}}}, "planPreview only plans"},
+		{"detectOnly", InfrastructureConfig{Workspaces: []WorkspaceConfig{{Name: "vpc", Dir: "/tmp/vpc", DetectOnly: true, Operations: refresh}}}, "detectOnly cannot be combined with operation 'refresh'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateInfrastructureConfig(tt.cfg)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
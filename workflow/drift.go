@@ -0,0 +1,15 @@
+package workflow
+
+import "github.com/fakoli/temporal-terraform-orchestrator/activities"
+
+// driftedWorkspaces lists, in config order, the workspaces whose drift check
+// found infrastructure changed outside terraform.
+func driftedWorkspaces(config InfrastructureConfig, details map[string]activities.WorkspaceDetails) []string {
+	var drifted []string
+	for _, ws := range config.Workspaces {
+		if drift := details[ws.Name].Drift; drift != nil && drift.Drifted {
+			drifted = append(drifted, ws.Name)
+		}
+	}
+	return drifted
+}
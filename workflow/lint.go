@@ -0,0 +1,103 @@
+package workflow
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// lintMaxChainDepth is the dependency depth beyond which a chain is flagged:
+// long chains serialize the orchestration and make failures cascade.
+const lintMaxChainDepth = 4
+
+// Lint rule identifiers
+const (
+	LintNoVariables       = "no-variables"
+	LintDeepChain         = "deep-chain"
+	LintSharedDir         = "shared-dir"
+	LintUnconsumedOutputs = "unconsumed-outputs"
+)
+
+// LintWarning is a non-blocking finding about a config that is valid but
+// likely not what the author intended.
+type LintWarning struct {
+	Rule      string `json:"rule"`
+	Workspace string `json:"workspace,omitempty"`
+	Message   string `json:"message"`
+}
+
+func (w LintWarning) String() string {
+	if w.Workspace == "" {
+		return fmt.Sprintf("[%s] %s", w.Rule, w.Message)
+	}
+	return fmt.Sprintf("[%s] %s: %s", w.Rule, w.Workspace, w.Message)
+}
+
+// LintConfig reports anti-patterns in a validated, normalized config. It
+// never fails; an empty result means nothing was flagged. Warnings are
+// grouped by rule, in config order within each rule.
+func LintConfig(cfg InfrastructureConfig) []LintWarning {
+	var warnings []LintWarning
+
+	for _, ws := range cfg.Workspaces {
+		if ws.TFVars == "" && len(ws.Vars) == 0 && len(ws.Inputs) == 0 && ws.RemoteVarSet == nil {
+			warnings = append(warnings, LintWarning{
+				Rule:      LintNoVariables,
+				Workspace: ws.Name,
+				Message:   "no tfvars, vars, remote variables or inputs; the module runs on defaults only",
+			})
+		}
+	}
+
+	depths := CalculateDepths(cfg.Workspaces)
+	for _, ws := range cfg.Workspaces {
+		if depths[ws.Name] > lintMaxChainDepth {
+			warnings = append(warnings, LintWarning{
+				Rule:      LintDeepChain,
+				Workspace: ws.Name,
+				Message:   fmt.Sprintf("sits %d dependencies deep (more than %d); long chains run serially and cascade failures", depths[ws.Name], lintMaxChainDepth),
+			})
+		}
+	}
+
+	byDir := make(map[string][]string)
+	var dirs []string
+	for _, ws := range cfg.Workspaces {
+		dir := filepath.Clean(filepath.Join(ws.Dir, ws.Chdir))
+		if _, ok := byDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], ws.Name)
+	}
+	for _, dir := range dirs {
+		if names := byDir[dir]; len(names) > 1 {
+			warnings = append(warnings, LintWarning{
+				Rule:    LintSharedDir,
+				Message: fmt.Sprintf("workspaces %s share module dir %s; concurrent runs contend for its .terraform directory and state", strings.Join(names, ", "), dir),
+			})
+		}
+	}
+
+	consumed := make(map[string]bool)
+	dependents := make(map[string][]string)
+	for _, ws := range cfg.Workspaces {
+		for _, input := range ws.Inputs {
+			consumed[input.SourceWorkspace] = true
+		}
+		for _, dep := range ws.DependsOn {
+			dependents[dep] = append(dependents[dep], ws.Name)
+		}
+	}
+	for _, ws := range cfg.Workspaces {
+		if !containsOperation(ws.Operations, "apply") || len(dependents[ws.Name]) == 0 || consumed[ws.Name] {
+			continue
+		}
+		warnings = append(warnings, LintWarning{
+			Rule:      LintUnconsumedOutputs,
+			Workspace: ws.Name,
+			Message:   fmt.Sprintf("dependents %s never map its outputs; use waitFor if they only need ordering", strings.Join(dependents[ws.Name], ", ")),
+		})
+	}
+
+	return warnings
+}
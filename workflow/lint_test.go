@@ -0,0 +1,93 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// lintRules returns the warnings for cfg keyed by rule.
+func lintRules(t *testing.T, cfg InfrastructureConfig) map[string][]LintWarning {
+	t.Helper()
+
+	cfg, err := PrepareConfig(cfg)
+	require.NoError(t, err)
+
+	byRule := make(map[string][]LintWarning)
+	for _, w := range LintConfig(cfg) {
+		byRule[w.Rule] = append(byRule[w.Rule], w)
+	}
+	return byRule
+}
+
+func TestLintConfig_CleanConfig(t *testing.T) {
+	cfg, err := PrepareConfig(InfrastructureConfig{
+		WorkspaceRoot: "/tmp/infra",
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "vpc", TFVars: "vpc.tfvars"},
+			{
+				Name:      "subnets",
+				Dir:       "subnets",
+				DependsOn: []string{"vpc"},
+				Inputs:    []InputMapping{{SourceWorkspace: "vpc", SourceOutput: "vpc_id", TargetVar: "vpc_id"}},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.Empty(t, LintConfig(cfg))
+}
+
+func TestLintConfig_NoVariables(t *testing.T) {
+	rules := lintRules(t, InfrastructureConfig{Workspaces: []WorkspaceConfig{
+		{Name: "bare", Dir: "/tmp/bare"},
+		{Name: "remote", Dir: "/tmp/remote", RemoteVarSet: &RemoteVarSet{WorkspaceID: "ws-1"}},
+	}})
+
+	require.Len(t, rules[LintNoVariables], 1)
+	require.Equal(t, "bare", rules[LintNoVariables][0].Workspace)
+}
+
+func TestLintConfig_DeepChain(t *testing.T) {
+	var workspaces []WorkspaceConfig
+	prev := ""
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		ws := WorkspaceConfig{Name: name, Dir: "/tmp/" + name, TFVars: "/tmp/" + name + ".tfvars"}
+		if prev != "" {
+			ws.WaitFor = []string{prev}
+		}
+		workspaces = append(workspaces, ws)
+		prev = name
+	}
+
+	rules := lintRules(t, InfrastructureConfig{Workspaces: workspaces})
+	require.Len(t, rules[LintDeepChain], 1)
+	require.Equal(t, "f", rules[LintDeepChain][0].Workspace)
+	require.Contains(t, rules[LintDeepChain][0].Message, "5 dependencies deep")
+}
+
+func TestLintConfig_SharedDir(t *testing.T) {
+	rules := lintRules(t, InfrastructureConfig{Workspaces: []WorkspaceConfig{
+		{Name: "dev", Dir: "/tmp/app", TFVars: "/tmp/dev.tfvars"},
+		{Name: "prod", Dir: "/tmp/app/", TFVars: "/tmp/prod.tfvars"},
+		{Name: "dev-db", Dir: "/tmp/app", Chdir: "db", TFVars: "/tmp/db.tfvars"},
+	}})
+
+	require.Len(t, rules[LintSharedDir], 1)
+	require.Contains(t, rules[LintSharedDir][0].Message, "workspaces dev, prod share module dir /tmp/app")
+}
+
+func TestLintConfig_UnconsumedOutputs(t *testing.T) {
+	rules := lintRules(t, InfrastructureConfig{Workspaces: []WorkspaceConfig{
+		{Name: "vpc", Dir: "/tmp/vpc", TFVars: "/tmp/vpc.tfvars"},
+		{Name: "dns", Dir: "/tmp/dns", TFVars: "/tmp/dns.tfvars"},
+		{Name: "app", Dir: "/tmp/app", DependsOn: []string{"vpc", "dns"},
+			Inputs: []InputMapping{{SourceWorkspace: "dns", SourceOutput: "zone_id", TargetVar: "zone_id"}}},
+		{Name: "preview", Dir: "/tmp/preview", TFVars: "/tmp/preview.tfvars", Operations: []string{"init", "validate", "plan"}},
+		{Name: "report", Dir: "/tmp/report", TFVars: "/tmp/report.tfvars", DependsOn: []string{"preview"}},
+	}})
+
+	// preview doesn't apply, so its dependents can't expect fresh outputs anyway
+	require.Len(t, rules[LintUnconsumedOutputs], 1)
+	require.Equal(t, "vpc", rules[LintUnconsumedOutputs][0].Workspace)
+	require.Contains(t, rules[LintUnconsumedOutputs][0].Message, "use waitFor")
+}
@@ -0,0 +1,139 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fakoli/temporal-terraform-orchestrator/activities"
+	"go.temporal.io/sdk/temporal"
+)
+
+// OrchestrationFailedErrorType is the ApplicationError type of a failed
+// orchestration. Its details carry the partial OrchestrationResult.
+const OrchestrationFailedErrorType = "OrchestrationFailed"
+
+// OrchestrationResult is returned by ParentWorkflow once every workspace has
+// finished. Outputs stay namespaced by workspace, so workspaces exporting the
+// same output name never collide.
+//
+// When the orchestration fails, the same type is attached to the error (see
+// PartialResult) with the outputs of the workspaces that succeeded and the
+// reason every other workspace has none.
+type OrchestrationResult struct {
+	Outputs map[string]map[string]interface{} `json:"outputs"`
+
+	// Details maps each workspace that recorded any, such as a captured
+	// plan or an apply summary, to what it reported besides its outputs.
+	Details map[string]activities.WorkspaceDetails `json:"details,omitempty"`
+
+	// Failed maps each failed workspace to its error.
+	Failed map[string]string `json:"failed,omitempty"`
+
+	// Skipped maps each workspace that never ran to the reason why.
+	Skipped map[string]string `json:"skipped,omitempty"`
+
+	// OutputRemap is the config's outputRemap, applied by Flattened.
+	OutputRemap map[string]string `json:"outputRemap,omitempty"`
+
+	// Preview aggregates the workspaces' plans when the config sets planPreview.
+	Preview *PlanPreview `json:"preview,omitempty"`
+
+	// DriftedWorkspaces lists the workspaces whose drift check found
+	// changes made outside terraform, in config order.
+	DriftedWorkspaces []string `json:"driftedWorkspaces,omitempty"`
+}
+
+// Flattened is the "workspace.output" view of the result's outputs with the
+// config's outputRemap applied.
+func (r OrchestrationResult) Flattened() FlattenedOutputs {
+	return RemapOutputs(r.Outputs, r.OutputRemap)
+}
+
+// PartialResult extracts the partial OrchestrationResult from a failed
+// ParentWorkflow's error, as returned by WorkflowRun.Get. ok is false for
+// errors that carry none, such as an invalid config.
+func PartialResult(err error) (result OrchestrationResult, ok bool) {
+	var appErr *temporal.ApplicationError
+	if !errors.As(err, &appErr) || appErr.Type() != OrchestrationFailedErrorType || !appErr.HasDetails() {
+		return OrchestrationResult{}, false
+	}
+	if err := appErr.Details(&result); err != nil {
+		return OrchestrationResult{}, false
+	}
+	return result, true
+}
+
+// FlattenedOutputs is a convenience view of workspace outputs keyed as
+// "workspace.output".
+type FlattenedOutputs struct {
+	Values map[string]interface{} `json:"values"`
+
+	// Collisions lists keys produced by more than one workspace/output pair
+	// (e.g. workspace "a.b" output "c" and workspace "a" output "b.c").
+	// Colliding keys are left out of Values; use the namespaced outputs instead.
+	Collisions []string `json:"collisions,omitempty"`
+}
+
+// FlattenOutputs builds the "workspace.output" view of namespaced outputs,
+// reporting any keys that would otherwise silently overwrite each other.
+func FlattenOutputs(outputs map[string]map[string]interface{}) FlattenedOutputs {
+	return RemapOutputs(outputs, nil)
+}
+
+// RemapOutputs flattens namespaced outputs like FlattenOutputs, renaming the
+// "workspace.output" keys listed in remap. Unmapped keys pass through, and a
+// new name that clashes with another key is reported as a collision.
+func RemapOutputs(outputs map[string]map[string]interface{}, remap map[string]string) FlattenedOutputs {
+	sources := make(map[string][]string)
+	values := make(map[string]interface{})
+
+	workspaces := make([]string, 0, len(outputs))
+	for name := range outputs {
+		workspaces = append(workspaces, name)
+	}
+	sort.Strings(workspaces)
+
+	for _, ws := range workspaces {
+		names := make([]string, 0, len(outputs[ws]))
+		for name := range outputs[ws] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			key := ws + "." + name
+			if renamed, ok := remap[key]; ok {
+				key = renamed
+			}
+			sources[key] = append(sources[key], fmt.Sprintf("%s/%s", ws, name))
+			values[key] = outputs[ws][name]
+		}
+	}
+
+	flat := FlattenedOutputs{Values: values}
+	for key, from := range sources {
+		if len(from) > 1 {
+			delete(flat.Values, key)
+			flat.Collisions = append(flat.Collisions, fmt.Sprintf("%s (from %s)", key, strings.Join(from, ", ")))
+		}
+	}
+	sort.Strings(flat.Collisions)
+	return flat
+}
+
+// splitOutputs separates the values of `terraform output -json`, which
+// dependents consume, from the sorted names of the sensitive ones.
+func splitOutputs(outputs map[string]activities.OutputValue) (map[string]interface{}, []string) {
+	values := make(map[string]interface{}, len(outputs))
+	var sensitive []string
+	for name, out := range outputs {
+		values[name] = out.Value
+		if out.Sensitive {
+			sensitive = append(sensitive, name)
+		}
+	}
+	sort.Strings(sensitive)
+	return values, sensitive
+}
@@ -0,0 +1,89 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestFlattenOutputs(t *testing.T) {
+	outputs := map[string]map[string]interface{}{
+		"vpc":     {"id": "vpc-1", "cidr": "10.0.0.0/16"},
+		"subnets": {"id": "subnet-1"},
+		"empty":   {},
+	}
+
+	flat := FlattenOutputs(outputs)
+	require.Empty(t, flat.Collisions)
+	require.Equal(t, map[string]interface{}{
+		"vpc.id":     "vpc-1",
+		"vpc.cidr":   "10.0.0.0/16",
+		"subnets.id": "subnet-1",
+	}, flat.Values)
+}
+
+func TestFlattenOutputs_ReportsCollisions(t *testing.T) {
+	outputs := map[string]map[string]interface{}{
+		"a.b": {"c": 1, "d": 2},
+		"a":   {"b.c": 3},
+	}
+
+	flat := FlattenOutputs(outputs)
+	require.Equal(t, []string{"a.b.c (from a/b.c, a.b/c)"}, flat.Collisions)
+	require.Equal(t, map[string]interface{}{"a.b.d": 2}, flat.Values, "colliding keys are left out")
+}
+
+func TestFlattenOutputs_Empty(t *testing.T) {
+	flat := FlattenOutputs(nil)
+	require.Empty(t, flat.Values)
+	require.Empty(t, flat.Collisions)
+}
+
+func TestRemapOutputs(t *testing.T) {
+	outputs := map[string]map[string]interface{}{
+		"vpc":     {"vpc_id": "vpc-1", "cidr": "10.0.0.0/16"},
+		"subnets": {"id": "subnet-1"},
+	}
+
+	flat := RemapOutputs(outputs, map[string]string{"vpc.vpc_id": "network_vpc_id"})
+	require.Empty(t, flat.Collisions)
+	require.Equal(t, map[string]interface{}{
+		"network_vpc_id": "vpc-1",
+		"vpc.cidr":       "10.0.0.0/16",
+		"subnets.id":     "subnet-1",
+	}, flat.Values, "unmapped keys pass through")
+
+	result := OrchestrationResult{Outputs: outputs, OutputRemap: map[string]string{"vpc.vpc_id": "network_vpc_id"}}
+	require.Equal(t, flat, result.Flattened())
+}
+
+func TestRemapOutputs_ReportsCollisions(t *testing.T) {
+	outputs := map[string]map[string]interface{}{
+		"vpc":     {"id": "vpc-1"},
+		"subnets": {"id": "subnet-1"},
+	}
+
+	flat := RemapOutputs(outputs, map[string]string{"vpc.id": "subnets.id"})
+	require.Equal(t, []string{"subnets.id (from subnets/id, vpc/id)"}, flat.Collisions)
+	require.Empty(t, flat.Values)
+}
+
+func TestPartialResult(t *testing.T) {
+	partial := OrchestrationResult{
+		Outputs: map[string]map[string]interface{}{"vpc": {"id": "vpc-1"}},
+		Failed:  map[string]string{"eks": "apply failed"},
+	}
+	err := fmt.Errorf("workflow failed: %w", temporal.NewApplicationError("workspace eks failed", OrchestrationFailedErrorType, partial))
+
+	got, ok := PartialResult(err)
+	require.True(t, ok)
+	require.Equal(t, partial, got)
+
+	_, ok = PartialResult(errors.New("no workspaces defined"))
+	require.False(t, ok)
+	_, ok = PartialResult(temporal.NewApplicationError("other", "SomethingElse", partial))
+	require.False(t, ok)
+}
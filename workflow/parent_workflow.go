@@ -2,70 +2,240 @@ package workflow
 
 import (
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/fakoli/temporal-terraform-orchestrator/activities"
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
 
-func ParentWorkflow(ctx workflow.Context, rawConfig InfrastructureConfig) error {
-	if err := ValidateInfrastructureConfig(rawConfig); err != nil {
-		return err
+func ParentWorkflow(ctx workflow.Context, rawConfig InfrastructureConfig) (OrchestrationResult, error) {
+	config, err := PrepareConfig(rawConfig)
+	if err != nil {
+		return OrchestrationResult{}, err
 	}
-
-	config := NormalizeInfrastructureConfig(rawConfig)
-	workflow.GetLogger(ctx).Info("Starting parent workflow", "workspaces", len(config.Workspaces))
+	config.Workspaces = ScheduledWorkspaces(config)
+	workflow.GetLogger(ctx).Info("Starting parent workflow", "workspaces", len(config.Workspaces),
+		"destroy", config.Destroy,
+		"speculative_plan", config.SpeculativePlan,
+		"plan_preview", config.PlanPreview != nil,
+	)
 
 	depths := CalculateDepths(config.Workspaces)
 	completedWorkspaces := make(map[string]bool)
 	workspaceOutputs := make(map[string]map[string]interface{})
-	runningWorkflows := make(map[string]string) // name -> WorkflowID
+	workspaceDetails := make(map[string]activities.WorkspaceDetails)
+	sensitiveOutputs := make(map[string][]string) // name -> outputs terraform marks sensitive
+	runningWorkflows := make(map[string]string)   // name -> WorkflowID
 	rootFutures := make(map[string]workflow.ChildWorkflowFuture)
+	failedWorkspaces := make(map[string]string) // name -> error
+	skipReasons := make(map[string]string)      // name -> reason
+	var failureOrder []string
+	aborting := false
+	retriesUsed := 0                        // activity retries reported under RetryBudget
+	retriesReserved := make(map[string]int) // name -> share of RetryBudget while in flight
 
-	finishedChan := workflow.GetSignalChannel(ctx, SignalWorkspaceFinished)
+	// Let operators see progress without reading the workflow history
+	if err := workflow.SetQueryHandler(ctx, QueryProgress, func() (OrchestrationProgress, error) {
+		return orchestrationProgress(config, completedWorkspaces, runningWorkflows, failedWorkspaces, skipReasons), nil
+	}); err != nil {
+		return OrchestrationResult{}, err
+	}
+	if err := workflow.SetQueryHandler(ctx, QueryHealth, func() (OrchestrationHealth, error) {
+		return orchestrationHealth(config, completedWorkspaces, workspaceOutputs, runningWorkflows, failedWorkspaces, skipReasons), nil
+	}); err != nil {
+		return OrchestrationResult{}, err
+	}
 
-	// Start root workspaces (those with no dependencies)
+	// Workspaces whose `when` condition is false count as completed with no
+	// outputs so their dependents aren't blocked.
+	skipped, err := skippedWorkspaces(config)
+	if err != nil {
+		return OrchestrationResult{}, err
+	}
 	for _, ws := range config.Workspaces {
-		if len(ws.DependsOn) == 0 {
-			info := workflow.GetInfo(ctx)
-			childID := fmt.Sprintf("iac-%s-%s", info.WorkflowExecution.RunID, ws.Name)
+		if skipped[ws.Name] {
+			workflow.GetLogger(ctx).Info("Skipping workspace: condition is false", "workspace", ws.Name, "when", ws.When)
+			completedWorkspaces[ws.Name] = true
+			workspaceOutputs[ws.Name] = map[string]interface{}{}
+		}
+	}
+
+	// Catch overlapping network ranges before any workspace applies. A
+	// teardown creates nothing, and overlaps must not block removing them.
+	if !config.Destroy {
+		overlaps, err := checkCIDROverlaps(ctx, config, skipped)
+		if err != nil {
+			return OrchestrationResult{}, err
+		}
+		if len(overlaps) > 0 {
+			descriptions := make([]string, len(overlaps))
+			for i, overlap := range overlaps {
+				descriptions[i] = overlap.String()
+			}
+			return OrchestrationResult{}, fmt.Errorf("overlapping CIDRs: %s", strings.Join(descriptions, "; "))
+		}
+	}
+
+	// State written to the worker's disk is lost with the worker
+	localState, err := checkLocalState(ctx, config, skipped)
+	if err != nil {
+		return OrchestrationResult{}, err
+	}
+	if len(localState) > 0 {
+		if config.BackendCheck == BackendCheckError {
+			return OrchestrationResult{}, fmt.Errorf("workspaces apply with local state: %s (configure a remote backend or set allowLocalState)", strings.Join(localState, ", "))
+		}
+		workflow.GetLogger(ctx).Warn("Workspaces apply with local state", "workspaces", localState)
+	}
+
+	// A restarted run (workflow retry or continue-as-new) starts with empty
+	// state; adopt the children the previous run already started instead of
+	// running them again. Plain worker crashes don't need this: they replay history.
+	info := workflow.GetInfo(ctx)
+	if info.Attempt > 1 || info.ContinuedExecutionRunID != "" {
+		if err := reconcileWorkspaces(ctx, config, completedWorkspaces, workspaceOutputs, sensitiveOutputs, workspaceDetails, runningWorkflows); err != nil {
+			return OrchestrationResult{}, err
+		}
+	}
+
+	finishedChan := workflow.GetSignalChannel(ctx, SignalWorkspaceFinished)
 
-			childOptions := workflow.ChildWorkflowOptions{
-				WorkflowID: childID,
+	// startReady starts workspaces whose dependencies have all completed,
+	// highest priority first, up to MaxConcurrency in flight
+	startReady := func() {
+		if aborting {
+			return
+		}
+		var ready []WorkspaceConfig
+		for _, ws := range config.Workspaces {
+			if completedWorkspaces[ws.Name] || isRunning(ws.Name, runningWorkflows) {
+				continue
 			}
-			if ws.TaskQueue != "" {
-				childOptions.TaskQueue = ws.TaskQueue
+
+			if allDependenciesMet(ws, completedWorkspaces) {
+				ready = append(ready, ws)
 			}
+		}
+		sort.SliceStable(ready, func(i, j int) bool {
+			return ready[i].Priority > ready[j].Priority
+		})
 
-			ctxChild := workflow.WithChildOptions(ctx, childOptions)
-			future := workflow.ExecuteChildWorkflow(ctxChild, TerraformWorkflow, ws)
-			rootFutures[ws.Name] = future
-			runningWorkflows[ws.Name] = childID
+		if config.MaxConcurrency > 0 {
+			ready = ready[:min(len(ready), max(config.MaxConcurrency-inFlight(runningWorkflows, completedWorkspaces), 0))]
+		}
+
+		// Workspaces in flight each hold a share of the budget, so parallel
+		// workspaces can't all spend the same retries. Once it is spent
+		// workspaces still run, but a failed activity is no longer retried.
+		var shares []int
+		if config.RetryBudget > 0 {
+			left := config.RetryBudget - retriesUsed
+			for _, reserved := range retriesReserved {
+				left -= reserved
+			}
+			shares = splitRetries(left, len(ready))
+		}
+		for i, ws := range ready {
+			if shares != nil {
+				retriesReserved[ws.Name] = shares[i]
+				ws.RetriesLeft = &shares[i]
+			}
+			startWorkspace(ctx, ws, depths, workspaceOutputs, sensitiveOutputs, runningWorkflows, rootFutures)
 		}
 	}
 
-	// Orchestration loop: wait for workspace completions and start ready children
+	// Start root workspaces (those with no pending dependencies)
+	startReady()
+
+	// Watchdog: fail with a useful message instead of waiting forever on a stuck workspace
+	timeout, err := orchestrationTimeout(config)
+	if err != nil {
+		return OrchestrationResult{}, err
+	}
+	var watchdog workflow.Future
+	if timeout > 0 {
+		watchdog = workflow.NewTimer(ctx, timeout)
+	}
+	timedOut := false
+
+	// Orchestration loop: wait for workspace completions and start ready
+	// children. After an aborting failure, wait only for those in flight.
 	for len(completedWorkspaces) < len(config.Workspaces) {
+		if aborting && inFlight(runningWorkflows, completedWorkspaces) == 0 {
+			break
+		}
 		selector := workflow.NewSelector(ctx)
+		if watchdog != nil {
+			selector.AddFuture(watchdog, func(f workflow.Future) {
+				timedOut = true
+			})
+		}
 		selector.AddReceive(finishedChan, func(c workflow.ReceiveChannel, more bool) {
 			var signal WorkspaceFinishedSignal
 			c.Receive(ctx, &signal)
 
 			completedWorkspaces[signal.Name] = true
-			workspaceOutputs[signal.Name] = signal.Outputs
-			workflow.GetLogger(ctx).Info("Workspace completed", "workspace", signal.Name)
+			if config.RetryBudget > 0 && retriesUsed < config.RetryBudget && retriesUsed+signal.Retries >= config.RetryBudget {
+				workflow.GetLogger(ctx).Warn("Retry budget spent; failed activities are no longer retried", "budget", config.RetryBudget, "retries", retriesUsed+signal.Retries)
+			}
+			retriesUsed += signal.Retries
+			delete(retriesReserved, signal.Name) // an unused share returns to the budget
+			if signal.Error != "" {
+				failedWorkspaces[signal.Name] = signal.Error
+				failureOrder = append(failureOrder, signal.Name)
+				workflow.GetLogger(ctx).Error("Workspace failed", "workspace", signal.Name, "error", signal.Error)
 
-			// Trigger any workspaces that are now ready
-			for _, ws := range config.Workspaces {
-				if completedWorkspaces[ws.Name] || isRunning(ws.Name, runningWorkflows) {
-					continue
+				// Dependents can never run; count them as done so the loop ends
+				for _, name := range transitiveDependents(config.Workspaces, signal.Name) {
+					if completedWorkspaces[name] {
+						continue
+					}
+					completedWorkspaces[name] = true
+					skipReasons[name] = fmt.Sprintf("dependency %s failed", signal.Name)
+					workflow.GetLogger(ctx).Warn("Skipping workspace: dependency failed", "workspace", name, "dependency", signal.Name)
 				}
-
-				if allDependenciesMet(ws, completedWorkspaces) {
-					startWorkspace(ctx, ws, depths, workspaceOutputs, runningWorkflows, rootFutures)
+				aborting = aborting || !config.ContinueOnError
+			} else {
+				workspaceOutputs[signal.Name] = signal.Outputs
+				sensitiveOutputs[signal.Name] = signal.SensitiveOutputs
+				if signal.Details != nil {
+					workspaceDetails[signal.Name] = *signal.Details
 				}
+				workflow.GetLogger(ctx).Info("Workspace completed", "workspace", signal.Name)
 			}
+
+			// Trigger any workspaces that are now ready
+			startReady()
 		})
 
 		selector.Select(ctx)
+		if timedOut {
+			pending := pendingWorkspaces(config, completedWorkspaces)
+			partial := OrchestrationResult{Outputs: workspaceOutputs, Details: workspaceDetails, Skipped: make(map[string]string, len(pending)), OutputRemap: config.OutputRemap}
+			for _, name := range pending {
+				partial.Skipped[name] = "pending when the orchestration timed out"
+			}
+			return OrchestrationResult{}, temporal.NewApplicationError(
+				fmt.Sprintf("orchestration timed out after %s, pending: %v", timeout, pending),
+				OrchestrationFailedErrorType, partial)
+		}
+	}
+
+	// Give hosting workflows time to finish work done after reporting
+	// completion before asking them to shut down
+	grace, err := shutdownGracePeriod(config)
+	if err != nil {
+		return OrchestrationResult{}, err
+	}
+	if grace > 0 && len(runningWorkflows) > 0 {
+		workflow.GetLogger(ctx).Info("Waiting before shutting down hosting workflows", "grace_period", grace)
+		if err := workflow.Sleep(ctx, grace); err != nil {
+			return OrchestrationResult{}, err
+		}
 	}
 
 	// Signal shutdown to all hosting workflows
@@ -87,21 +257,270 @@ func ParentWorkflow(ctx workflow.Context, rawConfig InfrastructureConfig) error
 		}
 	}
 
+	if len(failureOrder) > 0 {
+		return OrchestrationResult{}, orchestrationFailure(config, failureOrder, failedWorkspaces, skipReasons, pendingWorkspaces(config, completedWorkspaces), workspaceOutputs, workspaceDetails)
+	}
 	if firstErr != nil {
-		return firstErr
+		return OrchestrationResult{}, firstErr
+	}
+
+	result := OrchestrationResult{Outputs: workspaceOutputs, Details: workspaceDetails, OutputRemap: config.OutputRemap}
+	if config.PlanPreview != nil {
+		result.Preview = buildPlanPreview(config, workspaceOutputs, workspaceDetails, skipped)
+	}
+	result.DriftedWorkspaces = driftedWorkspaces(config, workspaceDetails)
+	if len(result.DriftedWorkspaces) > 0 {
+		workflow.GetLogger(ctx).Warn("Drift detected", "workspaces", result.DriftedWorkspaces)
 	}
 
 	workflow.GetLogger(ctx).Info("Parent workflow completed", "workspaces", len(config.Workspaces))
+	return result, nil
+}
+
+// orchestrationFailure reports the first failed workspace's error together
+// with the workspaces skipped because of a failed dependency and those never
+// started because the orchestration aborted. The outputs and details of the
+// workspaces that succeeded are attached as the error's partial result.
+func orchestrationFailure(config InfrastructureConfig, failureOrder []string, failed, skipped map[string]string, notStarted []string, outputs map[string]map[string]interface{}, details map[string]activities.WorkspaceDetails) error {
+	first := failureOrder[0]
+	msg := fmt.Sprintf("workspace %s failed: %s", first, failed[first])
+	if len(failureOrder) > 1 {
+		msg += fmt.Sprintf("; also failed: %s", strings.Join(failureOrder[1:], ", "))
+	}
+
+	var dependents []string
+	for _, ws := range config.Workspaces {
+		if _, ok := skipped[ws.Name]; ok {
+			dependents = append(dependents, ws.Name)
+		}
+	}
+	if len(dependents) > 0 {
+		msg += fmt.Sprintf("; skipped due to failed dependency: %s", strings.Join(dependents, ", "))
+	}
+	if len(notStarted) > 0 {
+		msg += fmt.Sprintf("; not started: %s", strings.Join(notStarted, ", "))
+	}
+
+	partial := OrchestrationResult{Outputs: outputs, Details: details, Failed: failed, Skipped: make(map[string]string, len(skipped)+len(notStarted)), OutputRemap: config.OutputRemap}
+	for name, reason := range skipped {
+		partial.Skipped[name] = reason
+	}
+	for _, name := range notStarted {
+		partial.Skipped[name] = "not started after an earlier failure"
+	}
+	return temporal.NewApplicationError(msg, OrchestrationFailedErrorType, partial)
+}
+
+// transitiveDependents lists, in config order, every workspace that depends
+// on name directly or transitively through dependsOn or waitFor.
+func transitiveDependents(workspaces []WorkspaceConfig, name string) []string {
+	affected := map[string]bool{name: true}
+	for changed := true; changed; {
+		changed = false
+		for _, ws := range workspaces {
+			if affected[ws.Name] {
+				continue
+			}
+			for _, dep := range orderingDependencies(ws) {
+				if affected[dep] {
+					affected[ws.Name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	var dependents []string
+	for _, ws := range workspaces {
+		if ws.Name != name && affected[ws.Name] {
+			dependents = append(dependents, ws.Name)
+		}
+	}
+	return dependents
+}
+
+// orchestrationProgress classifies every workspace for the progress query.
+// Workspaces skipped by their `when` condition count as completed.
+func orchestrationProgress(config InfrastructureConfig, completed map[string]bool, running, failed, skipped map[string]string) OrchestrationProgress {
+	progress := OrchestrationProgress{
+		Total:     len(config.Workspaces),
+		Completed: []string{},
+		Running:   []string{},
+		Pending:   []string{},
+	}
+	for _, ws := range config.Workspaces {
+		switch workspaceStatus(ws.Name, completed, running, failed, skipped) {
+		case WorkspaceStatusFailed:
+			progress.Failed = append(progress.Failed, ws.Name)
+		case WorkspaceStatusSkipped:
+			progress.Skipped = append(progress.Skipped, ws.Name)
+		case WorkspaceStatusCompleted:
+			progress.Completed = append(progress.Completed, ws.Name)
+		case WorkspaceStatusRunning:
+			progress.Running = append(progress.Running, ws.Name)
+		default:
+			progress.Pending = append(progress.Pending, ws.Name)
+		}
+	}
+	return progress
+}
+
+// orchestrationHealth builds the health query's snapshot: each workspace's
+// status, its outputs once completed, the unfinished dependencies blocking
+// it while pending, and why it failed or was skipped.
+func orchestrationHealth(
+	config InfrastructureConfig,
+	completed map[string]bool,
+	outputs map[string]map[string]interface{},
+	running, failed, skipped map[string]string,
+) OrchestrationHealth {
+	health := OrchestrationHealth{Workspaces: make([]WorkspaceHealth, 0, len(config.Workspaces))}
+	for _, ws := range config.Workspaces {
+		entry := WorkspaceHealth{
+			Name:   ws.Name,
+			Status: workspaceStatus(ws.Name, completed, running, failed, skipped),
+		}
+		switch entry.Status {
+		case WorkspaceStatusCompleted:
+			entry.Outputs = outputs[ws.Name]
+		case WorkspaceStatusPending:
+			for _, dep := range orderingDependencies(ws) {
+				if !completed[dep] {
+					entry.BlockedBy = append(entry.BlockedBy, dep)
+				}
+			}
+		case WorkspaceStatusFailed:
+			entry.Reason = failed[ws.Name]
+		case WorkspaceStatusSkipped:
+			entry.Reason = skipped[ws.Name]
+		}
+		health.Workspaces = append(health.Workspaces, entry)
+	}
+	return health
+}
+
+// workspaceStatus classifies a workspace for the progress and health
+// queries. Failed and skipped workspaces are also marked completed, and
+// hosting workflows stay running after their workspace completes, so the
+// checks are ordered.
+func workspaceStatus(name string, completed map[string]bool, running, failed, skipped map[string]string) string {
+	if _, ok := failed[name]; ok {
+		return WorkspaceStatusFailed
+	}
+	if _, ok := skipped[name]; ok {
+		return WorkspaceStatusSkipped
+	}
+	switch {
+	case completed[name]:
+		return WorkspaceStatusCompleted
+	case isRunning(name, running):
+		return WorkspaceStatusRunning
+	default:
+		return WorkspaceStatusPending
+	}
+}
+
+// pendingWorkspaces lists, in config order, the workspaces that haven't completed.
+func pendingWorkspaces(config InfrastructureConfig, completed map[string]bool) []string {
+	var pending []string
+	for _, ws := range config.Workspaces {
+		if !completed[ws.Name] {
+			pending = append(pending, ws.Name)
+		}
+	}
+	return pending
+}
+
+// reconcileWorkspaces rebuilds orchestration state from workspace workflows
+// started by a previous run, found via their deterministic IDs. Finished
+// workspaces are marked completed with their outputs and details; running
+// ones are tracked so they aren't started twice and receive the final
+// shutdown signal.
+func reconcileWorkspaces(
+	ctx workflow.Context,
+	config InfrastructureConfig,
+	completedWorkspaces map[string]bool,
+	workspaceOutputs map[string]map[string]interface{},
+	sensitiveOutputs map[string][]string,
+	workspaceDetails map[string]activities.WorkspaceDetails,
+	runningWorkflows map[string]string,
+) error {
+	orchestrationID := workflow.GetInfo(ctx).WorkflowExecution.ID
+	ids := make(map[string]string, len(config.Workspaces))
+	for _, ws := range config.Workspaces {
+		if completedWorkspaces[ws.Name] {
+			continue
+		}
+		ids[ws.Name] = childWorkflowID(orchestrationID, ws.Name)
+	}
+
+	actx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 1 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 3,
+		},
+	})
+
+	var oa *activities.OrchestrationActivities
+	var states map[string]activities.WorkspaceState
+	params := activities.ReconcileParams{WorkflowIDs: ids, QueryType: QueryWorkspaceState}
+	if err := workflow.ExecuteActivity(actx, oa.ReconcileWorkspaces, params).Get(ctx, &states); err != nil {
+		return fmt.Errorf("failed to reconcile workspaces: %w", err)
+	}
+
+	for _, ws := range config.Workspaces {
+		state, ok := states[ws.Name]
+		if !ok {
+			continue
+		}
+		if state.Running {
+			runningWorkflows[ws.Name] = ids[ws.Name]
+		}
+		if state.Finished {
+			completedWorkspaces[ws.Name] = true
+			workspaceOutputs[ws.Name] = state.Outputs
+			sensitiveOutputs[ws.Name] = state.SensitiveOutputs
+			if state.Details != nil {
+				workspaceDetails[ws.Name] = *state.Details
+			}
+		}
+		workflow.GetLogger(ctx).Info("Reconciled workspace from previous run",
+			"workspace", ws.Name,
+			"running", state.Running,
+			"finished", state.Finished,
+		)
+	}
 	return nil
 }
 
+// childWorkflowID derives a workspace's workflow ID from the orchestration's
+// workflow ID. Unlike the RunID, the workflow ID survives retries and
+// continue-as-new, so re-executing an orchestration addresses the same children.
+func childWorkflowID(orchestrationID, workspace string) string {
+	return fmt.Sprintf("iac-%s-%s", orchestrationID, workspace)
+}
+
 func isRunning(name string, running map[string]string) bool {
 	_, ok := running[name]
 	return ok
 }
 
+// inFlight counts started workspaces that haven't finished. Hosting workflows
+// keep running after their workspace finishes, so runningWorkflows alone
+// overcounts.
+func inFlight(running map[string]string, completed map[string]bool) int {
+	n := 0
+	for name := range running {
+		if !completed[name] {
+			n++
+		}
+	}
+	return n
+}
+
 func allDependenciesMet(ws WorkspaceConfig, completed map[string]bool) bool {
-	for _, dep := range ws.DependsOn {
+	for _, dep := range orderingDependencies(ws) {
 		if !completed[dep] {
 			return false
 		}
@@ -114,6 +533,7 @@ func startWorkspace(
 	ws WorkspaceConfig,
 	depths map[string]int,
 	workspaceOutputs map[string]map[string]interface{},
+	sensitiveOutputs map[string][]string,
 	runningWorkflows map[string]string,
 	rootFutures map[string]workflow.ChildWorkflowFuture,
 ) {
@@ -122,26 +542,36 @@ func startWorkspace(
 		if ws.ExtraVars == nil {
 			ws.ExtraVars = make(map[string]interface{})
 		}
+		var sensitive []string
 		for _, mapping := range ws.Inputs {
 			sourceOuts := workspaceOutputs[mapping.SourceWorkspace]
 			if val, ok := sourceOuts[mapping.SourceOutput]; ok {
 				// Preserve the original JSON type (string, array, object, etc.)
 				ws.ExtraVars[mapping.TargetVar] = val
 			}
+			// A sensitive output stays redacted in the dependent's effective vars
+			if slices.Contains(sensitiveOutputs[mapping.SourceWorkspace], mapping.SourceOutput) {
+				sensitive = append(sensitive, mapping.TargetVar)
+			}
+		}
+		if len(sensitive) > 0 {
+			ws.SensitiveVars = append(slices.Clone(ws.SensitiveVars), sensitive...)
 		}
 	}
 
 	// 2. Determine if we should nest or start a new root
-	if len(ws.DependsOn) > 0 {
-		// Nest under the "deepest" dependency to maintain a logical hierarchy
-		hostName := ws.DependsOn[0]
-		maxDepth := depths[hostName]
-		for _, dep := range ws.DependsOn {
-			if depths[dep] > maxDepth {
-				maxDepth = depths[dep]
-				hostName = dep
-			}
+	// Nest under the "deepest" running dependency to maintain a logical hierarchy;
+	// dependencies that never ran (skipped by a condition) can't host children.
+	hostName := ""
+	for _, dep := range ws.DependsOn {
+		if !isRunning(dep, runningWorkflows) {
+			continue
+		}
+		if hostName == "" || depths[dep] > depths[hostName] {
+			hostName = dep
 		}
+	}
+	if hostName != "" {
 		hostID := runningWorkflows[hostName]
 
 		// Signal host to start child
@@ -150,8 +580,7 @@ func startWorkspace(
 		}).Get(ctx, nil)
 
 		if err == nil {
-			info := workflow.GetInfo(ctx)
-			childID := fmt.Sprintf("iac-%s-%s", info.WorkflowExecution.RunID, ws.Name)
+			childID := childWorkflowID(workflow.GetInfo(ctx).WorkflowExecution.ID, ws.Name)
 			runningWorkflows[ws.Name] = childID
 			return
 		}
@@ -164,8 +593,7 @@ func startWorkspace(
 	}
 
 	// 3. Start as root workflow (either no deps, or signal failed)
-	info := workflow.GetInfo(ctx)
-	childID := fmt.Sprintf("iac-%s-%s", info.WorkflowExecution.RunID, ws.Name)
+	childID := childWorkflowID(workflow.GetInfo(ctx).WorkflowExecution.ID, ws.Name)
 
 	childOptions := workflow.ChildWorkflowOptions{
 		WorkflowID: childID,
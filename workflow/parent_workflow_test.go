@@ -1,12 +1,17 @@
 package workflow
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/fakoli/temporal-terraform-orchestrator/activities"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/testsuite"
 	"go.temporal.io/sdk/workflow"
 )
@@ -203,6 +208,65 @@ func TestParentWorkflow_OutputPropagation(t *testing.T) {
 	require.Equal(t, "10.0.0.0/16", subnetsWS.ExtraVars["cidr_block"])
 }
 
+func TestParentWorkflow_SensitiveOutputsStaySensitiveInDependents(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	var app WorkspaceConfig
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		signal := WorkspaceFinishedSignal{Name: ws.Name, Outputs: map[string]interface{}{}}
+		if ws.Name == "db" {
+			signal.Outputs = map[string]interface{}{"endpoint": "db.internal:5432", "master_password": "hunter2"}
+			signal.SensitiveOutputs = []string{"master_password"}
+		} else {
+			app = ws
+		}
+		env.SignalWorkflow(SignalWorkspaceFinished, signal)
+		return signal.Outputs, nil
+	}
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("fallback"))
+
+	cfg := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "db", Dir: "/tmp/db"},
+			{
+				Name:                 "app",
+				Dir:                  "/tmp/app",
+				DependsOn:            []string{"db"},
+				IncludeEffectiveVars: true,
+				SensitiveVars:        []string{"api_key"},
+				Inputs: []InputMapping{
+					{SourceWorkspace: "db", SourceOutput: "endpoint", TargetVar: "db_endpoint"},
+					{SourceWorkspace: "db", SourceOutput: "master_password", TargetVar: "db_auth"},
+				},
+			},
+		},
+	}
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	// The value still reaches terraform, but the name doesn't look secret,
+	// so only the upstream sensitivity keeps it out of effective vars
+	require.Equal(t, "hunter2", app.ExtraVars["db_auth"])
+	require.Equal(t, []string{"api_key", "db_auth"}, app.SensitiveVars)
+	require.Equal(t, []string{"api_key"}, cfg.Workspaces[1].SensitiveVars, "the config is left alone")
+
+	effective, err := (&activities.TerraformActivities{}).TerraformEffectiveVars(context.Background(), activities.TerraformParams{
+		Dir:           t.TempDir(),
+		Vars:          app.ExtraVars,
+		SensitiveVars: app.SensitiveVars,
+		RunID:         "sensitive-inputs",
+		TempRoot:      t.TempDir(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, activities.RedactedValue, effective["db_auth"])
+	require.Equal(t, "db.internal:5432", effective["db_endpoint"])
+}
+
 func TestParentWorkflow_InvalidConfigAtRuntime(t *testing.T) {
 	suite := &testsuite.WorkflowTestSuite{}
 	env := suite.NewTestWorkflowEnvironment()
@@ -222,6 +286,165 @@ func TestParentWorkflow_InvalidConfigAtRuntime(t *testing.T) {
 	require.Contains(t, env.GetWorkflowError().Error(), "cycle")
 }
 
+func TestParentWorkflow_FailsOnOverlappingCIDRs(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	started := false
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		started = true
+		return nil, nil
+	}
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+	a := &activities.TerraformActivities{}
+	env.RegisterActivity(a.CheckCIDROverlaps)
+
+	cfg := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc-a", Dir: "/tmp/a", Vars: map[string]interface{}{"cidr": "10.0.0.0/16"}, CIDRVars: []string{"cidr"}},
+			{Name: "vpc-b", Dir: "/tmp/b", Vars: map[string]interface{}{"cidr": "10.0.32.0/19"}, CIDRVars: []string{"cidr"}},
+		},
+	}
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.Error(t, env.GetWorkflowError())
+	require.Contains(t, env.GetWorkflowError().Error(), "overlapping CIDRs: vpc-a.cidr (10.0.0.0/16) overlaps vpc-b.cidr (10.0.32.0/19)")
+	require.False(t, started)
+}
+
+func TestParentWorkflow_DestroySkipsCIDROverlapCheck(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	var started []string
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		started = append(started, ws.Name)
+		env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{Name: ws.Name, Outputs: map[string]interface{}{}})
+		return nil, nil
+	}
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+	a := &activities.TerraformActivities{}
+	env.RegisterActivity(a.CheckCIDROverlaps)
+
+	// Overlapping ranges must not block tearing them down
+	cfg := InfrastructureConfig{
+		Destroy: true,
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc-a", Dir: "/tmp/a", Vars: map[string]interface{}{"cidr": "10.0.0.0/16"}, CIDRVars: []string{"cidr"}},
+			{Name: "vpc-b", Dir: "/tmp/b", Vars: map[string]interface{}{"cidr": "10.0.32.0/19"}, CIDRVars: []string{"cidr"}},
+		},
+	}
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.ElementsMatch(t, []string{"vpc-a", "vpc-b"}, started)
+}
+
+func TestParentWorkflow_BackendCheckErrorFailsOnLocalState(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	started := false
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		started = true
+		return nil, nil
+	}
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+
+	// Only workspaces that apply and aren't exempt are checked
+	a := &activities.TerraformActivities{}
+	env.OnActivity(a.CheckLocalState, mock.Anything, mock.MatchedBy(func(sources []activities.BackendSource) bool {
+		return len(sources) == 2 && sources[0].Workspace == "vpc" && sources[1].Workspace == "scratch"
+	})).Return([]string{"scratch"}, nil).Once()
+
+	cfg := InfrastructureConfig{
+		BackendCheck: BackendCheckError,
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "scratch", Dir: "/tmp/scratch"},
+			{Name: "sandbox", Dir: "/tmp/sandbox", AllowLocalState: true},
+			{Name: "drift", Dir: "/tmp/drift", Operations: []string{"init", "validate", "plan"}},
+		},
+	}
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.Error(t, env.GetWorkflowError())
+	require.Contains(t, env.GetWorkflowError().Error(), "workspaces apply with local state: scratch")
+	require.False(t, started)
+	env.AssertExpectations(t)
+}
+
+func TestParentWorkflow_BackendCheckWarnContinues(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	var started []string
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		started = append(started, ws.Name)
+		env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{Name: ws.Name})
+		return nil, nil
+	}
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+
+	a := &activities.TerraformActivities{}
+	env.OnActivity(a.CheckLocalState, mock.Anything, mock.Anything).Return([]string{"scratch"}, nil).Once()
+
+	cfg := InfrastructureConfig{
+		BackendCheck: BackendCheckWarn,
+		Workspaces:   []WorkspaceConfig{{Name: "scratch", Dir: "/tmp/scratch"}},
+	}
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.Equal(t, []string{"scratch"}, started)
+	env.AssertExpectations(t)
+}
+
+func TestParentWorkflow_RetryBudgetPassesRetriesLeft(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	// Each workspace succeeds; vpc and dns burn retries on the way
+	retries := map[string]int{"vpc": 4, "dns": 2, "iam": 0}
+	budgets := make(map[string]int)
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		budgets[ws.Name] = *ws.RetriesLeft
+		env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{
+			Name:    ws.Name,
+			Outputs: map[string]interface{}{},
+			Retries: retries[ws.Name],
+		})
+		return nil, nil
+	}
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("fallback"))
+
+	cfg := InfrastructureConfig{
+		RetryBudget:    5,
+		MaxConcurrency: 1,
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "dns", Dir: "/tmp/dns"},
+			{Name: "iam", Dir: "/tmp/iam"},
+		},
+	}
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+
+	// A spent budget only stops retries; iam still runs, with none left
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.Equal(t, map[string]int{"vpc": 5, "dns": 1, "iam": 0}, budgets)
+}
+
 func TestParentWorkflow_EmptyWorkspaceList(t *testing.T) {
 	suite := &testsuite.WorkflowTestSuite{}
 	env := suite.NewTestWorkflowEnvironment()
@@ -314,3 +537,795 @@ func indexOf(slice []string, value string) int {
 	}
 	return -1
 }
+
+func TestParentWorkflow_ConditionalSkip(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	var executionOrder []string
+	var mu sync.Mutex
+
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		mu.Lock()
+		executionOrder = append(executionOrder, ws.Name)
+		mu.Unlock()
+
+		env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{
+			Name:    ws.Name,
+			Outputs: map[string]interface{}{},
+		})
+		return map[string]interface{}{}, nil
+	}
+
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("fallback"))
+
+	cfg := InfrastructureConfig{
+		Vars: map[string]interface{}{"env": "dev"},
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "waf", Dir: "/tmp/waf", DependsOn: []string{"vpc"}, When: `vars.env == "prod"`},
+			{Name: "app", Dir: "/tmp/app", DependsOn: []string{"waf"}},
+		},
+	}
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	// waf is skipped but its dependent still runs
+	require.Equal(t, []string{"vpc", "app"}, executionOrder)
+}
+
+func TestParentWorkflow_ConditionalSkipDependentNeedsOutputs(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	cfg := InfrastructureConfig{
+		Vars: map[string]interface{}{"env": "dev"},
+		Workspaces: []WorkspaceConfig{
+			{Name: "waf", Dir: "/tmp/waf", When: `vars.env == "prod"`},
+			{
+				Name:      "app",
+				Dir:       "/tmp/app",
+				DependsOn: []string{"waf"},
+				Inputs: []InputMapping{
+					{SourceWorkspace: "waf", SourceOutput: "acl_arn", TargetVar: "waf_acl_arn"},
+				},
+			},
+		},
+	}
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.Error(t, env.GetWorkflowError())
+	require.Contains(t, env.GetWorkflowError().Error(), "which was skipped by its condition")
+}
+
+func TestParentWorkflow_ChildIDsDeriveFromWorkflowID(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetStartWorkflowOptions(client.StartWorkflowOptions{ID: "orchestration-1"})
+
+	childIDs := make(map[string]string)
+	var mu sync.Mutex
+
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		mu.Lock()
+		childIDs[ws.Name] = workflow.GetInfo(ctx).WorkflowExecution.ID
+		mu.Unlock()
+
+		env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{
+			Name:    ws.Name,
+			Outputs: map[string]interface{}{},
+		})
+		return map[string]interface{}{}, nil
+	}
+
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("fallback"))
+
+	cfg := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "subnets", Dir: "/tmp/subnets", DependsOn: []string{"vpc"}},
+		},
+	}
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	// IDs are based on the orchestration's workflow ID, not its RunID
+	require.Equal(t, "iac-orchestration-1-vpc", childIDs["vpc"])
+	require.Equal(t, "iac-orchestration-1-subnets", childIDs["subnets"])
+}
+
+func TestChildWorkflowID(t *testing.T) {
+	require.Equal(t, "iac-terraform-parent-workflow-eks", childWorkflowID("terraform-parent-workflow", "eks"))
+}
+
+func TestParentWorkflow_RestartReusesFinishedWorkspaces(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetStartWorkflowOptions(client.StartWorkflowOptions{ID: "orchestration-1"})
+	env.SetContinuedExecutionRunID("previous-run")
+
+	var executed []WorkspaceConfig
+	var mu sync.Mutex
+
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		mu.Lock()
+		executed = append(executed, ws)
+		mu.Unlock()
+
+		env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{
+			Name:    ws.Name,
+			Outputs: map[string]interface{}{},
+		})
+		return map[string]interface{}{}, nil
+	}
+
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("fallback"))
+
+	var oa *activities.OrchestrationActivities
+	env.OnActivity(oa.ReconcileWorkspaces, mock.Anything, activities.ReconcileParams{
+		WorkflowIDs: map[string]string{
+			"vpc":     "iac-orchestration-1-vpc",
+			"subnets": "iac-orchestration-1-subnets",
+		},
+		QueryType: QueryWorkspaceState,
+	}).Return(map[string]activities.WorkspaceState{
+		"vpc": {Finished: true, Outputs: map[string]interface{}{"vpc_id": "vpc-12345"}},
+	}, nil)
+
+	cfg := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{
+				Name:      "subnets",
+				Dir:       "/tmp/subnets",
+				DependsOn: []string{"vpc"},
+				Inputs: []InputMapping{
+					{SourceWorkspace: "vpc", SourceOutput: "vpc_id", TargetVar: "vpc_id"},
+				},
+			},
+		},
+	}
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	// vpc finished in the previous run; only subnets runs, with vpc's outputs
+	require.Len(t, executed, 1)
+	require.Equal(t, "subnets", executed[0].Name)
+	require.Equal(t, "vpc-12345", executed[0].ExtraVars["vpc_id"])
+}
+
+func TestParentWorkflow_ReturnsNamespacedOutputs(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		// Both workspaces export "id"; the result keeps them apart
+		outputs := map[string]interface{}{"id": ws.Name + "-id"}
+		env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{
+			Name:    ws.Name,
+			Outputs: outputs,
+		})
+		return outputs, nil
+	}
+
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("fallback"))
+
+	cfg := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "subnets", Dir: "/tmp/subnets", DependsOn: []string{"vpc"}},
+		},
+		OutputRemap: map[string]string{"vpc.id": "network_vpc_id"},
+	}
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var result OrchestrationResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	require.Equal(t, map[string]map[string]interface{}{
+		"vpc":     {"id": "vpc-id"},
+		"subnets": {"id": "subnets-id"},
+	}, result.Outputs)
+
+	// The remap only renames keys of the flattened view
+	require.Equal(t, map[string]interface{}{
+		"network_vpc_id": "vpc-id",
+		"subnets.id":     "subnets-id",
+	}, result.Flattened().Values)
+}
+
+func TestParentWorkflow_AggregatesDriftedWorkspaces(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	drifted := map[string]bool{"vpc": true, "eks": true}
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		outputs := map[string]interface{}{"id": ws.Name}
+		details := &activities.WorkspaceDetails{Drift: &activities.DriftResult{Drifted: drifted[ws.Name], Changed: 1}}
+		env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{Name: ws.Name, Outputs: outputs, Details: details})
+		return outputs, nil
+	}
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("fallback"))
+
+	cfg := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc", DriftCheck: true},
+			{Name: "subnets", Dir: "/tmp/subnets", DriftCheck: true, DependsOn: []string{"vpc"}},
+			{Name: "eks", Dir: "/tmp/eks", DriftCheck: true, DependsOn: []string{"subnets"}},
+		},
+	}
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var result OrchestrationResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	require.Equal(t, []string{"vpc", "eks"}, result.DriftedWorkspaces)
+
+	// Drift results stay out of the outputs dependents and callers read
+	require.Equal(t, map[string]interface{}{"id": "vpc"}, result.Outputs["vpc"])
+	require.Equal(t, map[string]interface{}{"vpc.id": "vpc", "subnets.id": "subnets", "eks.id": "eks"}, result.Flattened().Values)
+	require.True(t, result.Details["eks"].Drift.Drifted)
+}
+
+func TestParentWorkflow_WatchdogReportsPendingWorkspaces(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		if ws.Name == "eks" {
+			// Stuck workspace: never finishes within the orchestration timeout
+			if err := workflow.Sleep(ctx, 24*time.Hour); err != nil {
+				return nil, err
+			}
+		}
+		env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{
+			Name:    ws.Name,
+			Outputs: map[string]interface{}{},
+		})
+		return map[string]interface{}{}, nil
+	}
+
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("fallback"))
+
+	cfg := InfrastructureConfig{
+		Timeout: "1h",
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "eks", Dir: "/tmp/eks", DependsOn: []string{"vpc"}},
+			{Name: "apps", Dir: "/tmp/apps", DependsOn: []string{"eks"}},
+		},
+	}
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.Error(t, env.GetWorkflowError())
+	require.Contains(t, env.GetWorkflowError().Error(), "orchestration timed out after 1h0m0s, pending: [eks apps]")
+
+	partial, ok := PartialResult(env.GetWorkflowError())
+	require.True(t, ok)
+	require.Contains(t, partial.Outputs, "vpc")
+	require.Equal(t, map[string]string{
+		"eks":  "pending when the orchestration timed out",
+		"apps": "pending when the orchestration timed out",
+	}, partial.Skipped)
+}
+
+// runRecordingOrder executes ParentWorkflow with a stub TerraformWorkflow that
+// finishes immediately, returning the order workspaces ran in.
+func runRecordingOrder(t *testing.T, cfg InfrastructureConfig) []string {
+	t.Helper()
+
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	var executionOrder []string
+	var mu sync.Mutex
+
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		mu.Lock()
+		executionOrder = append(executionOrder, ws.Name)
+		mu.Unlock()
+
+		env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{Name: ws.Name, Outputs: map[string]interface{}{}})
+		return map[string]interface{}{}, nil
+	}
+
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("fallback"))
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	return executionOrder
+}
+
+func TestParentWorkflow_PriorityOrdersReadyWorkspaces(t *testing.T) {
+	order := runRecordingOrder(t, InfrastructureConfig{
+		MaxConcurrency: 1,
+		Workspaces: []WorkspaceConfig{
+			{Name: "logging", Dir: "/tmp/logging"},
+			{Name: "vpc", Dir: "/tmp/vpc", Priority: 10},
+			{Name: "dns", Dir: "/tmp/dns", Priority: 5},
+			{Name: "eks", Dir: "/tmp/eks", DependsOn: []string{"vpc"}, Priority: 1},
+		},
+	})
+
+	// eks becomes ready after vpc and outranks logging, but not dns
+	require.Equal(t, []string{"vpc", "dns", "eks", "logging"}, order)
+}
+
+func TestParentWorkflow_PriorityTiesKeepConfigOrder(t *testing.T) {
+	order := runRecordingOrder(t, InfrastructureConfig{
+		MaxConcurrency: 1,
+		Workspaces: []WorkspaceConfig{
+			{Name: "c", Dir: "/tmp/c", Priority: 1},
+			{Name: "a", Dir: "/tmp/a"},
+			{Name: "b", Dir: "/tmp/b", Priority: 1},
+			{Name: "d", Dir: "/tmp/d"},
+		},
+	})
+
+	require.Equal(t, []string{"c", "b", "a", "d"}, order)
+}
+
+func TestParentWorkflow_WaitForOrdersWithoutNesting(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	var executionOrder []string
+	var mu sync.Mutex
+
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		mu.Lock()
+		executionOrder = append(executionOrder, ws.Name)
+		mu.Unlock()
+
+		env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{Name: ws.Name, Outputs: map[string]interface{}{}})
+		return map[string]interface{}{}, nil
+	}
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+
+	// Only shutdown signals are expected: a start-child signal would mean
+	// app was nested under lock
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, SignalShutdown, mock.Anything).Return(nil)
+
+	cfg := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "app", Dir: "/tmp/app", WaitFor: []string{"lock"}},
+			{Name: "lock", Dir: "/tmp/lock"},
+		},
+	}
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.Equal(t, []string{"lock", "app"}, executionOrder)
+}
+
+func TestParentWorkflow_DestroyReversesOrder(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	var executionOrder []string
+	operations := make(map[string][]string)
+	var mu sync.Mutex
+
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		mu.Lock()
+		executionOrder = append(executionOrder, ws.Name)
+		operations[ws.Name] = ws.Operations
+		mu.Unlock()
+
+		env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{Name: ws.Name, Outputs: map[string]interface{}{}})
+		return map[string]interface{}{}, nil
+	}
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+
+	// Every workspace runs as a root: nesting follows dependsOn, which has
+	// no meaning once the graph is reversed
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, SignalShutdown, mock.Anything).Return(nil)
+
+	cfg := InfrastructureConfig{
+		Destroy: true,
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "subnets", Dir: "/tmp/subnets", DependsOn: []string{"vpc"}, Inputs: []InputMapping{
+				{SourceWorkspace: "vpc", SourceOutput: "vpc_id", TargetVar: "vpc_id"},
+			}},
+			{Name: "eks", Dir: "/tmp/eks", DependsOn: []string{"subnets"}},
+		},
+	}
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.Equal(t, []string{"eks", "subnets", "vpc"}, executionOrder)
+	for _, name := range executionOrder {
+		require.Equal(t, []string{"init", "validate", "destroy"}, operations[name])
+	}
+}
+
+func TestParentWorkflow_SpeculativePlanIgnoresDependencies(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	startedAt := make(map[string]time.Time)
+	operations := make(map[string][]string)
+	extraVars := make(map[string]map[string]interface{})
+	var mu sync.Mutex
+
+	// Each plan takes a minute; with dependencies honored eks would start
+	// two minutes after vpc
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		mu.Lock()
+		startedAt[ws.Name] = workflow.Now(ctx)
+		operations[ws.Name] = ws.Operations
+		extraVars[ws.Name] = ws.ExtraVars
+		mu.Unlock()
+
+		if err := workflow.Sleep(ctx, time.Minute); err != nil {
+			return nil, err
+		}
+		env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{Name: ws.Name, Outputs: map[string]interface{}{"id": ws.Name}})
+		return map[string]interface{}{}, nil
+	}
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, SignalShutdown, mock.Anything).Return(nil)
+
+	cfg := InfrastructureConfig{
+		SpeculativePlan: true,
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "subnets", Dir: "/tmp/subnets", DependsOn: []string{"vpc"}, Inputs: []InputMapping{
+				{SourceWorkspace: "vpc", SourceOutput: "id", TargetVar: "vpc_id"},
+			}},
+			{Name: "eks", Dir: "/tmp/eks", DependsOn: []string{"subnets"}, WaitFor: []string{"vpc"}},
+		},
+	}
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	require.Len(t, startedAt, 3)
+	for _, name := range []string{"subnets", "eks"} {
+		require.Equal(t, startedAt["vpc"], startedAt[name], "%s waited on a dependency", name)
+		require.Empty(t, extraVars[name], "%s resolved inputs", name)
+	}
+	for name, ops := range operations {
+		require.Equal(t, []string{"init", "validate", "plan"}, ops, name)
+	}
+}
+
+func TestParentWorkflow_ShutdownWaitsGracePeriod(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	// The stub hosts like TerraformWorkflow: it reports completion, then
+	// stays up until told to shut down
+	var finishedAt, shutdownAt time.Time
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		finishedAt = workflow.Now(ctx)
+		env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{Name: ws.Name, Outputs: map[string]interface{}{}})
+		workflow.GetSignalChannel(ctx, SignalShutdown).Receive(ctx, nil)
+		shutdownAt = workflow.Now(ctx)
+		return map[string]interface{}{}, nil
+	}
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+
+	env.ExecuteWorkflow(ParentWorkflow, InfrastructureConfig{
+		ShutdownGracePeriod: "45s",
+		Workspaces:          []WorkspaceConfig{{Name: "vpc", Dir: "/tmp/vpc"}},
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	require.False(t, shutdownAt.IsZero(), "shutdown was never signalled")
+	require.GreaterOrEqual(t, shutdownAt.Sub(finishedAt), 45*time.Second)
+}
+
+func TestParentWorkflow_ProgressQuery(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		if ws.Name == "vpc" {
+			if err := workflow.Sleep(ctx, time.Minute); err != nil {
+				return nil, err
+			}
+		}
+		env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{Name: ws.Name, Outputs: map[string]interface{}{}})
+		return map[string]interface{}{}, nil
+	}
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("fallback"))
+
+	var midway OrchestrationProgress
+	env.RegisterDelayedCallback(func() {
+		value, err := env.QueryWorkflow(QueryProgress)
+		require.NoError(t, err)
+		require.NoError(t, value.Get(&midway))
+	}, 30*time.Second)
+
+	env.ExecuteWorkflow(ParentWorkflow, InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "subnets", Dir: "/tmp/subnets", DependsOn: []string{"vpc"}},
+			{Name: "logging", Dir: "/tmp/logging"},
+		},
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	require.Equal(t, OrchestrationProgress{
+		Total:     3,
+		Completed: []string{"logging"},
+		Running:   []string{"vpc"},
+		Pending:   []string{"subnets"},
+	}, midway)
+
+	value, err := env.QueryWorkflow(QueryProgress)
+	require.NoError(t, err)
+	var final OrchestrationProgress
+	require.NoError(t, value.Get(&final))
+	require.Equal(t, []string{"vpc", "subnets", "logging"}, final.Completed)
+	require.Empty(t, final.Running)
+	require.Empty(t, final.Pending)
+}
+
+func TestOrchestrationProgress_FailedAndSkipped(t *testing.T) {
+	cfg := failureConfig()
+	progress := orchestrationProgress(cfg,
+		map[string]bool{"vpc": true, "subnets": true, "eks": true, "logging": true},
+		map[string]string{"vpc": "id-vpc", "logging": "id-logging", "audit": "id-audit"},
+		map[string]string{"vpc": "plan failed"},
+		map[string]string{"subnets": "dependency vpc failed", "eks": "dependency vpc failed"},
+	)
+
+	require.Equal(t, OrchestrationProgress{
+		Total:     5,
+		Completed: []string{"logging"},
+		Running:   []string{"audit"},
+		Pending:   []string{},
+		Failed:    []string{"vpc"},
+		Skipped:   []string{"subnets", "eks"},
+	}, progress)
+}
+
+func TestParentWorkflow_HealthQuery(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		if ws.Name == "subnets" {
+			if err := workflow.Sleep(ctx, time.Minute); err != nil {
+				return nil, err
+			}
+		}
+		outputs := map[string]interface{}{"id": ws.Name + "-id"}
+		env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{Name: ws.Name, Outputs: outputs})
+		return outputs, nil
+	}
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("fallback"))
+
+	var midway OrchestrationHealth
+	env.RegisterDelayedCallback(func() {
+		value, err := env.QueryWorkflow(QueryHealth)
+		require.NoError(t, err)
+		require.NoError(t, value.Get(&midway))
+	}, 30*time.Second)
+
+	env.ExecuteWorkflow(ParentWorkflow, InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "subnets", Dir: "/tmp/subnets", DependsOn: []string{"vpc"}},
+			{Name: "eks", Dir: "/tmp/eks", DependsOn: []string{"vpc", "subnets"}},
+			{Name: "dns", Dir: "/tmp/dns", WaitFor: []string{"eks"}},
+		},
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	require.Equal(t, OrchestrationHealth{Workspaces: []WorkspaceHealth{
+		{Name: "vpc", Status: WorkspaceStatusCompleted, Outputs: map[string]interface{}{"id": "vpc-id"}},
+		{Name: "subnets", Status: WorkspaceStatusRunning},
+		{Name: "eks", Status: WorkspaceStatusPending, BlockedBy: []string{"subnets"}},
+		{Name: "dns", Status: WorkspaceStatusPending, BlockedBy: []string{"eks"}},
+	}}, midway)
+}
+
+func TestOrchestrationHealth_FailedAndSkipped(t *testing.T) {
+	cfg := failureConfig()
+	health := orchestrationHealth(cfg,
+		map[string]bool{"vpc": true, "subnets": true, "eks": true, "logging": true},
+		map[string]map[string]interface{}{"logging": {"bucket": "logs"}},
+		map[string]string{"vpc": "id-vpc", "logging": "id-logging", "audit": "id-audit"},
+		map[string]string{"vpc": "plan failed"},
+		map[string]string{"subnets": "dependency vpc failed", "eks": "dependency vpc failed"},
+	)
+
+	byName := make(map[string]WorkspaceHealth)
+	for _, ws := range health.Workspaces {
+		byName[ws.Name] = ws
+	}
+	require.Len(t, health.Workspaces, 5)
+	require.Equal(t, WorkspaceHealth{Name: "vpc", Status: WorkspaceStatusFailed, Reason: "plan failed"}, byName["vpc"])
+	require.Equal(t, WorkspaceHealth{Name: "eks", Status: WorkspaceStatusSkipped, Reason: "dependency vpc failed"}, byName["eks"])
+	require.Equal(t, map[string]interface{}{"bucket": "logs"}, byName["logging"].Outputs)
+	require.Equal(t, WorkspaceStatusRunning, byName["audit"].Status)
+}
+
+// runWithFailure executes ParentWorkflow with a stub TerraformWorkflow that
+// fails the named workspace, returning the execution order and workflow error.
+func runWithFailure(t *testing.T, cfg InfrastructureConfig, failing string) ([]string, error) {
+	t.Helper()
+
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	var executionOrder []string
+	var mu sync.Mutex
+
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		mu.Lock()
+		executionOrder = append(executionOrder, ws.Name)
+		mu.Unlock()
+
+		if ws.Name == failing {
+			env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{Name: ws.Name, Error: "plan failed"})
+			return nil, errors.New("plan failed")
+		}
+		env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{Name: ws.Name, Outputs: map[string]interface{}{"id": ws.Name}})
+		return map[string]interface{}{}, nil
+	}
+
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(fmt.Errorf("fallback"))
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+	require.True(t, env.IsWorkflowCompleted())
+	return executionOrder, env.GetWorkflowError()
+}
+
+func failureConfig() InfrastructureConfig {
+	return InfrastructureConfig{
+		MaxConcurrency: 1,
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "subnets", Dir: "/tmp/subnets", DependsOn: []string{"vpc"}},
+			{Name: "eks", Dir: "/tmp/eks", WaitFor: []string{"subnets"}},
+			{Name: "logging", Dir: "/tmp/logging"},
+			{Name: "audit", Dir: "/tmp/audit", DependsOn: []string{"logging"}},
+		},
+	}
+}
+
+func TestParentWorkflow_FailedDependencyAbortsByDefault(t *testing.T) {
+	order, err := runWithFailure(t, failureConfig(), "vpc")
+
+	require.Equal(t, []string{"vpc"}, order)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "workspace vpc failed: plan failed")
+	require.Contains(t, err.Error(), "skipped due to failed dependency: subnets, eks")
+	require.Contains(t, err.Error(), "not started: logging, audit")
+}
+
+func TestParentWorkflow_ContinueOnErrorRunsUnrelatedBranches(t *testing.T) {
+	cfg := failureConfig()
+	cfg.ContinueOnError = true
+
+	order, err := runWithFailure(t, cfg, "vpc")
+
+	require.Equal(t, []string{"vpc", "logging", "audit"}, order)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "workspace vpc failed: plan failed")
+	require.Contains(t, err.Error(), "skipped due to failed dependency: subnets, eks")
+	require.NotContains(t, err.Error(), "not started")
+}
+
+func TestParentWorkflow_ContinueOnErrorDiamond(t *testing.T) {
+	// network -> {left, right} -> app -> dns, plus an independent monitoring
+	cfg := InfrastructureConfig{
+		MaxConcurrency:  1,
+		ContinueOnError: true,
+		Workspaces: []WorkspaceConfig{
+			{Name: "network", Dir: "/tmp/network"},
+			{Name: "left", Dir: "/tmp/left", DependsOn: []string{"network"}},
+			{Name: "right", Dir: "/tmp/right", DependsOn: []string{"network"}},
+			{Name: "app", Dir: "/tmp/app", DependsOn: []string{"left", "right"}},
+			{Name: "dns", Dir: "/tmp/dns", DependsOn: []string{"app"}},
+			{Name: "monitoring", Dir: "/tmp/monitoring"},
+		},
+	}
+
+	order, err := runWithFailure(t, cfg, "left")
+
+	// The sibling branch and the unrelated root still run; the join and everything below it don't
+	require.ElementsMatch(t, []string{"network", "left", "right", "monitoring"}, order)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "workspace left failed: plan failed")
+	require.Contains(t, err.Error(), "skipped due to failed dependency: app, dns")
+
+	partial, ok := PartialResult(err)
+	require.True(t, ok)
+	require.Equal(t, map[string]string{"left": "plan failed"}, partial.Failed)
+	require.Equal(t, map[string]string{
+		"app": "dependency left failed",
+		"dns": "dependency left failed",
+	}, partial.Skipped)
+	require.Equal(t, map[string]map[string]interface{}{
+		"network":    {"id": "network"},
+		"right":      {"id": "right"},
+		"monitoring": {"id": "monitoring"},
+	}, partial.Outputs)
+}
+
+func TestParentWorkflow_FailureReturnsPartialResults(t *testing.T) {
+	cfg := failureConfig()
+	cfg.ContinueOnError = true
+
+	_, err := runWithFailure(t, cfg, "subnets")
+	require.Error(t, err)
+
+	partial, ok := PartialResult(err)
+	require.True(t, ok)
+	require.Equal(t, map[string]map[string]interface{}{
+		"vpc":     {"id": "vpc"},
+		"logging": {"id": "logging"},
+		"audit":   {"id": "audit"},
+	}, partial.Outputs)
+	require.Equal(t, map[string]string{"subnets": "plan failed"}, partial.Failed)
+	require.Equal(t, map[string]string{"eks": "dependency subnets failed"}, partial.Skipped)
+}
+
+func TestParentWorkflow_AbortedPartialResultListsNotStarted(t *testing.T) {
+	_, err := runWithFailure(t, failureConfig(), "vpc")
+	require.Error(t, err)
+
+	partial, ok := PartialResult(err)
+	require.True(t, ok)
+	require.Empty(t, partial.Outputs)
+	require.Equal(t, map[string]string{"vpc": "plan failed"}, partial.Failed)
+	require.Equal(t, map[string]string{
+		"subnets": "dependency vpc failed",
+		"eks":     "dependency vpc failed",
+		"logging": "not started after an earlier failure",
+		"audit":   "not started after an earlier failure",
+	}, partial.Skipped)
+}
+
+func TestTransitiveDependents(t *testing.T) {
+	cfg := failureConfig()
+	require.Equal(t, []string{"subnets", "eks"}, transitiveDependents(cfg.Workspaces, "vpc"))
+	require.Equal(t, []string{"audit"}, transitiveDependents(cfg.Workspaces, "logging"))
+	require.Empty(t, transitiveDependents(cfg.Workspaces, "eks"))
+}
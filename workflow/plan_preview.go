@@ -0,0 +1,56 @@
+package workflow
+
+import "github.com/fakoli/temporal-terraform-orchestrator/activities"
+
+// PlanPreviewConfig turns an orchestration into a plan-only preview whose
+// result aggregates every workspace's plan, e.g. for a pull request comment.
+type PlanPreviewConfig struct {
+	// IncludeText adds each workspace's rendered plan to the preview.
+	IncludeText bool `json:"includeText,omitempty" yaml:"includeText,omitempty"`
+}
+
+// PlanPreview aggregates the plans of a plan-only orchestration.
+type PlanPreview struct {
+	Workspaces []WorkspacePlanPreview `json:"workspaces"`
+
+	// Totals across all workspaces
+	Add     int `json:"add"`
+	Change  int `json:"change"`
+	Destroy int `json:"destroy"`
+}
+
+// WorkspacePlanPreview is one workspace's plan. Changes is false for plans
+// with nothing to do; workspaces skipped by their `when` condition are left out.
+type WorkspacePlanPreview struct {
+	Name    string `json:"name"`
+	Changes bool   `json:"changes"`
+	Add     int    `json:"add"`
+	Change  int    `json:"change"`
+	Destroy int    `json:"destroy"`
+	Text    string `json:"text,omitempty"`
+}
+
+// buildPlanPreview collects the plan summaries captured in the workspace
+// details, in config order. Workspaces whose plan had no changes have no
+// summary; those without outputs failed or never ran and are left out.
+func buildPlanPreview(config InfrastructureConfig, outputs map[string]map[string]interface{}, details map[string]activities.WorkspaceDetails, skipped map[string]bool) *PlanPreview {
+	preview := &PlanPreview{Workspaces: []WorkspacePlanPreview{}}
+	for _, ws := range config.Workspaces {
+		if _, ok := outputs[ws.Name]; !ok || skipped[ws.Name] {
+			continue
+		}
+		entry := WorkspacePlanPreview{Name: ws.Name}
+		if summary := details[ws.Name].Plan; summary != nil {
+			entry.Changes = true
+			entry.Add, entry.Change, entry.Destroy = summary.Add, summary.Change, summary.Destroy
+			if config.PlanPreview.IncludeText {
+				entry.Text = summary.Text
+			}
+		}
+		preview.Add += entry.Add
+		preview.Change += entry.Change
+		preview.Destroy += entry.Destroy
+		preview.Workspaces = append(preview.Workspaces, entry)
+	}
+	return preview
+}
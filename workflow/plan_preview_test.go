@@ -0,0 +1,87 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/fakoli/temporal-terraform-orchestrator/activities"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestParentWorkflow_PlanPreviewAggregatesSummaries(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	summaries := map[string]activities.PlanSummary{
+		"vpc": {Text: "vpc plan", Add: 3},
+		"eks": {Text: "eks plan", Add: 1, Change: 2, Destroy: 1},
+	}
+	var started []WorkspaceConfig
+	stubWF := func(ctx workflow.Context, ws WorkspaceConfig) (map[string]interface{}, error) {
+		started = append(started, ws)
+		outputs := map[string]interface{}{"id": ws.Name}
+		// Like TerraformWorkflow, a summary is only captured when the plan has changes
+		var details *activities.WorkspaceDetails
+		if summary, ok := summaries[ws.Name]; ok {
+			details = &activities.WorkspaceDetails{Plan: &summary}
+		}
+		env.SignalWorkflow(SignalWorkspaceFinished, WorkspaceFinishedSignal{Name: ws.Name, Outputs: outputs, Details: details})
+		return outputs, nil
+	}
+	env.RegisterWorkflowWithOptions(stubWF, workflow.RegisterOptions{Name: "TerraformWorkflow"})
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	cfg := InfrastructureConfig{
+		Vars:        map[string]interface{}{"env": "dev"},
+		PlanPreview: &PlanPreviewConfig{IncludeText: true},
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "subnets", Dir: "/tmp/subnets", DependsOn: []string{"vpc"}},
+			{Name: "eks", Dir: "/tmp/eks", DependsOn: []string{"subnets"}},
+			{Name: "waf", Dir: "/tmp/waf", When: `vars.env == "prod"`},
+		},
+	}
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	for _, ws := range started {
+		require.True(t, ws.CapturePlan, ws.Name)
+		require.Equal(t, []string{"init", "validate", "plan"}, ws.Operations, ws.Name)
+	}
+
+	var result OrchestrationResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	require.Equal(t, &PlanPreview{
+		Workspaces: []WorkspacePlanPreview{
+			{Name: "vpc", Changes: true, Add: 3, Text: "vpc plan"},
+			{Name: "subnets"},
+			{Name: "eks", Changes: true, Add: 1, Change: 2, Destroy: 1, Text: "eks plan"},
+		},
+		Add:     4,
+		Change:  2,
+		Destroy: 1,
+	}, result.Preview)
+}
+
+func TestBuildPlanPreview_OmitsTextByDefault(t *testing.T) {
+	cfg := InfrastructureConfig{
+		PlanPreview: &PlanPreviewConfig{},
+		Workspaces:  []WorkspaceConfig{{Name: "vpc"}, {Name: "eks"}},
+	}
+	outputs := map[string]map[string]interface{}{"vpc": {}}
+	details := map[string]activities.WorkspaceDetails{
+		"vpc": {Plan: &activities.PlanSummary{Text: "plan", Add: 2, Destroy: 1}},
+	}
+
+	preview := buildPlanPreview(cfg, outputs, details, nil)
+	require.Equal(t, &PlanPreview{
+		Workspaces: []WorkspacePlanPreview{{Name: "vpc", Changes: true, Add: 2, Destroy: 1}},
+		Add:        2,
+		Destroy:    1,
+	}, preview, "workspaces without outputs (failed or not run) are left out")
+}
@@ -0,0 +1,19 @@
+package workflow
+
+import (
+	"github.com/fakoli/temporal-terraform-orchestrator/activities"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+)
+
+// RegisterWorker registers every orchestrator workflow and activity on w.
+// The worker binary and the integration tests share it so they can't drift.
+// terraform carries the worker's operator settings, such as its plan store.
+func RegisterWorker(w worker.Registry, c client.Client, terraform *activities.TerraformActivities) {
+	w.RegisterWorkflow(ParentWorkflow)
+	w.RegisterWorkflow(TerraformWorkflow)
+	w.RegisterWorkflow(ValidateOnlyWorkflow)
+
+	w.RegisterActivity(terraform)
+	w.RegisterActivity(&activities.OrchestrationActivities{Client: c})
+}
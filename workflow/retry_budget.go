@@ -0,0 +1,115 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// RetryBudgetErrorType is the ApplicationError type returned when a
+// workspace stops retrying a failed activity because the orchestration's
+// retry budget is spent.
+const RetryBudgetErrorType = "RetryBudgetExhausted"
+
+// retryBudget runs a workspace's activities under an orchestration retry
+// budget. The server's retries aren't visible to the workflow, so the
+// workflow retries failed activities itself, following the retry policy of
+// the context's activity options. First attempts are free; each retry is
+// counted, and a failed activity is no longer retried once the retries left
+// are used. A nil budget runs activities with the server retrying as usual.
+type retryBudget struct {
+	left int
+	used int
+}
+
+// splitRetries divides the retries left in the budget among n workspaces
+// starting together, the first ones getting any remainder.
+func splitRetries(left, n int) []int {
+	left = max(left, 0)
+	shares := make([]int, n)
+	for i := range shares {
+		shares[i] = left / n
+		if i < left%n {
+			shares[i]++
+		}
+	}
+	return shares
+}
+
+// newRetryBudget returns nil when the workspace has no budget.
+func newRetryBudget(left *int) *retryBudget {
+	if left == nil {
+		return nil
+	}
+	return &retryBudget{left: *left}
+}
+
+// retries is the number of activity retries made so far.
+func (b *retryBudget) retries() int {
+	if b == nil {
+		return 0
+	}
+	return b.used
+}
+
+// execute runs activity and decodes its result into result, like
+// workflow.ExecuteActivity(...).Get.
+func (b *retryBudget) execute(ctx workflow.Context, result interface{}, activity interface{}, args ...interface{}) error {
+	if b == nil {
+		return workflow.ExecuteActivity(ctx, activity, args...).Get(ctx, result)
+	}
+
+	var policy temporal.RetryPolicy
+	if p := workflow.GetActivityOptions(ctx).RetryPolicy; p != nil {
+		policy = *p
+	}
+	actx := workflow.WithRetryPolicy(ctx, temporal.RetryPolicy{MaximumAttempts: 1})
+	interval := policy.InitialInterval
+	for attempt := int32(1); ; attempt++ {
+		err := workflow.ExecuteActivity(actx, activity, args...).Get(ctx, result)
+		if err == nil || !isRetryable(err, policy.NonRetryableErrorTypes) {
+			return err
+		}
+		if policy.MaximumAttempts > 0 && attempt >= policy.MaximumAttempts {
+			return err
+		}
+		if b.used >= b.left {
+			return temporal.NewNonRetryableApplicationError(
+				fmt.Sprintf("retry budget exhausted (the workspace's share was %d retries)", b.left), RetryBudgetErrorType, err)
+		}
+		b.used++
+		if err := workflow.Sleep(ctx, interval); err != nil {
+			return err
+		}
+		interval = nextRetryInterval(policy, interval)
+	}
+}
+
+// nextRetryInterval applies the policy's backoff to the previous interval.
+func nextRetryInterval(policy temporal.RetryPolicy, interval time.Duration) time.Duration {
+	if policy.BackoffCoefficient > 1 {
+		interval = time.Duration(float64(interval) * policy.BackoffCoefficient)
+	}
+	if policy.MaximumInterval > 0 && interval > policy.MaximumInterval {
+		interval = policy.MaximumInterval
+	}
+	return interval
+}
+
+// isRetryable reports whether the server would retry an activity that
+// failed with err under a policy with the given non-retryable error types.
+func isRetryable(err error, nonRetryableTypes []string) bool {
+	var canceled *temporal.CanceledError
+	if errors.As(err, &canceled) {
+		return false
+	}
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) {
+		return !appErr.NonRetryable() && !slices.Contains(nonRetryableTypes, appErr.Type())
+	}
+	return true
+}
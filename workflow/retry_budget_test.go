@@ -0,0 +1,144 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fakoli/temporal-terraform-orchestrator/activities"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func retriesLeft(n int) *int {
+	return &n
+}
+
+func TestTerraformWorkflow_RetryBudgetStopsRetries(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:        "vpc",
+		Dir:         "/tmp/vpc",
+		Operations:  []string{"init", "validate", "plan", "apply"},
+		RetriesLeft: retriesLeft(1),
+	}
+
+	// A flaky init would get three attempts without the budget
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).
+		Return(errors.New("registry unavailable")).Times(2)
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	err := env.GetWorkflowError()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "init failed: retry budget exhausted (the workspace's share was 1 retries)")
+	require.Contains(t, err.Error(), "type: "+RetryBudgetErrorType)
+	env.AssertExpectations(t)
+}
+
+func TestTerraformWorkflow_RetryBudgetRetriesLikeThePolicy(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:        "vpc",
+		Dir:         "/tmp/vpc",
+		Operations:  []string{"init", "validate", "plan", "apply"},
+		RetriesLeft: retriesLeft(10),
+	}
+
+	// Retried until it succeeds, within the policy's three attempts
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).
+		Return(errors.New("registry unavailable")).Times(2)
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	// Non-retryable errors fail on the first attempt
+	env.OnActivity((*activities.TerraformActivities).TerraformPlan, mock.Anything, mock.Anything, mock.Anything).
+		Return(false, temporal.NewNonRetryableApplicationError("invalid reference", "TerraformError", nil)).Once()
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.Error(t, env.GetWorkflowError())
+	require.Contains(t, env.GetWorkflowError().Error(), "plan failed")
+	require.Contains(t, env.GetWorkflowError().Error(), "invalid reference")
+	require.NotContains(t, env.GetWorkflowError().Error(), "retry budget")
+	env.AssertExpectations(t)
+}
+
+func TestTerraformWorkflow_SpentRetryBudgetStillRunsHealthyActivities(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:        "vpc",
+		Dir:         "/tmp/vpc",
+		Operations:  []string{"init", "validate", "plan", "apply"},
+		RetriesLeft: retriesLeft(0),
+	}
+
+	// First attempts are free, so activities that succeed never touch the budget
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	env.OnActivity((*activities.TerraformActivities).TerraformPlan, mock.Anything, mock.Anything, mock.Anything).Return(true, nil).Once()
+	env.OnActivity((*activities.TerraformActivities).TerraformApply, mock.Anything, mock.Anything, mock.Anything).Return(activities.ApplyResult{Applied: true}, nil).Once()
+	env.OnActivity((*activities.TerraformActivities).TerraformOutputDetails, mock.Anything, mock.Anything, mock.Anything).Return(plainOutputs(map[string]interface{}{}), nil).Once()
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertExpectations(t)
+}
+
+func TestParentWorkflow_ParallelWorkspacesShareRetryBudget(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(TerraformWorkflow)
+	env.OnSignalExternalWorkflow(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	// Both workspaces run at once against a registry that never recovers
+	attempts := 0
+	a := &activities.TerraformActivities{}
+	env.OnActivity(a.TerraformInit, mock.Anything, mock.Anything).Return(func(context.Context, activities.TerraformParams) error {
+		attempts++
+		return errors.New("registry unavailable")
+	})
+
+	cfg := InfrastructureConfig{
+		RetryBudget:     2,
+		ContinueOnError: true,
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "dns", Dir: "/tmp/dns"},
+		},
+	}
+
+	env.ExecuteWorkflow(ParentWorkflow, cfg)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.Error(t, env.GetWorkflowError())
+	require.Contains(t, env.GetWorkflowError().Error(), "the workspace's share was 1 retries")
+	// Two first attempts, and no more retries than the budget between them
+	require.Equal(t, 2+cfg.RetryBudget, attempts)
+}
+
+func TestSplitRetries(t *testing.T) {
+	require.Equal(t, []int{2, 1, 1}, splitRetries(4, 3))
+	require.Equal(t, []int{1, 0, 0}, splitRetries(1, 3))
+	require.Equal(t, []int{0, 0}, splitRetries(-2, 2))
+	require.Empty(t, splitRetries(5, 0))
+}
+
+func TestIsRetryable(t *testing.T) {
+	require.True(t, isRetryable(errors.New("boom"), nil))
+	require.True(t, isRetryable(temporal.NewApplicationError("locked", activities.StateLockErrorType), nil))
+	require.False(t, isRetryable(temporal.NewApplicationError("locked", activities.StateLockErrorType), []string{activities.StateLockErrorType}))
+	require.False(t, isRetryable(temporal.NewNonRetryableApplicationError("bad", "TerraformError", nil), nil))
+	require.False(t, isRetryable(temporal.NewCanceledError(), nil))
+}
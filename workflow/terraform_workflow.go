@@ -1,7 +1,11 @@
 package workflow
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/fakoli/temporal-terraform-orchestrator/activities"
@@ -23,6 +27,31 @@ func TerraformWorkflow(ctx workflow.Context, ws WorkspaceConfig) (map[string]int
 	}
 	ctx = workflow.WithActivityOptions(ctx, options)
 
+	// Reading outputs is quick, and it mostly fails for reasons a retry
+	// won't fix, such as a malformed state
+	outputCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 1 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts:        2,
+			InitialInterval:        2 * time.Second,
+			BackoffCoefficient:     2.0,
+			MaximumInterval:        10 * time.Second,
+			NonRetryableErrorTypes: []string{},
+		},
+	})
+
+	// Under an orchestration retry budget every retry is counted and
+	// reported to the parent
+	budget := newRetryBudget(ws.RetriesLeft)
+
+	// Expose progress so a restarted orchestration can adopt this workspace
+	state := activities.WorkspaceState{Running: true}
+	if err := workflow.SetQueryHandler(ctx, QueryWorkspaceState, func() (activities.WorkspaceState, error) {
+		return state, nil
+	}); err != nil {
+		return nil, err
+	}
+
 	var a *activities.TerraformActivities
 	info := workflow.GetInfo(ctx)
 	rootRunID := info.WorkflowExecution.RunID
@@ -35,8 +64,31 @@ func TerraformWorkflow(ctx workflow.Context, ws WorkspaceConfig) (map[string]int
 		Dir:      ws.Dir,
 		TFVars:   ws.TFVars,
 		PlanFile: planFile,
-		Vars:     ws.ExtraVars,
+		Vars:     mergeVars(ws.Vars, ws.ExtraVars),
+		VarTypes: inputVarTypes(ws.Inputs),
 		RunID:    rootRunID,
+		TempRoot: ws.TempRoot,
+
+		Chdir:            ws.Chdir,
+		DetectOnly:       ws.DetectOnly,
+		LayerVarFiles:    ws.LayerVarFiles,
+		PreferAutoTFVars: ws.PreferAutoTFVars,
+		LockTimeout:      ws.LockTimeout,
+		DisableLock:      ws.DisableLock,
+		LegacyRefresh:    ws.LegacyRefresh,
+		Targets:          ws.Targets,
+		Parallelism:      ws.Parallelism,
+
+		SkipInitIfInitialized: ws.SkipInitIfInitialized,
+		InitUpgrade:           ws.InitUpgrade,
+
+		MaxOutputBytes: ws.MaxOutputBytes,
+		OutputLogDir:   ws.OutputLogDir,
+		SensitiveVars:  ws.SensitiveVars,
+	}
+	if ws.Providers != nil {
+		params.AllowedProviders = ws.Providers.Allow
+		params.DeniedProviders = ws.Providers.Deny
 	}
 
 	// Determine orchestrator ID for signaling completion
@@ -48,42 +100,147 @@ func TerraformWorkflow(ctx workflow.Context, ws WorkspaceConfig) (map[string]int
 		orchestratorID = info.RootWorkflowExecution.ID
 	}
 
-	signalParent := func(outs map[string]interface{}) {
+	signalParent := func(outs map[string]interface{}, sensitive []string, details *activities.WorkspaceDetails, runErr error) {
 		if orchestratorID == "" {
 			// No parent workflow to signal (e.g., in test environment)
 			return
 		}
 		finishedSignal := WorkspaceFinishedSignal{
-			Name:    ws.Name,
-			Outputs: outs,
+			Name:             ws.Name,
+			Outputs:          outs,
+			SensitiveOutputs: sensitive,
+			Details:          details,
+			Retries:          budget.retries(),
+		}
+		if runErr != nil {
+			finishedSignal.Error = runErr.Error()
 		}
 		if err := workflow.SignalExternalWorkflow(ctx, orchestratorID, "", SignalWorkspaceFinished, finishedSignal).Get(ctx, nil); err != nil {
 			workflow.GetLogger(ctx).Warn("Failed to signal parent workflow", "workspace", ws.Name, "error", err)
 		}
 	}
 
+	// Set by runTerraform when the workspace recorded any details
+	var details *activities.WorkspaceDetails
+	// Set by runTerraform to the outputs terraform marks sensitive
+	var sensitiveOutputs []string
+
 	runTerraform := func() (map[string]interface{}, error) {
 		changesPresent := false
+		var initInfo *activities.InitInfo
+		var effectiveVars map[string]interface{}
+		var planSummary *activities.PlanSummary
+		var applyResult *activities.ApplyResult
+		var drift *activities.DriftResult
+		destroyed := false
+
+		if ws.RemoteVarSet != nil {
+			var remote activities.RemoteVars
+			if err := budget.execute(ctx, &remote, a.FetchRemoteVars, remoteVarsParams(ws.RemoteVarSet)); err != nil {
+				return nil, fmt.Errorf("remote vars failed: %w", err)
+			}
+			params.Vars = mergeRemoteVars(remote.Values, params.Vars)
+			// Log names only; values may be secrets even when not marked sensitive
+			workflow.GetLogger(ctx).Info("Loaded remote variables",
+				"workspace", ws.Name,
+				"variables", sortedKeys(remote.Values),
+				"sensitive_skipped", remote.Sensitive,
+			)
+		}
+
+		// Skip init/validate when nothing they depend on changed since the last
+		// successful run; plan and apply always run
+		var cache activities.ValidationCacheResult
+		if ws.CacheValidation {
+			if err := budget.execute(ctx, &cache, a.CheckValidationCache, params); err != nil {
+				return nil, fmt.Errorf("validation cache failed: %w", err)
+			}
+			if cache.Hit {
+				workflow.GetLogger(ctx).Info("Skipping init and validate: module unchanged since last validation", "workspace", ws.Name)
+			}
+		}
 
 		// Execute operations in the order specified
 		for _, op := range ws.Operations {
+			if cache.Hit && (op == "init" || op == "validate") {
+				continue
+			}
 			switch op {
 			case "init":
-				if err := workflow.ExecuteActivity(ctx, a.TerraformInit, params).Get(ctx, nil); err != nil {
+				if ws.CaptureInitInfo {
+					initInfo = &activities.InitInfo{}
+					if err := budget.execute(ctx, initInfo, a.TerraformInitInfo, params); err != nil {
+						return nil, fmt.Errorf("init failed: %w", err)
+					}
+					continue
+				}
+				if err := budget.execute(ctx, nil, a.TerraformInit, params); err != nil {
 					return nil, fmt.Errorf("init failed: %w", err)
 				}
 
 			case "validate":
-				if err := workflow.ExecuteActivity(ctx, a.TerraformValidate, params).Get(ctx, nil); err != nil {
+				if err := budget.execute(ctx, nil, a.TerraformValidate, params); err != nil {
 					return nil, fmt.Errorf("validate failed: %w", err)
 				}
+				if ws.CacheValidation {
+					if err := budget.execute(ctx, nil, a.RecordValidationCache, params, cache.Key); err != nil {
+						// A missed cache write only costs a re-validation next time
+						workflow.GetLogger(ctx).Warn("Failed to record validation cache", "workspace", ws.Name, "error", err)
+					}
+				}
+
+			case "refresh":
+				if err := budget.execute(ctx, nil, a.TerraformRefresh, params); err != nil {
+					return nil, fmt.Errorf("refresh failed: %w", err)
+				}
 
 			case "plan":
-				if err := workflow.ExecuteActivity(ctx, a.TerraformPlan, params).Get(ctx, &changesPresent); err != nil {
+				if ws.IncludeEffectiveVars {
+					if err := budget.execute(ctx, &effectiveVars, a.TerraformEffectiveVars, params); err != nil {
+						return nil, fmt.Errorf("effective vars failed: %w", err)
+					}
+				}
+				if ws.DriftCheck {
+					drift = &activities.DriftResult{}
+					if err := budget.execute(ctx, drift, a.TerraformDriftCheck, params); err != nil {
+						return nil, fmt.Errorf("drift check failed: %w", err)
+					}
+					workflow.GetLogger(ctx).Info("Drift check complete", "workspace", ws.Name,
+						"drifted", drift.Drifted,
+						"changed", drift.Changed,
+					)
+					continue
+				}
+				if err := budget.execute(ctx, &changesPresent, a.TerraformPlan, params); err != nil {
+					if effectiveVars != nil {
+						return nil, fmt.Errorf("plan failed (effective variables for %s: %s): %w", ws.Name, compactJSON(effectiveVars), err)
+					}
 					return nil, fmt.Errorf("plan failed: %w", err)
 				}
+				if changesPresent && len(ws.ProtectedResources) > 0 {
+					if err := budget.execute(ctx, nil, a.TerraformCheckProtectedResources, params, ws.ProtectedResources); err != nil {
+						return nil, fmt.Errorf("protected resources check failed: %w", err)
+					}
+				}
+				if ws.PersistPlan {
+					key := planStoreKey(info, ws.Name)
+					var saved bool
+					if err := budget.execute(ctx, &saved, a.TerraformSavePlan, params, key); err != nil {
+						return nil, fmt.Errorf("save plan failed: %w", err)
+					}
+					if saved {
+						workflow.GetLogger(ctx).Info("Saved plan", "workspace", ws.Name, "key", key)
+					} else {
+						workflow.GetLogger(ctx).Warn("Plan discarded: the worker has no plan store (set PLAN_STORE_DIR)", "workspace", ws.Name, "key", key)
+					}
+				}
 				if !changesPresent {
 					workflow.GetLogger(ctx).Info("No changes detected in plan", "workspace", ws.Name, "dir", ws.Dir)
+				} else if ws.CapturePlan {
+					planSummary = &activities.PlanSummary{}
+					if err := budget.execute(ctx, planSummary, a.TerraformPlanSummary, params); err != nil {
+						return nil, fmt.Errorf("plan summary failed: %w", err)
+					}
 				}
 
 			case "apply":
@@ -92,28 +249,74 @@ func TerraformWorkflow(ctx workflow.Context, ws WorkspaceConfig) (map[string]int
 					workflow.GetLogger(ctx).Info("Skipping apply: no changes to apply", "workspace", ws.Name, "dir", ws.Dir)
 					continue
 				}
-				if err := workflow.ExecuteActivity(ctx, a.TerraformApply, params).Get(ctx, nil); err != nil {
+				applyResult = &activities.ApplyResult{}
+				if err := budget.execute(ctx, applyResult, a.TerraformApply, params); err != nil {
 					return nil, fmt.Errorf("apply failed: %w", err)
 				}
+				workflow.GetLogger(ctx).Info("Apply complete", "workspace", ws.Name,
+					"added", applyResult.Added,
+					"changed", applyResult.Changed,
+					"destroyed", applyResult.Destroyed,
+				)
+
+			case "destroy":
+				if err := budget.execute(ctx, nil, a.TerraformDestroy, params); err != nil {
+					return nil, fmt.Errorf("destroy failed: %w", err)
+				}
+				destroyed = true
+				workflow.GetLogger(ctx).Info("Destroy complete", "workspace", ws.Name, "dir", ws.Dir)
 
 			default:
 				return nil, fmt.Errorf("unknown operation: %s", op)
 			}
 		}
 
-		// Always fetch outputs at the end (needed for dependent workspaces)
+		// Fetch outputs at the end (needed for dependent workspaces). A
+		// destroyed workspace has none left, and its dependents were torn
+		// down before it, so nothing reads them.
 		var outputs map[string]interface{}
-		err := workflow.ExecuteActivity(ctx, a.TerraformOutput, params).Get(ctx, &outputs)
-		return outputs, err
+		if destroyed {
+			outputs = make(map[string]interface{})
+		} else {
+			var values map[string]activities.OutputValue
+			if err := budget.execute(outputCtx, &values, a.TerraformOutputDetails, params); err != nil {
+				if ws.failOnOutputError() {
+					return nil, err
+				}
+				// Nothing consumes this workspace's outputs (enforced by
+				// validation), so the operations' success stands
+				workflow.GetLogger(ctx).Warn("Ignoring output error", "workspace", ws.Name, "error", err)
+			}
+			outputs, sensitiveOutputs = splitOutputs(values)
+
+			// Fail fast if critical outputs don't match what the config expects
+			if err := verifyExpectedOutputs(ws.ExpectedOutputs, outputs); err != nil {
+				return nil, err
+			}
+		}
+		if initInfo != nil || effectiveVars != nil || planSummary != nil || applyResult != nil || drift != nil {
+			details = &activities.WorkspaceDetails{
+				Init:          initInfo,
+				EffectiveVars: effectiveVars,
+				Plan:          planSummary,
+				Apply:         applyResult,
+				Drift:         drift,
+			}
+		}
+		return outputs, nil
 	}
 
 	// Execute Terraform operations
 	outputs, err := runTerraform()
-	signalParent(outputs)
+	signalParent(outputs, sensitiveOutputs, details, err)
 
 	if err != nil {
 		return nil, err
 	}
+	state.Finished = true
+	state.Outputs = outputs
+	state.SensitiveOutputs = sensitiveOutputs
+	state.Details = details
 
 	// Only enter hosting mode if this workflow has a parent (i.e., is part of an orchestration)
 	if orchestratorID == "" {
@@ -134,7 +337,7 @@ func TerraformWorkflow(ctx workflow.Context, ws WorkspaceConfig) (map[string]int
 
 		activeChildren++
 		childOptions := workflow.ChildWorkflowOptions{
-			WorkflowID: fmt.Sprintf("iac-%s-%s", rootRunID, signal.Workspace.Name),
+			WorkflowID: childWorkflowID(orchestratorID, signal.Workspace.Name),
 		}
 		if signal.Workspace.TaskQueue != "" {
 			childOptions.TaskQueue = signal.Workspace.TaskQueue
@@ -167,3 +370,119 @@ func TerraformWorkflow(ctx workflow.Context, ws WorkspaceConfig) (map[string]int
 
 	return outputs, nil
 }
+
+// planStoreKey names a persisted plan after the orchestration that produced
+// it: <workflow id>/<run id>/<workspace>.tfplan, using the root workflow when
+// the workspace runs as part of one.
+func planStoreKey(info *workflow.Info, workspace string) string {
+	execution := info.WorkflowExecution
+	if info.RootWorkflowExecution != nil {
+		execution = *info.RootWorkflowExecution
+	}
+	return fmt.Sprintf("%s/%s/%s.tfplan", execution.ID, execution.RunID, workspace)
+}
+
+// remoteVarsParams converts the workspace's remote variable source to activity params.
+func remoteVarsParams(src *RemoteVarSet) activities.RemoteVarsParams {
+	return activities.RemoteVarsParams{
+		Address:     src.Address,
+		WorkspaceID: src.WorkspaceID,
+		VarSetID:    src.VarSetID,
+		TokenEnv:    src.TokenEnv,
+	}
+}
+
+// mergeRemoteVars layers config and propagated vars over remote ones: values
+// set for this workspace are more specific than a shared variable set.
+func mergeRemoteVars(remote, vars map[string]interface{}) map[string]interface{} {
+	if len(remote) == 0 {
+		return vars
+	}
+	return mergeVars(remote, vars)
+}
+
+// mergeVars returns base with overrides layered on top, without modifying
+// either. It returns nil when both are empty.
+func mergeVars(base, overrides map[string]interface{}) map[string]interface{} {
+	if len(base) == 0 && len(overrides) == 0 {
+		return nil
+	}
+	merged := make(map[string]interface{}, len(base)+len(overrides))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+	return merged
+}
+
+// compactJSON renders v on one line for error messages; map keys are sorted.
+func compactJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// verifyExpectedOutputs compares actual outputs against the expected values,
+// reporting every missing or mismatched output. Values are compared after a
+// JSON round trip so numbers and nested structures compare by value.
+func verifyExpectedOutputs(expected, actual map[string]interface{}) error {
+	if len(expected) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(expected))
+	for name := range expected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var mismatches []string
+	for _, name := range names {
+		got, ok := actual[name]
+		if !ok {
+			mismatches = append(mismatches, fmt.Sprintf("%s: missing", name))
+			continue
+		}
+		want, err := json.Marshal(expected[name])
+		if err != nil {
+			return fmt.Errorf("failed to encode expected output %s: %w", name, err)
+		}
+		have, err := json.Marshal(got)
+		if err != nil {
+			return fmt.Errorf("failed to encode output %s: %w", name, err)
+		}
+		if !jsonEqual(want, have) {
+			mismatches = append(mismatches, fmt.Sprintf("%s: expected %s, got %s", name, want, have))
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("expected outputs mismatch: %s", strings.Join(mismatches, "; "))
+	}
+	return nil
+}
+
+// jsonEqual reports whether two JSON documents decode to equal values.
+func jsonEqual(a, b []byte) bool {
+	var va, vb interface{}
+	if err := json.Unmarshal(a, &va); err != nil {
+		return false
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
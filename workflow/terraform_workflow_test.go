@@ -1,12 +1,19 @@
 package workflow
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/fakoli/temporal-terraform-orchestrator/activities"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/testsuite"
 )
 
@@ -25,9 +32,9 @@ func TestTerraformWorkflow_FullSequenceWithChanges(t *testing.T) {
 	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	env.OnActivity((*activities.TerraformActivities).TerraformPlan, mock.Anything, mock.Anything, mock.Anything).Return(true, nil) // Changes present
-	env.OnActivity((*activities.TerraformActivities).TerraformApply, mock.Anything, mock.Anything, mock.Anything).Return(nil)
-	env.OnActivity((*activities.TerraformActivities).TerraformOutput, mock.Anything, mock.Anything, mock.Anything).Return(
-		map[string]interface{}{"vpc_id": "vpc-12345"},
+	env.OnActivity((*activities.TerraformActivities).TerraformApply, mock.Anything, mock.Anything, mock.Anything).Return(activities.ApplyResult{Applied: true}, nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformOutputDetails, mock.Anything, mock.Anything, mock.Anything).Return(
+		plainOutputs(map[string]interface{}{"vpc_id": "vpc-12345"}),
 		nil,
 	)
 
@@ -40,7 +47,8 @@ func TestTerraformWorkflow_FullSequenceWithChanges(t *testing.T) {
 	var result map[string]interface{}
 	err := env.GetWorkflowResult(&result)
 	require.NoError(t, err)
-	require.Equal(t, "vpc-12345", result["vpc_id"])
+	require.Equal(t, map[string]interface{}{"vpc_id": "vpc-12345"}, result, "details stay out of the outputs")
+	require.Equal(t, &activities.ApplyResult{Applied: true}, workspaceDetails(t, env).Apply)
 
 	// Verify all activities were called in correct order
 	env.AssertExpectations(t)
@@ -60,8 +68,8 @@ func TestTerraformWorkflow_NoChangesSkipsApply(t *testing.T) {
 	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	env.OnActivity((*activities.TerraformActivities).TerraformPlan, mock.Anything, mock.Anything, mock.Anything).Return(false, nil) // No changes
-	env.OnActivity((*activities.TerraformActivities).TerraformOutput, mock.Anything, mock.Anything, mock.Anything).Return(
-		map[string]interface{}{"vpc_id": "vpc-existing"},
+	env.OnActivity((*activities.TerraformActivities).TerraformOutputDetails, mock.Anything, mock.Anything, mock.Anything).Return(
+		plainOutputs(map[string]interface{}{"vpc_id": "vpc-existing"}),
 		nil,
 	)
 
@@ -73,6 +81,58 @@ func TestTerraformWorkflow_NoChangesSkipsApply(t *testing.T) {
 
 	// When plan returns no changes, Apply should be skipped but init/validate/plan still run
 	// The test passes if workflow completes successfully without calling apply
+	require.Nil(t, workspaceDetails(t, env))
+}
+
+// workspaceDetails queries a finished TerraformWorkflow for its details.
+func workspaceDetails(t *testing.T, env *testsuite.TestWorkflowEnvironment) *activities.WorkspaceDetails {
+	t.Helper()
+
+	value, err := env.QueryWorkflow(QueryWorkspaceState)
+	require.NoError(t, err)
+	var state activities.WorkspaceState
+	require.NoError(t, value.Get(&state))
+	require.True(t, state.Finished)
+	return state.Details
+}
+
+// plainOutputs wraps values as the non-sensitive outputs
+// TerraformOutputDetails returns.
+func plainOutputs(values map[string]interface{}) map[string]activities.OutputValue {
+	outputs := make(map[string]activities.OutputValue, len(values))
+	for name, value := range values {
+		outputs[name] = activities.OutputValue{Value: value}
+	}
+	return outputs
+}
+
+func TestTerraformWorkflow_ReportsSensitiveOutputs(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{Name: "db", Dir: "/tmp/db", Operations: []string{"init"}}
+
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformOutputDetails, mock.Anything, mock.Anything, mock.Anything).Return(
+		map[string]activities.OutputValue{
+			"endpoint":    {Value: "db.internal:5432", Type: "string"},
+			"db_password": {Value: "hunter2", Sensitive: true, Type: "string"},
+		},
+		nil,
+	)
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	// Dependents still get the value; the name tells the parent to redact it
+	value, err := env.QueryWorkflow(QueryWorkspaceState)
+	require.NoError(t, err)
+	var state activities.WorkspaceState
+	require.NoError(t, value.Get(&state))
+	require.Equal(t, map[string]interface{}{"endpoint": "db.internal:5432", "db_password": "hunter2"}, state.Outputs)
+	require.Equal(t, []string{"db_password"}, state.SensitiveOutputs)
 }
 
 // NOTE: TestTerraformWorkflow_WithExtraVars was removed because ExtraVars
@@ -148,7 +208,7 @@ func TestTerraformWorkflow_ApplyFailure(t *testing.T) {
 	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	env.OnActivity((*activities.TerraformActivities).TerraformPlan, mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
 	env.OnActivity((*activities.TerraformActivities).TerraformApply, mock.Anything, mock.Anything, mock.Anything).
-		Return(errors.New("terraform apply failed: insufficient permissions"))
+		Return(activities.ApplyResult{}, errors.New("terraform apply failed: insufficient permissions"))
 
 	// Execute workflow (no signal expectations - standalone workflows don't signal)
 	env.ExecuteWorkflow(TerraformWorkflow, ws)
@@ -158,6 +218,57 @@ func TestTerraformWorkflow_ApplyFailure(t *testing.T) {
 	require.Contains(t, env.GetWorkflowError().Error(), "apply failed")
 }
 
+func TestTerraformWorkflow_DestroySequence(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:       "test-vpc",
+		Dir:        "/tmp/vpc",
+		TFVars:     "/tmp/vpc/vars.tfvars",
+		Operations: []string{"init", "validate", "destroy"},
+	}
+
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformDestroy, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var result map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&result))
+	require.Empty(t, result)
+
+	// Nothing is left to read outputs from after a destroy
+	env.AssertExpectations(t)
+	env.AssertNotCalled(t, "TerraformOutputDetails", mock.Anything, mock.Anything)
+}
+
+func TestTerraformWorkflow_DestroyFailure(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:       "test-vpc",
+		Dir:        "/tmp/vpc",
+		Operations: []string{"init", "validate", "destroy"},
+	}
+
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformDestroy, mock.Anything, mock.Anything, mock.Anything).
+		Return(errors.New("terraform destroy failed: resource in use"))
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.Error(t, env.GetWorkflowError())
+	require.Contains(t, env.GetWorkflowError().Error(), "destroy failed")
+}
+
 func TestTerraformWorkflow_PlanOnlyMode(t *testing.T) {
 	suite := &testsuite.WorkflowTestSuite{}
 	env := suite.NewTestWorkflowEnvironment()
@@ -174,8 +285,8 @@ func TestTerraformWorkflow_PlanOnlyMode(t *testing.T) {
 	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	env.OnActivity((*activities.TerraformActivities).TerraformPlan, mock.Anything, mock.Anything, mock.Anything).Return(true, nil) // Changes present
-	env.OnActivity((*activities.TerraformActivities).TerraformOutput, mock.Anything, mock.Anything, mock.Anything).Return(
-		map[string]interface{}{"vpc_id": "vpc-12345"},
+	env.OnActivity((*activities.TerraformActivities).TerraformOutputDetails, mock.Anything, mock.Anything, mock.Anything).Return(
+		plainOutputs(map[string]interface{}{"vpc_id": "vpc-12345"}),
 		nil,
 	)
 	// Note: TerraformApply should NOT be called
@@ -207,8 +318,8 @@ func TestTerraformWorkflow_EmptyOperations(t *testing.T) {
 	}
 
 	// Mock output activity since it's always called
-	env.OnActivity((*activities.TerraformActivities).TerraformOutput, mock.Anything, mock.Anything, mock.Anything).Return(
-		map[string]interface{}{},
+	env.OnActivity((*activities.TerraformActivities).TerraformOutputDetails, mock.Anything, mock.Anything, mock.Anything).Return(
+		plainOutputs(map[string]interface{}{}),
 		nil,
 	)
 
@@ -236,7 +347,7 @@ func TestTerraformWorkflow_OutputFailure(t *testing.T) {
 	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	env.OnActivity((*activities.TerraformActivities).TerraformPlan, mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
-	env.OnActivity((*activities.TerraformActivities).TerraformOutput, mock.Anything, mock.Anything, mock.Anything).Return(
+	env.OnActivity((*activities.TerraformActivities).TerraformOutputDetails, mock.Anything, mock.Anything, mock.Anything).Return(
 		nil,
 		errors.New("failed to read terraform output"),
 	)
@@ -248,6 +359,129 @@ func TestTerraformWorkflow_OutputFailure(t *testing.T) {
 	require.Contains(t, env.GetWorkflowError().Error(), "failed to read terraform output")
 }
 
+func TestTerraformWorkflow_OutputUsesOwnActivityOptions(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:       "test-vpc",
+		Dir:        "/tmp/vpc",
+		Operations: []string{"init", "plan"},
+	}
+
+	timeouts := map[string]time.Duration{}
+	attempts := map[string]int{}
+	env.SetOnActivityStartedListener(func(info *activity.Info, _ context.Context, _ converter.EncodedValues) {
+		timeouts[info.ActivityType.Name] = info.Deadline.Sub(info.StartedTime).Round(time.Second)
+		attempts[info.ActivityType.Name]++
+	})
+
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	// Plan fails twice before succeeding: the uniform policy allows 3 attempts
+	env.OnActivity((*activities.TerraformActivities).TerraformPlan, mock.Anything, mock.Anything, mock.Anything).Return(false, errors.New("transient")).Twice()
+	env.OnActivity((*activities.TerraformActivities).TerraformPlan, mock.Anything, mock.Anything, mock.Anything).Return(false, nil).Once()
+	env.OnActivity((*activities.TerraformActivities).TerraformOutputDetails, mock.Anything, mock.Anything, mock.Anything).Return(
+		nil,
+		errors.New("failed to read terraform output"),
+	)
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.Error(t, env.GetWorkflowError())
+	require.Contains(t, env.GetWorkflowError().Error(), "failed to read terraform output")
+
+	require.Equal(t, 3, attempts["TerraformPlan"])
+	require.Equal(t, 2, attempts["TerraformOutputDetails"])
+	require.Equal(t, 10*time.Minute, timeouts["TerraformPlan"])
+	require.Equal(t, 1*time.Minute, timeouts["TerraformOutputDetails"])
+}
+
+func TestTerraformWorkflow_DriftCheckReportsWithoutApplying(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:       "test-vpc",
+		Dir:        "/tmp/vpc",
+		Operations: []string{"init", "validate", "plan"},
+		DriftCheck: true,
+	}
+
+	drift := activities.DriftResult{Drifted: true, Changed: 1, Resources: []string{"aws_vpc.main"}}
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformDriftCheck, mock.Anything, mock.Anything, mock.Anything).Return(drift, nil).Once()
+	env.OnActivity((*activities.TerraformActivities).TerraformOutputDetails, mock.Anything, mock.Anything, mock.Anything).Return(
+		plainOutputs(map[string]interface{}{"vpc_id": "vpc-123"}),
+		nil,
+	)
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertNotCalled(t, "TerraformPlan", mock.Anything, mock.Anything)
+
+	var outputs map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&outputs))
+	require.Equal(t, map[string]interface{}{"vpc_id": "vpc-123"}, outputs)
+	require.Equal(t, &drift, workspaceDetails(t, env).Drift)
+}
+
+func TestTerraformWorkflow_OutputFailureHonoursFailOnOutputError(t *testing.T) {
+	tests := []struct {
+		name              string
+		failOnOutputError *bool
+		wantErr           bool
+	}{
+		{"default fails", nil, true},
+		{"explicit true fails", boolPtr(true), true},
+		{"false tolerates", boolPtr(false), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite := &testsuite.WorkflowTestSuite{}
+			env := suite.NewTestWorkflowEnvironment()
+
+			ws := WorkspaceConfig{
+				Name:              "test-dns",
+				Dir:               "/tmp/dns",
+				Operations:        []string{"init", "plan", "apply"},
+				FailOnOutputError: tt.failOnOutputError,
+			}
+
+			env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			env.OnActivity((*activities.TerraformActivities).TerraformPlan, mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+			env.OnActivity((*activities.TerraformActivities).TerraformApply, mock.Anything, mock.Anything, mock.Anything).Return(activities.ApplyResult{Applied: true, Added: 1}, nil)
+			env.OnActivity((*activities.TerraformActivities).TerraformOutputDetails, mock.Anything, mock.Anything, mock.Anything).Return(
+				nil,
+				errors.New("failed to read terraform output"),
+			)
+
+			env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+			require.True(t, env.IsWorkflowCompleted())
+			if tt.wantErr {
+				require.Error(t, env.GetWorkflowError())
+				require.Contains(t, env.GetWorkflowError().Error(), "failed to read terraform output")
+				return
+			}
+			require.NoError(t, env.GetWorkflowError())
+
+			var outputs map[string]interface{}
+			require.NoError(t, env.GetWorkflowResult(&outputs))
+			require.Empty(t, outputs)
+			require.Equal(t, &activities.ApplyResult{Applied: true, Added: 1}, workspaceDetails(t, env).Apply)
+		})
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
 func TestTerraformWorkflow_ValidateFailure(t *testing.T) {
 	suite := &testsuite.WorkflowTestSuite{}
 	env := suite.NewTestWorkflowEnvironment()
@@ -269,3 +503,503 @@ func TestTerraformWorkflow_ValidateFailure(t *testing.T) {
 	require.Error(t, env.GetWorkflowError())
 	require.Contains(t, env.GetWorkflowError().Error(), "validate failed")
 }
+
+func TestTerraformWorkflow_ExpectedOutputsMatch(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:       "test-vpc",
+		Dir:        "/tmp/vpc",
+		Operations: []string{"init", "validate", "plan", "apply"},
+		ExpectedOutputs: map[string]interface{}{
+			"vpc_cidr": "10.0.0.0/16",
+			"az_count": 3,
+		},
+	}
+
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformPlan, mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformApply, mock.Anything, mock.Anything, mock.Anything).Return(activities.ApplyResult{Applied: true}, nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformOutputDetails, mock.Anything, mock.Anything, mock.Anything).Return(
+		plainOutputs(map[string]interface{}{"vpc_id": "vpc-12345", "vpc_cidr": "10.0.0.0/16", "az_count": float64(3)}),
+		nil,
+	)
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var result map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&result))
+	require.Equal(t, "10.0.0.0/16", result["vpc_cidr"])
+}
+
+func TestTerraformWorkflow_CapturePlan(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:        "test-vpc",
+		Dir:         "/tmp/vpc",
+		Operations:  []string{"init", "validate", "plan"},
+		CapturePlan: true,
+	}
+
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformPlan, mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformPlanSummary, mock.Anything, mock.Anything, mock.Anything).Return(
+		activities.PlanSummary{Text: "  + aws_vpc.main", Add: 1}, nil,
+	)
+	env.OnActivity((*activities.TerraformActivities).TerraformOutputDetails, mock.Anything, mock.Anything, mock.Anything).Return(
+		plainOutputs(map[string]interface{}{}), nil,
+	)
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	require.Equal(t, &activities.PlanSummary{Text: "  + aws_vpc.main", Add: 1}, workspaceDetails(t, env).Plan)
+}
+
+func TestTerraformWorkflow_CapturePlanSkippedWithoutChanges(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:        "test-vpc",
+		Dir:         "/tmp/vpc",
+		Operations:  []string{"init", "validate", "plan"},
+		CapturePlan: true,
+	}
+
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformPlan, mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformOutputDetails, mock.Anything, mock.Anything, mock.Anything).Return(
+		plainOutputs(map[string]interface{}{"vpc_id": "vpc-12345"}), nil,
+	)
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	require.Nil(t, workspaceDetails(t, env))
+	env.AssertNotCalled(t, "TerraformPlanSummary", mock.Anything, mock.Anything)
+}
+
+func TestTerraformWorkflow_ExpectedOutputsMismatch(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:       "test-vpc",
+		Dir:        "/tmp/vpc",
+		Operations: []string{"init", "validate", "plan", "apply"},
+		ExpectedOutputs: map[string]interface{}{
+			"vpc_cidr":  "10.0.0.0/16",
+			"flow_logs": true,
+		},
+	}
+
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformPlan, mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformApply, mock.Anything, mock.Anything, mock.Anything).Return(activities.ApplyResult{Applied: true}, nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformOutputDetails, mock.Anything, mock.Anything, mock.Anything).Return(
+		plainOutputs(map[string]interface{}{"vpc_id": "vpc-12345", "vpc_cidr": "10.1.0.0/16"}),
+		nil,
+	)
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.Error(t, env.GetWorkflowError())
+	require.Contains(t, env.GetWorkflowError().Error(), "expected outputs mismatch")
+	require.Contains(t, env.GetWorkflowError().Error(), "flow_logs: missing")
+	require.Contains(t, env.GetWorkflowError().Error(), `vpc_cidr: expected "10.0.0.0/16", got "10.1.0.0/16"`)
+}
+
+func TestTerraformWorkflow_CaptureInitInfo(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:            "test-vpc",
+		Dir:             "/tmp/vpc",
+		Operations:      []string{"init", "validate", "plan"},
+		CaptureInitInfo: true,
+	}
+
+	env.OnActivity((*activities.TerraformActivities).TerraformInitInfo, mock.Anything, mock.Anything, mock.Anything).Return(
+		activities.InitInfo{
+			Providers: []activities.InstalledProvider{{Source: "hashicorp/aws", Version: "5.31.0"}},
+			Backend:   "s3",
+		},
+		nil,
+	)
+	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformPlan, mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformOutputDetails, mock.Anything, mock.Anything, mock.Anything).Return(
+		plainOutputs(map[string]interface{}{"vpc_id": "vpc-12345"}),
+		nil,
+	)
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var result map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&result))
+	require.Equal(t, map[string]interface{}{"vpc_id": "vpc-12345"}, result)
+	info := workspaceDetails(t, env).Init
+	require.Equal(t, "s3", info.Backend)
+	require.Equal(t, []activities.InstalledProvider{{Source: "hashicorp/aws", Version: "5.31.0"}}, info.Providers)
+
+	// Plain init isn't run when init info is captured
+	env.AssertNotCalled(t, "TerraformInit", mock.Anything, mock.Anything)
+}
+
+func TestTerraformWorkflow_RemoteVarSet(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:         "eks",
+		Dir:          "/tmp/eks",
+		Operations:   []string{"init", "validate", "plan"},
+		RemoteVarSet: &RemoteVarSet{VarSetID: "varset-1", TokenEnv: "TFE_TOKEN"},
+		ExtraVars:    map[string]interface{}{"vpc_id": "vpc-12345", "region": "eu-west-1"},
+	}
+
+	// Method values (not expressions) so the mocks see the decoded params
+	a := &activities.TerraformActivities{}
+	env.OnActivity(a.FetchRemoteVars, mock.Anything, activities.RemoteVarsParams{VarSetID: "varset-1", TokenEnv: "TFE_TOKEN"}).Return(
+		activities.RemoteVars{
+			Values:    map[string]interface{}{"region": "us-west-2", "cluster_version": "1.29"},
+			Sensitive: []string{"db_password"},
+		},
+		nil,
+	)
+	env.OnActivity(a.TerraformInit, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity(a.TerraformValidate, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity(a.TerraformPlan, mock.Anything, mock.MatchedBy(func(p activities.TerraformParams) bool {
+		return p.Vars["cluster_version"] == "1.29" && // from the variable set
+			p.Vars["region"] == "eu-west-1" && // mapped inputs win over remote values
+			p.Vars["vpc_id"] == "vpc-12345"
+	})).Return(true, nil)
+	env.OnActivity(a.TerraformOutputDetails, mock.Anything, mock.Anything).Return(plainOutputs(map[string]interface{}{}), nil)
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertExpectations(t)
+}
+
+func TestTerraformWorkflow_PassesTargetsAndParallelism(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:        "vpc",
+		Dir:         "/tmp/vpc",
+		Operations:  []string{"init", "plan", "apply"},
+		Targets:     []string{"aws_subnet.private[0]", "module.nat"},
+		Parallelism: 30,
+	}
+
+	a := &activities.TerraformActivities{}
+	env.OnActivity(a.TerraformInit, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity(a.TerraformPlan, mock.Anything, mock.MatchedBy(func(p activities.TerraformParams) bool {
+		return slices.Equal(p.Targets, []string{"aws_subnet.private[0]", "module.nat"}) && p.Parallelism == 30
+	})).Return(true, nil)
+	env.OnActivity(a.TerraformApply, mock.Anything, mock.Anything).Return(activities.ApplyResult{Applied: true}, nil)
+	env.OnActivity(a.TerraformOutputDetails, mock.Anything, mock.Anything).Return(plainOutputs(map[string]interface{}{}), nil)
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertExpectations(t)
+}
+
+func TestTerraformWorkflow_PersistPlan(t *testing.T) {
+	for _, persist := range []bool{true, false} {
+		t.Run(fmt.Sprintf("persistPlan=%v", persist), func(t *testing.T) {
+			suite := &testsuite.WorkflowTestSuite{}
+			env := suite.NewTestWorkflowEnvironment()
+
+			ws := WorkspaceConfig{
+				Name:        "vpc",
+				Dir:         "/tmp/vpc",
+				Operations:  []string{"init", "plan"},
+				PersistPlan: persist,
+			}
+
+			a := &activities.TerraformActivities{}
+			env.OnActivity(a.TerraformInit, mock.Anything, mock.Anything).Return(nil)
+			env.OnActivity(a.TerraformPlan, mock.Anything, mock.Anything).Return(false, nil)
+			env.OnActivity(a.TerraformSavePlan, mock.Anything, mock.Anything, "default-test-workflow-id/default-test-run-id/vpc.tfplan").Return(true, nil)
+			env.OnActivity(a.TerraformOutputDetails, mock.Anything, mock.Anything).Return(plainOutputs(map[string]interface{}{}), nil)
+
+			env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+			require.True(t, env.IsWorkflowCompleted())
+			require.NoError(t, env.GetWorkflowError())
+			if persist {
+				env.AssertCalled(t, "TerraformSavePlan", mock.Anything, mock.Anything, "default-test-workflow-id/default-test-run-id/vpc.tfplan")
+			} else {
+				env.AssertNotCalled(t, "TerraformSavePlan", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestTerraformWorkflow_ProtectedResourcesBlockApply(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:               "db",
+		Dir:                "/tmp/db",
+		Operations:         []string{"init", "plan", "apply"},
+		ProtectedResources: []string{"aws_db_instance.main"},
+	}
+
+	a := &activities.TerraformActivities{}
+	env.OnActivity(a.TerraformInit, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity(a.TerraformPlan, mock.Anything, mock.Anything).Return(true, nil)
+	env.OnActivity(a.TerraformCheckProtectedResources, mock.Anything, mock.Anything, []string{"aws_db_instance.main"}).Return(
+		temporal.NewNonRetryableApplicationError("plan would destroy protected resources: aws_db_instance.main (replace)", activities.ProtectedResourceErrorType, nil))
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	err := env.GetWorkflowError()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "protected resources check failed")
+	require.Contains(t, err.Error(), "aws_db_instance.main (replace)")
+	env.AssertNotCalled(t, "TerraformApply", mock.Anything, mock.Anything)
+}
+
+func TestTerraformWorkflow_StaticVarsPrecedence(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:         "eks",
+		Dir:          "/tmp/eks",
+		Operations:   []string{"init", "plan"},
+		RemoteVarSet: &RemoteVarSet{VarSetID: "varset-1", TokenEnv: "TFE_TOKEN"},
+		Vars:         map[string]interface{}{"region": "us-east-1", "node_count": 3, "vpc_id": "vpc-default"},
+		ExtraVars:    map[string]interface{}{"vpc_id": "vpc-12345"},
+	}
+
+	a := &activities.TerraformActivities{}
+	env.OnActivity(a.FetchRemoteVars, mock.Anything, mock.Anything).Return(
+		activities.RemoteVars{Values: map[string]interface{}{"region": "us-west-2", "cluster_version": "1.29"}},
+		nil,
+	)
+	env.OnActivity(a.TerraformInit, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity(a.TerraformPlan, mock.Anything, mock.MatchedBy(func(p activities.TerraformParams) bool {
+		return p.Vars["cluster_version"] == "1.29" && // from the variable set
+			p.Vars["region"] == "us-east-1" && // static vars win over remote values
+			p.Vars["node_count"] == float64(3) &&
+			p.Vars["vpc_id"] == "vpc-12345" // mapped outputs win over static vars
+	})).Return(false, nil)
+	env.OnActivity(a.TerraformOutputDetails, mock.Anything, mock.Anything).Return(plainOutputs(map[string]interface{}{}), nil)
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertExpectations(t)
+}
+
+func TestMergeVars(t *testing.T) {
+	base := map[string]interface{}{"region": "us-east-1", "vpc_id": "vpc-default"}
+	overrides := map[string]interface{}{"vpc_id": "vpc-1"}
+
+	require.Equal(t, map[string]interface{}{"region": "us-east-1", "vpc_id": "vpc-1"}, mergeVars(base, overrides))
+	require.Equal(t, "vpc-default", base["vpc_id"], "inputs must not be modified")
+	require.Equal(t, base, mergeVars(base, nil))
+	require.Nil(t, mergeVars(nil, nil))
+}
+
+func TestTerraformWorkflow_IncludeEffectiveVars(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:                 "eks",
+		Dir:                  "/tmp/eks",
+		Operations:           []string{"init", "validate", "plan"},
+		IncludeEffectiveVars: true,
+	}
+
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformEffectiveVars, mock.Anything, mock.Anything, mock.Anything).Return(
+		map[string]interface{}{"vpc_id": "vpc-12345", "db_password": activities.RedactedValue},
+		nil,
+	)
+	env.OnActivity((*activities.TerraformActivities).TerraformPlan, mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformOutputDetails, mock.Anything, mock.Anything, mock.Anything).Return(
+		plainOutputs(map[string]interface{}{"cluster": "eks-1"}),
+		nil,
+	)
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var result map[string]interface{}
+	require.NoError(t, env.GetWorkflowResult(&result))
+	require.Equal(t, map[string]interface{}{"cluster": "eks-1"}, result)
+	require.Equal(t, map[string]interface{}{"vpc_id": "vpc-12345", "db_password": "(sensitive)"}, workspaceDetails(t, env).EffectiveVars)
+}
+
+func TestTerraformWorkflow_PlanFailureReportsEffectiveVars(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:                 "eks",
+		Dir:                  "/tmp/eks",
+		Operations:           []string{"init", "validate", "plan"},
+		IncludeEffectiveVars: true,
+	}
+
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformEffectiveVars, mock.Anything, mock.Anything, mock.Anything).Return(
+		map[string]interface{}{"vpc_id": "vpc-wrong", "db_password": activities.RedactedValue},
+		nil,
+	)
+	env.OnActivity((*activities.TerraformActivities).TerraformPlan, mock.Anything, mock.Anything, mock.Anything).
+		Return(false, errors.New("invalid vpc id"))
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.Error(t, env.GetWorkflowError())
+	msg := env.GetWorkflowError().Error()
+	require.Contains(t, msg, `effective variables for eks: {"db_password":"(sensitive)","vpc_id":"vpc-wrong"}`)
+}
+
+func TestTerraformWorkflow_CacheValidationHitSkipsInitAndValidate(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+	a := &activities.TerraformActivities{}
+
+	ws := WorkspaceConfig{
+		Name:            "test-vpc",
+		Dir:             "/tmp/vpc",
+		Operations:      []string{"init", "validate", "plan"},
+		CacheValidation: true,
+	}
+
+	env.OnActivity(a.CheckValidationCache, mock.Anything, mock.Anything).Return(
+		activities.ValidationCacheResult{Key: "abc", Hit: true}, nil)
+	env.OnActivity(a.TerraformPlan, mock.Anything, mock.Anything).Return(false, nil)
+	env.OnActivity(a.TerraformOutputDetails, mock.Anything, mock.Anything).Return(plainOutputs(map[string]interface{}{}), nil)
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertExpectations(t)
+	env.AssertNotCalled(t, "TerraformInit", mock.Anything, mock.Anything)
+	env.AssertNotCalled(t, "TerraformValidate", mock.Anything, mock.Anything)
+	env.AssertNotCalled(t, "RecordValidationCache", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestTerraformWorkflow_CacheValidationMissRecordsKey(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+	a := &activities.TerraformActivities{}
+
+	ws := WorkspaceConfig{
+		Name:            "test-vpc",
+		Dir:             "/tmp/vpc",
+		Operations:      []string{"init", "validate", "plan"},
+		CacheValidation: true,
+	}
+
+	env.OnActivity(a.CheckValidationCache, mock.Anything, mock.Anything).Return(
+		activities.ValidationCacheResult{Key: "abc"}, nil)
+	env.OnActivity(a.TerraformInit, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity(a.TerraformValidate, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity(a.RecordValidationCache, mock.Anything, mock.Anything, "abc").Return(nil)
+	env.OnActivity(a.TerraformPlan, mock.Anything, mock.Anything).Return(false, nil)
+	env.OnActivity(a.TerraformOutputDetails, mock.Anything, mock.Anything).Return(plainOutputs(map[string]interface{}{}), nil)
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertExpectations(t)
+}
+
+func TestTerraformWorkflow_RefreshRunsBeforePlan(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:          "vpc",
+		Dir:           "/tmp/vpc",
+		Operations:    []string{"init", "validate", "refresh", "plan", "apply"},
+		LegacyRefresh: true,
+	}
+
+	var calls []string
+	record := func(name string) func(args mock.Arguments) {
+		return func(args mock.Arguments) { calls = append(calls, name) }
+	}
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(record("init"))
+	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(record("validate"))
+	a := &activities.TerraformActivities{}
+	env.OnActivity(a.TerraformRefresh, mock.Anything, mock.MatchedBy(func(p activities.TerraformParams) bool {
+		return p.LegacyRefresh && p.Dir == "/tmp/vpc"
+	})).Return(nil).Run(record("refresh")).Once()
+	env.OnActivity((*activities.TerraformActivities).TerraformPlan, mock.Anything, mock.Anything, mock.Anything).Return(false, nil).Run(record("plan"))
+	env.OnActivity((*activities.TerraformActivities).TerraformOutputDetails, mock.Anything, mock.Anything, mock.Anything).Return(plainOutputs(map[string]interface{}{}), nil).Run(record("output"))
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.Equal(t, []string{"init", "validate", "refresh", "plan", "output"}, calls)
+	env.AssertExpectations(t)
+}
+
+func TestTerraformWorkflow_RefreshFailureStopsBeforePlan(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	ws := WorkspaceConfig{
+		Name:       "vpc",
+		Dir:        "/tmp/vpc",
+		Operations: []string{"init", "validate", "refresh", "plan", "apply"},
+	}
+
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformRefresh, mock.Anything, mock.Anything, mock.Anything).Return(
+		temporal.NewNonRetryableApplicationError("no valid credential sources", "TerraformError", nil))
+
+	env.ExecuteWorkflow(TerraformWorkflow, ws)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.Error(t, env.GetWorkflowError())
+	require.Contains(t, env.GetWorkflowError().Error(), "refresh failed")
+	env.AssertNotCalled(t, "TerraformPlan", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,209 @@
+package workflow
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fakoli/temporal-terraform-orchestrator/activities"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// ValidationResponse aggregates the results of ValidateOnlyWorkflow.
+type ValidationResponse struct {
+	Valid bool `json:"valid"`
+
+	// Error is set when the config itself is invalid (cycles, unknown
+	// dependencies, ...); no workspace is validated in that case.
+	Error string `json:"error,omitempty"`
+
+	Workspaces []WorkspaceValidation `json:"workspaces,omitempty"`
+
+	// CIDROverlaps lists declared cidrVars ranges that overlap across workspaces
+	CIDROverlaps []activities.CIDROverlap `json:"cidrOverlaps,omitempty"`
+
+	// LocalState lists workspaces flagged by backendCheck: they apply but
+	// keep state locally. They only make the config invalid in "error" mode.
+	LocalState []string `json:"localState,omitempty"`
+}
+
+// WorkspaceValidation is the terraform validation result of one workspace.
+type WorkspaceValidation struct {
+	Name    string `json:"name"`
+	Valid   bool   `json:"valid"`
+	Skipped bool   `json:"skipped,omitempty"` // `when` condition is false
+	Error   string `json:"error,omitempty"`
+}
+
+// ValidateOnlyWorkflow runs a config through structural validation and
+// terraform init/validate for every workspace, without planning or applying
+// anything. terraform validate doesn't depend on variable values, so
+// workspaces are validated concurrently regardless of their dependencies.
+// Validation failures are reported in the response rather than failing the workflow.
+func ValidateOnlyWorkflow(ctx workflow.Context, rawConfig InfrastructureConfig) (ValidationResponse, error) {
+	config, err := PrepareConfig(rawConfig)
+	if err != nil {
+		return ValidationResponse{Error: err.Error()}, nil
+	}
+
+	skipped, err := skippedWorkspaces(config)
+	if err != nil {
+		return ValidationResponse{Error: err.Error()}, nil
+	}
+
+	overlaps, err := checkCIDROverlaps(ctx, config, skipped)
+	if err != nil {
+		return ValidationResponse{Error: err.Error()}, nil
+	}
+
+	localState, err := checkLocalState(ctx, config, skipped)
+	if err != nil {
+		return ValidationResponse{Error: err.Error()}, nil
+	}
+
+	options := workflow.ActivityOptions{
+		StartToCloseTimeout: 10 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts:    3,
+			InitialInterval:    5 * time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    1 * time.Minute,
+		},
+	}
+
+	var a *activities.TerraformActivities
+	results := make([]WorkspaceValidation, len(config.Workspaces))
+	wg := workflow.NewWaitGroup(ctx)
+
+	for i, ws := range config.Workspaces {
+		results[i] = WorkspaceValidation{Name: ws.Name}
+		if skipped[ws.Name] {
+			results[i].Valid = true
+			results[i].Skipped = true
+			continue
+		}
+
+		i, ws := i, ws
+		wsOptions := options
+		wsOptions.TaskQueue = ws.TaskQueue
+		actx := workflow.WithActivityOptions(ctx, wsOptions)
+		params := activities.TerraformParams{
+			Dir:          ws.Dir,
+			TFVars:       ws.TFVars,
+			Chdir:        ws.Chdir,
+			SkipBackend:  true, // validation never touches remote state
+			OutputLogDir: ws.OutputLogDir,
+
+			SkipInitIfInitialized: ws.SkipInitIfInitialized,
+			InitUpgrade:           ws.InitUpgrade,
+
+			MaxOutputBytes: ws.MaxOutputBytes,
+		}
+		if ws.Providers != nil {
+			params.AllowedProviders = ws.Providers.Allow
+			params.DeniedProviders = ws.Providers.Deny
+		}
+
+		wg.Add(1)
+		workflow.Go(ctx, func(gctx workflow.Context) {
+			defer wg.Done()
+			if err := workflow.ExecuteActivity(actx, a.TerraformInit, params).Get(gctx, nil); err != nil {
+				results[i].Error = "init failed: " + err.Error()
+				return
+			}
+			if err := workflow.ExecuteActivity(actx, a.TerraformValidate, params).Get(gctx, nil); err != nil {
+				results[i].Error = "validate failed: " + err.Error()
+				return
+			}
+			results[i].Valid = true
+		})
+	}
+	wg.Wait(ctx)
+
+	response := ValidationResponse{Valid: len(overlaps) == 0, Workspaces: results, CIDROverlaps: overlaps, LocalState: localState}
+	if len(localState) > 0 && config.BackendCheck == BackendCheckError {
+		response.Valid = false
+	}
+	for _, result := range results {
+		if !result.Valid {
+			response.Valid = false
+		}
+	}
+	workflow.GetLogger(ctx).Info("Validation completed", "workspaces", len(results), "valid", response.Valid)
+	return response, nil
+}
+
+// checkCIDROverlaps compares the cidrVars ranges of every workspace that
+// isn't skipped. Only values known before anything runs (tfvars and static
+// vars) are checked; no activity runs when no workspace declares cidrVars.
+func checkCIDROverlaps(ctx workflow.Context, config InfrastructureConfig, skipped map[string]bool) ([]activities.CIDROverlap, error) {
+	var sources []activities.CIDRSource
+	for _, ws := range config.Workspaces {
+		if skipped[ws.Name] || len(ws.CIDRVars) == 0 {
+			continue
+		}
+		sources = append(sources, activities.CIDRSource{
+			Workspace: ws.Name,
+			Params: activities.TerraformParams{
+				Dir:              ws.Dir,
+				Chdir:            ws.Chdir,
+				TFVars:           ws.TFVars,
+				Vars:             ws.Vars,
+				PreferAutoTFVars: ws.PreferAutoTFVars,
+			},
+			Vars: ws.CIDRVars,
+		})
+	}
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	actx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 1 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 3,
+		},
+	})
+	var a *activities.TerraformActivities
+	var overlaps []activities.CIDROverlap
+	if err := workflow.ExecuteActivity(actx, a.CheckCIDROverlaps, sources).Get(ctx, &overlaps); err != nil {
+		return nil, fmt.Errorf("CIDR overlap check failed: %w", err)
+	}
+	return overlaps, nil
+}
+
+// checkLocalState runs the config's backendCheck, returning the workspaces
+// that apply without a remote backend. Skipped workspaces and those with
+// allowLocalState are exempt; no activity runs when the check is off or
+// nothing applies.
+func checkLocalState(ctx workflow.Context, config InfrastructureConfig, skipped map[string]bool) ([]string, error) {
+	if config.BackendCheck == "" {
+		return nil, nil
+	}
+	var sources []activities.BackendSource
+	for _, ws := range config.Workspaces {
+		if skipped[ws.Name] || ws.AllowLocalState || !containsOperation(ws.Operations, "apply") {
+			continue
+		}
+		sources = append(sources, activities.BackendSource{
+			Workspace: ws.Name,
+			Params:    activities.TerraformParams{Dir: ws.Dir, Chdir: ws.Chdir},
+		})
+	}
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	actx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 1 * time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 3,
+		},
+	})
+	var a *activities.TerraformActivities
+	var local []string
+	if err := workflow.ExecuteActivity(actx, a.CheckLocalState, sources).Get(ctx, &local); err != nil {
+		return nil, fmt.Errorf("backend check failed: %w", err)
+	}
+	return local, nil
+}
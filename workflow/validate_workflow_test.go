@@ -0,0 +1,167 @@
+package workflow
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/fakoli/temporal-terraform-orchestrator/activities"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestValidateOnlyWorkflow_NeverPlansOrApplies(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	// Method values (not expressions) so the mocks see the decoded params
+	a := &activities.TerraformActivities{}
+	env.OnActivity(a.TerraformInit, mock.Anything, mock.MatchedBy(func(p activities.TerraformParams) bool {
+		return p.SkipBackend
+	})).Return(nil)
+	env.OnActivity(a.TerraformValidate, mock.Anything, mock.MatchedBy(func(p activities.TerraformParams) bool {
+		return p.Dir != "/tmp/eks"
+	})).Return(nil)
+	env.OnActivity(a.TerraformValidate, mock.Anything, mock.MatchedBy(func(p activities.TerraformParams) bool {
+		return p.Dir == "/tmp/eks"
+	})).Return(temporal.NewNonRetryableApplicationError("unsupported argument", "TerraformError", errors.New("unsupported argument")))
+
+	cfg := InfrastructureConfig{
+		Vars: map[string]interface{}{"env": "dev"},
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "eks", Dir: "/tmp/eks", DependsOn: []string{"vpc"}},
+			{Name: "waf", Dir: "/tmp/waf", When: `vars.env == "prod"`},
+		},
+	}
+
+	env.ExecuteWorkflow(ValidateOnlyWorkflow, cfg)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var resp ValidationResponse
+	require.NoError(t, env.GetWorkflowResult(&resp))
+	require.False(t, resp.Valid)
+	require.Empty(t, resp.Error)
+	require.Len(t, resp.Workspaces, 3)
+
+	require.Equal(t, WorkspaceValidation{Name: "vpc", Valid: true}, resp.Workspaces[0])
+	require.Equal(t, "eks", resp.Workspaces[1].Name)
+	require.False(t, resp.Workspaces[1].Valid)
+	require.Contains(t, resp.Workspaces[1].Error, "validate failed:")
+	require.Contains(t, resp.Workspaces[1].Error, "unsupported argument")
+	require.Equal(t, WorkspaceValidation{Name: "waf", Valid: true, Skipped: true}, resp.Workspaces[2])
+
+	env.AssertNotCalled(t, "TerraformPlan", mock.Anything, mock.Anything)
+	env.AssertNotCalled(t, "TerraformApply", mock.Anything, mock.Anything)
+}
+
+func TestValidateOnlyWorkflow_AllValid(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	cfg := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "vpc", Dir: "/tmp/vpc"},
+			{Name: "subnets", Dir: "/tmp/subnets", DependsOn: []string{"vpc"}},
+		},
+	}
+
+	env.ExecuteWorkflow(ValidateOnlyWorkflow, cfg)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var resp ValidationResponse
+	require.NoError(t, env.GetWorkflowResult(&resp))
+	require.True(t, resp.Valid)
+	require.Len(t, resp.Workspaces, 2)
+}
+
+func TestValidateOnlyWorkflow_StructuralError(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	cfg := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "a", Dir: "/tmp/a", DependsOn: []string{"b"}},
+			{Name: "b", Dir: "/tmp/b", DependsOn: []string{"a"}},
+		},
+	}
+
+	env.ExecuteWorkflow(ValidateOnlyWorkflow, cfg)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var resp ValidationResponse
+	require.NoError(t, env.GetWorkflowResult(&resp))
+	require.False(t, resp.Valid)
+	require.Contains(t, resp.Error, "cycle")
+	require.Empty(t, resp.Workspaces)
+}
+
+func TestValidateOnlyWorkflow_ReportsCIDROverlaps(t *testing.T) {
+	suite := &testsuite.WorkflowTestSuite{}
+	env := suite.NewTestWorkflowEnvironment()
+
+	a := &activities.TerraformActivities{}
+	env.RegisterActivity(a.CheckCIDROverlaps)
+	env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	cfg := InfrastructureConfig{
+		Workspaces: []WorkspaceConfig{
+			{Name: "prod-vpc", Dir: "/tmp/prod", Vars: map[string]interface{}{"cidr": "10.0.0.0/16"}, CIDRVars: []string{"cidr"}},
+			{Name: "dev-vpc", Dir: "/tmp/dev", Vars: map[string]interface{}{"cidr": "10.0.0.0/20"}, CIDRVars: []string{"cidr"}},
+		},
+	}
+
+	env.ExecuteWorkflow(ValidateOnlyWorkflow, cfg)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var resp ValidationResponse
+	require.NoError(t, env.GetWorkflowResult(&resp))
+	require.False(t, resp.Valid)
+	require.Len(t, resp.CIDROverlaps, 1)
+	require.Equal(t, "prod-vpc", resp.CIDROverlaps[0].Workspace)
+	require.Equal(t, "dev-vpc", resp.CIDROverlaps[0].OtherWorkspace)
+	require.True(t, resp.Workspaces[0].Valid)
+	require.True(t, resp.Workspaces[1].Valid)
+}
+
+func TestValidateOnlyWorkflow_ReportsLocalState(t *testing.T) {
+	for _, mode := range []string{BackendCheckWarn, BackendCheckError} {
+		t.Run(mode, func(t *testing.T) {
+			suite := &testsuite.WorkflowTestSuite{}
+			env := suite.NewTestWorkflowEnvironment()
+
+			a := &activities.TerraformActivities{}
+			env.OnActivity(a.CheckLocalState, mock.Anything, mock.Anything).Return([]string{"scratch"}, nil)
+			env.OnActivity((*activities.TerraformActivities).TerraformInit, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			env.OnActivity((*activities.TerraformActivities).TerraformValidate, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+			cfg := InfrastructureConfig{
+				BackendCheck: mode,
+				Workspaces:   []WorkspaceConfig{{Name: "scratch", Dir: "/tmp/scratch"}},
+			}
+
+			env.ExecuteWorkflow(ValidateOnlyWorkflow, cfg)
+
+			require.True(t, env.IsWorkflowCompleted())
+			require.NoError(t, env.GetWorkflowError())
+
+			var resp ValidationResponse
+			require.NoError(t, env.GetWorkflowResult(&resp))
+			require.Equal(t, []string{"scratch"}, resp.LocalState)
+			require.Equal(t, mode == BackendCheckWarn, resp.Valid)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ConfigValidator checks organization-specific rules that go beyond the
+// built-in structural checks. ParentWorkflow validates its config inside the
+// workflow, so validators must be deterministic and registered identically in
+// every process (worker, starter, MCP server), typically from an init func.
+type ConfigValidator interface {
+	// Name identifies the validator in error messages.
+	Name() string
+	Validate(cfg InfrastructureConfig) error
+}
+
+var (
+	configValidatorsMu sync.RWMutex
+	configValidators   []ConfigValidator
+)
+
+// RegisterConfigValidator adds v to the validators run by
+// ValidateInfrastructureConfig, in registration order.
+func RegisterConfigValidator(v ConfigValidator) {
+	configValidatorsMu.Lock()
+	defer configValidatorsMu.Unlock()
+	configValidators = append(configValidators, v)
+}
+
+// runConfigValidators runs every registered validator, collecting each failure.
+func runConfigValidators(cfg InfrastructureConfig) []error {
+	configValidatorsMu.RLock()
+	validators := append([]ConfigValidator(nil), configValidators...)
+	configValidatorsMu.RUnlock()
+
+	var errs []error
+	for _, v := range validators {
+		if err := v.Validate(cfg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", v.Name(), err))
+		}
+	}
+	return errs
+}
+
+// RequireTaskQueue is an example ConfigValidator rejecting workspaces that
+// don't pin a taskQueue, for deployments where each workspace must run on a
+// dedicated worker pool.
+type RequireTaskQueue struct{}
+
+func (RequireTaskQueue) Name() string { return "require-task-queue" }
+
+func (RequireTaskQueue) Validate(cfg InfrastructureConfig) error {
+	var missing []string
+	for _, ws := range cfg.Workspaces {
+		if strings.TrimSpace(ws.TaskQueue) == "" {
+			missing = append(missing, ws.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("workspaces without a taskQueue: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}